@@ -0,0 +1,209 @@
+// Package calendar decides which days are workdays, from the configured
+// working weekdays and holidays.
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxSearchDays bounds the search for a workday, so a calendar with every
+// day off cannot loop forever.
+const maxSearchDays = 3660
+
+// Calendar knows which weekdays are worked and which dates are holidays.
+type Calendar struct {
+	workdays [7]bool
+	holidays map[string]bool // YYYY-MM-DD
+}
+
+// weekdayNames maps full and three-letter weekday names.
+var weekdayNames = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// New returns a calendar working on the given weekdays, with the given
+// holidays off.
+func New(workdays []time.Weekday, holidays []time.Time) *Calendar {
+	c := &Calendar{holidays: make(map[string]bool, len(holidays))}
+	for _, wd := range workdays {
+		c.workdays[wd] = true
+	}
+	for _, h := range holidays {
+		c.holidays[h.Format("2006-01-02")] = true
+	}
+	return c
+}
+
+// Default returns a Monday to Friday calendar without holidays.
+func Default() *Calendar {
+	return New([]time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, nil)
+}
+
+// ParseWeekday reads a weekday name such as "monday" or "mon".
+func ParseWeekday(s string) (time.Weekday, bool) {
+	wd, ok := weekdayNames[strings.ToLower(strings.TrimSpace(s))]
+	return wd, ok
+}
+
+// IsWorkday reports whether t falls on a working weekday that is not a
+// holiday.
+func (c *Calendar) IsWorkday(t time.Time) bool {
+	return c.workdays[t.Weekday()] && !c.holidays[t.Format("2006-01-02")]
+}
+
+// NextWorkday returns t if it is a workday, and otherwise the first
+// workday after it.
+func (c *Calendar) NextWorkday(t time.Time) time.Time {
+	for i := 0; i < maxSearchDays && !c.IsWorkday(t); i++ {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// AddWorkdays returns the nth workday after t.
+func (c *Calendar) AddWorkdays(t time.Time, n int) time.Time {
+	for i := 0; i < maxSearchDays && n > 0; i++ {
+		t = t.AddDate(0, 0, 1)
+		if c.IsWorkday(t) {
+			n--
+		}
+	}
+	return t
+}
+
+// WorkdaysUntil counts the workdays after from up to and including to, so
+// a date due today is 0 workdays away and the next workday 1. It is
+// negative when to is before from, counting the workdays missed since.
+func (c *Calendar) WorkdaysUntil(from, to time.Time) int {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.Local)
+	sign := 1
+	if to.Before(from) {
+		from, to, sign = to, from, -1
+	}
+	n := 0
+	for d := from.AddDate(0, 0, 1); !d.After(to); d = d.AddDate(0, 0, 1) {
+		if c.IsWorkday(d) {
+			n++
+		}
+	}
+	return sign * n
+}
+
+// Event is an all-day event from an iCalendar file.
+type Event struct {
+	Start time.Time
+	Days  int    // days covered, at least 1
+	RRule string // the RRULE value, such as "FREQ=YEARLY;BYMONTH=12;BYMONTHDAY=25"; empty if the event does not repeat
+}
+
+// Dates returns each day the event covers, from Start on.
+func (e Event) Dates() []time.Time {
+	dates := make([]time.Time, 0, e.Days)
+	for i := 0; i < e.Days; i++ {
+		dates = append(dates, e.Start.AddDate(0, 0, i))
+	}
+	return dates
+}
+
+// LoadICS reads the events in an iCalendar file (see ParseICS).
+func LoadICS(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read holiday file: %w", err)
+	}
+	defer f.Close()
+	return ParseICS(f)
+}
+
+// ParseICS returns the events of an iCalendar file, each covering the days
+// from DTSTART up to the exclusive DTEND. A recurring event is returned
+// once, with its RRULE for the caller to expand.
+func ParseICS(r io.Reader) ([]Event, error) {
+	var events []Event
+	var start, end time.Time
+	var rrule string
+	inEvent := false
+
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+		switch name {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent, start, end, rrule = true, time.Time{}, time.Time{}, ""
+			}
+		case "DTSTART", "DTEND":
+			if !inEvent {
+				continue
+			}
+			if len(value) < 8 {
+				return nil, fmt.Errorf("invalid %s %q in holiday file", name, value)
+			}
+			d, err := time.ParseInLocation("20060102", value[:8], time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q in holiday file", name, value)
+			}
+			if name == "DTSTART" {
+				start = d
+			} else {
+				end = d
+			}
+		case "RRULE":
+			if inEvent {
+				rrule = value
+			}
+		case "END":
+			if !inEvent || !strings.EqualFold(value, "VEVENT") {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				continue
+			}
+			e := Event{Start: start, Days: 1, RRule: rrule}
+			for d := start.AddDate(0, 0, 1); d.Before(end); d = d.AddDate(0, 0, 1) {
+				e.Days++
+			}
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// unfoldICS splits iCalendar content into lines, joining the continuation
+// lines that start with a space or tab.
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read holiday file: %w", err)
+	}
+	return lines, nil
+}
@@ -0,0 +1,88 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(y, m, d int) time.Time {
+	return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local)
+}
+
+func TestWorkdays(t *testing.T) {
+	// 2099-12-25 is a Friday
+	cal := New([]time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		[]time.Time{date(2099, 12, 25), date(2099, 12, 28)})
+
+	tests := []struct {
+		name string
+		got  time.Time
+		want time.Time
+	}{
+		{"workday stays", cal.NextWorkday(date(2099, 12, 24)), date(2099, 12, 24)},
+		{"holiday and weekend skipped", cal.NextWorkday(date(2099, 12, 25)), date(2099, 12, 29)},
+		{"saturday to monday", cal.NextWorkday(date(2099, 12, 19)), date(2099, 12, 21)},
+		{"add 1 over holidays", cal.AddWorkdays(date(2099, 12, 24), 1), date(2099, 12, 29)},
+		{"add 5", cal.AddWorkdays(date(2099, 12, 14), 5), date(2099, 12, 21)},
+	}
+	for _, tt := range tests {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("%s: got %s, want %s", tt.name, tt.got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+
+	counts := []struct {
+		from, to time.Time
+		want     int
+	}{
+		{date(2099, 12, 24), date(2099, 12, 24), 0},
+		{date(2099, 12, 24), date(2099, 12, 29), 1},
+		{date(2099, 12, 18), date(2099, 12, 22), 2},
+		{date(2099, 12, 22), date(2099, 12, 18), -2},
+	}
+	for _, c := range counts {
+		if got := cal.WorkdaysUntil(c.from, c.to); got != c.want {
+			t.Errorf("WorkdaysUntil(%s, %s) = %d, want %d", c.from.Format("2006-01-02"), c.to.Format("2006-01-02"), got, c.want)
+		}
+	}
+}
+
+func TestParseICS(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20991225",
+		"DTEND;VALUE=DATE:20991227",
+		"SUMMARY:Christmas and",
+		"  Boxing Day",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:21000101",
+		"RRULE:FREQ=YEARLY",
+		"SUMMARY:New Year",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := ParseICS(strings.NewReader(ics))
+	if err != nil {
+		t.Fatalf("ParseICS: %v", err)
+	}
+	var got []string
+	for _, e := range events {
+		for _, d := range e.Dates() {
+			got = append(got, d.Format("2006-01-02"))
+		}
+	}
+	if strings.Join(got, " ") != "2099-12-25 2099-12-26 2100-01-01" {
+		t.Errorf("ParseICS days = %v", got)
+	}
+	if len(events) != 2 || events[0].RRule != "" || events[1].RRule != "FREQ=YEARLY" {
+		t.Errorf("ParseICS events = %+v, want the RRULE kept on the second", events)
+	}
+
+	if _, err := ParseICS(strings.NewReader("BEGIN:VEVENT\nDTSTART:2099\nEND:VEVENT\n")); err == nil {
+		t.Error("ParseICS should reject a malformed DTSTART")
+	}
+}
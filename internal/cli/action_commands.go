@@ -16,11 +16,13 @@ import (
 	"github.com/mph-llm-experiments/acore"
 	"github.com/mph-llm-experiments/atask/internal/config"
 	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/policy"
+	"github.com/mph-llm-experiments/atask/internal/query"
 	"github.com/mph-llm-experiments/atask/internal/task"
 )
 
 // ActionCommand creates the action command with all subcommands
-func ActionCommand(cfg *config.Config) *Command {
+func ActionCommand(cfg *config.Config, op *denote.Op) *Command {
 	cmd := &Command{
 		Name:        "action",
 		Usage:       "atask action <command> [options]",
@@ -28,12 +30,13 @@ func ActionCommand(cfg *config.Config) *Command {
 	}
 
 	cmd.Subcommands = []*Command{
-		actionNewCommand(cfg),
+		actionNewCommand(cfg, op),
 		actionListCommand(cfg),
+		actionQueryCommand(cfg),
 		actionShowCommand(cfg),
-		actionUpdateCommand(cfg),
-		actionApproveCommand(cfg),
-		actionRejectCommand(cfg),
+		actionUpdateCommand(cfg, op),
+		actionApproveCommand(cfg, op),
+		actionRejectCommand(cfg, op),
 	}
 
 	return cmd
@@ -63,7 +66,7 @@ func (f *fieldFlag) Set(val string) error {
 	return nil
 }
 
-func actionNewCommand(cfg *config.Config) *Command {
+func actionNewCommand(cfg *config.Config, op *denote.Op) *Command {
 	fs := flag.NewFlagSet("new", flag.ContinueOnError)
 	actionType := fs.String("action-type", "", "Action type (e.g. task_create, calendar_reschedule, or any plugin type)")
 	proposedBy := fs.String("proposed-by", "cli", "Agent identifier")
@@ -86,25 +89,102 @@ func actionNewCommand(cfg *config.Config) *Command {
 				return fmt.Errorf("--action-type is required")
 			}
 
-			bodyText := *body
+			pol, err := policy.Load(policy.Path(cfg.NotesDirectory))
+			if err != nil {
+				return err
+			}
 
-			action, err := task.CreateAction(cfg.NotesDirectory, title, *actionType, *proposedBy, bodyText, fields.values)
+			action, err := task.CreateAction(op, cfg.NotesDirectory, title, *actionType, *proposedBy, *body, fields.values, pol)
 			if err != nil {
 				return err
 			}
 
+			var result []byte
+			var execErr error
+			decision := action.Policy
+			if decision != nil {
+				// A failed action stays pending, as with action approve
+				if decision.Decision == policy.Approve {
+					result, execErr = executeAction(cfg, op, action)
+				}
+				if err := recordPolicyOutcome(op, action, execErr); err != nil {
+					return err
+				}
+				switch {
+				case decision.Decision == policy.Approve && execErr == nil:
+					if err := closeAction(cfg, op, action, denote.ActionExecuted); err != nil {
+						return err
+					}
+				case decision.Decision == policy.Reject:
+					if err := closeAction(cfg, op, action, denote.ActionRejected); err != nil {
+						return err
+					}
+				}
+			}
+
 			if globalFlags.JSON {
-				return printActionJSON(action)
+				if err := printActionJSON(action, result, execErr); err != nil {
+					return err
+				}
+				return execErr
 			}
 
 			if !globalFlags.Quiet {
 				fmt.Printf("Created action #%d: %s\n", action.IndexID, action.Title)
+				if decision != nil {
+					fmt.Println(policy.Describe(*decision))
+				}
+			}
+			if execErr != nil {
+				if !globalFlags.Quiet {
+					fmt.Fprintf(os.Stderr, "Action failed: %s\n", execErr.Error())
+				}
+				return execErr
+			}
+			if action.Status == denote.ActionExecuted && !globalFlags.Quiet {
+				fmt.Printf("Action #%d executed successfully\n", action.IndexID)
 			}
 			return nil
 		},
 	}
 }
 
+// recordPolicyOutcome adds a Policy section to the body of an action the
+// queue policy decided, once the outcome is known: an auto-approved action
+// whose execution failed says so, since it is still pending.
+func recordPolicyOutcome(op *denote.Op, action *denote.Action, execErr error) error {
+	text := policy.Describe(*action.Policy)
+	if execErr != nil {
+		text += fmt.Sprintf(", but executing it failed, so it is left pending:\n\n    %s", strings.ReplaceAll(execErr.Error(), "\n", "\n    "))
+	}
+	if err := appendToBody(op, action.FilePath, "\n## Policy\n\n"+text+"\n"); err != nil {
+		return fmt.Errorf("failed to record policy decision: %w", err)
+	}
+
+	// Pick up the new mtime so closing the action isn't refused as stale
+	updated, err := denote.ParseActionFile(action.FilePath)
+	if err != nil {
+		return err
+	}
+	action.Content = updated.Content
+	action.ModTime = updated.ModTime
+	return nil
+}
+
+// closeAction sets the final status of an approved or rejected action and
+// moves it to the archive.
+func closeAction(cfg *config.Config, op *denote.Op, action *denote.Action, status string) error {
+	action.Status = status
+	action.Modified = acore.Now()
+	if err := denote.SaveAction(op, action); err != nil {
+		return fmt.Errorf("failed to update action status: %w", err)
+	}
+	if err := task.ArchiveAction(op, cfg.NotesDirectory, action); err != nil {
+		return fmt.Errorf("failed to archive action: %w", err)
+	}
+	return nil
+}
+
 func actionListCommand(cfg *config.Config) *Command {
 	fs := flag.NewFlagSet("list", flag.ContinueOnError)
 	showAll := fs.Bool("all", false, "Show all actions including archived")
@@ -154,27 +234,136 @@ func actionListCommand(cfg *config.Config) *Command {
 			if !globalFlags.Quiet {
 				fmt.Println("# Pending Actions")
 			}
+			printActionLines(actions)
+			return nil
+		},
+	}
+}
+
+// printActionLines prints one line per action, colored by status.
+func printActionLines(actions []*denote.Action) {
+	for _, a := range actions {
+		age := formatAge(a.ProposedAt)
+		statusColor := color.New(color.FgYellow)
+		if a.Status == denote.ActionExecuted {
+			statusColor = color.New(color.FgGreen)
+		} else if a.Status == denote.ActionFailed || a.Status == denote.ActionRejected {
+			statusColor = color.New(color.FgRed)
+		}
+
+		if globalFlags.NoColor {
+			fmt.Printf("  %d  %-16s %-40s (%s, %s)\n",
+				a.IndexID, a.ActionType, a.Title, a.ProposedBy, age)
+		} else {
+			fmt.Printf("  %d  %-16s %-40s (%s, %s)\n",
+				a.IndexID,
+				statusColor.Sprint(a.ActionType),
+				a.Title,
+				a.ProposedBy,
+				age)
+		}
+	}
+}
+
+func actionQueryCommand(cfg *config.Config) *Command {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	includeArchived := fs.Bool("include-archived", false, "Also search archived actions")
+
+	return &Command{
+		Name:        "query",
+		Usage:       "atask action query <expression> [ORDER BY ...] [LIMIT n] [FIELDS ...] [options]",
+		Description: "Query actions with filter expressions",
+		Flags:       fs,
+		Run: func(cmd *Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("query expression required\n\nExamples:\n  atask action query \"status:pending AND action_type:task_create\"\n  atask action query \"proposed_by:agent* AND proposed_at>-7d\"\n  atask action query \"fields.priority:p1\"")
+			}
+
+			q, err := query.ParseFor(args[0], cfg, query.ActionRecords)
+			if err != nil {
+				return queryError("query parse error", err)
+			}
+
+			scanner := denote.NewScanner(cfg.NotesDirectory)
+			actions, err := scanner.FindActions()
+			if err != nil {
+				return err
+			}
+			if *includeArchived {
+				archived, err := scanner.FindArchivedActions()
+				if err != nil {
+					return err
+				}
+				actions = append(actions, archived...)
+			}
+
+			var matched []*denote.Action
 			for _, a := range actions {
-				age := formatAge(a.ProposedAt)
-				statusColor := color.New(color.FgYellow)
-				if a.Status == denote.ActionExecuted {
-					statusColor = color.New(color.FgGreen)
-				} else if a.Status == denote.ActionFailed || a.Status == denote.ActionRejected {
-					statusColor = color.New(color.FgRed)
+				if q.Match(a, cfg) {
+					matched = append(matched, a)
 				}
+			}
 
-				if globalFlags.NoColor {
-					fmt.Printf("  %d  %-16s %-40s (%s, %s)\n",
-						a.IndexID, a.ActionType, a.Title, a.ProposedBy, age)
+			q.SortSlice(matched, func(i int) query.Record { return matched[i] })
+			total := len(matched)
+			if q.Limit > 0 && len(matched) > q.Limit {
+				matched = matched[:q.Limit]
+			}
+
+			items := make([]actionListJSON, len(matched))
+			for i, a := range matched {
+				items[i] = newActionListJSON(a)
+			}
+
+			var rows []query.Row
+			if len(q.Fields) > 0 {
+				rows = make([]query.Row, len(items))
+				for i, item := range items {
+					row, err := q.Project(item)
+					if err != nil {
+						return fmt.Errorf("failed to project fields: %w", err)
+					}
+					rows[i] = row
+				}
+			}
+
+			if globalFlags.JSON {
+				type Output struct {
+					Actions interface{} `json:"actions"`
+					Count   int         `json:"count"`
+					Total   int         `json:"total,omitempty"`
+				}
+
+				output := Output{Actions: items, Count: len(matched)}
+				if rows != nil {
+					output.Actions = rows
+				}
+				if q.Limit > 0 {
+					output.Total = total
+				}
+
+				data, err := json.MarshalIndent(output, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if !globalFlags.Quiet {
+				if total > len(matched) {
+					fmt.Printf("Actions (%d of %d):\n\n", len(matched), total)
 				} else {
-					fmt.Printf("  %d  %-16s %-40s (%s, %s)\n",
-						a.IndexID,
-						statusColor.Sprint(a.ActionType),
-						a.Title,
-						a.ProposedBy,
-						age)
+					fmt.Printf("Actions (%d):\n\n", len(matched))
 				}
 			}
+
+			if rows != nil {
+				printRows(q.Fields, rows)
+				return nil
+			}
+
+			printActionLines(matched)
 			return nil
 		},
 	}
@@ -214,6 +403,9 @@ func actionShowCommand(cfg *config.Config) *Command {
 			fmt.Printf("  Status:      %s\n", action.Status)
 			fmt.Printf("  Proposed By: %s\n", action.ProposedBy)
 			fmt.Printf("  Proposed At: %s\n", action.ProposedAt)
+			if action.Policy != nil {
+				fmt.Printf("  Policy:      %s\n", policy.Describe(*action.Policy))
+			}
 			fmt.Println()
 
 			if len(action.Fields) > 0 {
@@ -234,7 +426,7 @@ func actionShowCommand(cfg *config.Config) *Command {
 	}
 }
 
-func actionUpdateCommand(cfg *config.Config) *Command {
+func actionUpdateCommand(cfg *config.Config, op *denote.Op) *Command {
 	fs := flag.NewFlagSet("update", flag.ContinueOnError)
 	title := fs.String("title", "", "Update action title")
 	actionType := fs.String("action-type", "", "Update action type")
@@ -282,7 +474,7 @@ func actionUpdateCommand(cfg *config.Config) *Command {
 			}
 
 			action.Modified = acore.Now()
-			if err := acore.UpdateFrontmatter(acore.NewLocalStore(filepath.Dir(action.FilePath)), filepath.Base(action.FilePath), action); err != nil {
+			if err := denote.SaveAction(op, action); err != nil {
 				return fmt.Errorf("failed to update action: %w", err)
 			}
 
@@ -293,7 +485,7 @@ func actionUpdateCommand(cfg *config.Config) *Command {
 			}
 
 			if globalFlags.JSON {
-				return printActionJSON(action)
+				return printActionJSON(action, nil, nil)
 			}
 
 			if !globalFlags.Quiet {
@@ -304,7 +496,7 @@ func actionUpdateCommand(cfg *config.Config) *Command {
 	}
 }
 
-func actionApproveCommand(cfg *config.Config) *Command {
+func actionApproveCommand(cfg *config.Config, op *denote.Op) *Command {
 	return &Command{
 		Name:        "approve",
 		Usage:       "atask action approve <id>",
@@ -324,7 +516,7 @@ func actionApproveCommand(cfg *config.Config) *Command {
 			}
 
 			// Execute the action directly — stay pending on failure so user can fix and retry
-			result, execErr := executeAction(action)
+			result, execErr := executeAction(cfg, op, action)
 
 			if execErr != nil {
 				if globalFlags.JSON {
@@ -332,6 +524,9 @@ func actionApproveCommand(cfg *config.Config) *Command {
 						"status": "failed",
 						"error":  execErr.Error(),
 					}
+					if fe, ok := execErr.(*task.FieldError); ok {
+						errResult["field"] = fe.Field
+					}
 					data, _ := json.MarshalIndent(errResult, "", "  ")
 					fmt.Println(string(data))
 				} else if !globalFlags.Quiet {
@@ -341,14 +536,8 @@ func actionApproveCommand(cfg *config.Config) *Command {
 			}
 
 			// Mark as executed and archive
-			action.Status = denote.ActionExecuted
-			action.Modified = acore.Now()
-			if err := acore.UpdateFrontmatter(acore.NewLocalStore(filepath.Dir(action.FilePath)), filepath.Base(action.FilePath), action); err != nil {
-				return fmt.Errorf("failed to update action status: %w", err)
-			}
-
-			if err := task.ArchiveAction(cfg.NotesDirectory, action); err != nil {
-				return fmt.Errorf("failed to archive action: %w", err)
+			if err := closeAction(cfg, op, action, denote.ActionExecuted); err != nil {
+				return err
 			}
 
 			if globalFlags.JSON {
@@ -367,7 +556,7 @@ func actionApproveCommand(cfg *config.Config) *Command {
 	}
 }
 
-func actionRejectCommand(cfg *config.Config) *Command {
+func actionRejectCommand(cfg *config.Config, op *denote.Op) *Command {
 	return &Command{
 		Name:        "reject",
 		Usage:       "atask action reject <id>",
@@ -386,14 +575,8 @@ func actionRejectCommand(cfg *config.Config) *Command {
 				return fmt.Errorf("cannot reject action with status: %s", action.Status)
 			}
 
-			action.Status = denote.ActionRejected
-			action.Modified = acore.Now()
-			if err := acore.UpdateFrontmatter(acore.NewLocalStore(filepath.Dir(action.FilePath)), filepath.Base(action.FilePath), action); err != nil {
-				return fmt.Errorf("failed to update action status: %w", err)
-			}
-
-			if err := task.ArchiveAction(cfg.NotesDirectory, action); err != nil {
-				return fmt.Errorf("failed to archive action: %w", err)
+			if err := closeAction(cfg, op, action, denote.ActionRejected); err != nil {
+				return err
 			}
 
 			if globalFlags.JSON {
@@ -411,7 +594,7 @@ func actionRejectCommand(cfg *config.Config) *Command {
 }
 
 // executePlugin runs an external plugin script with JSON on stdin.
-func executePlugin(pluginPath string, action *denote.Action) ([]byte, error) {
+func executePlugin(op *denote.Op, pluginPath string, action *denote.Action) ([]byte, error) {
 	input := map[string]interface{}{
 		"action_type": action.ActionType,
 		"title":       action.Title,
@@ -423,6 +606,7 @@ func executePlugin(pluginPath string, action *denote.Action) ([]byte, error) {
 	}
 
 	cmd := exec.Command(pluginPath)
+	cmd.Env = append(os.Environ(), op.Env()...)
 	cmd.Stdin = bytes.NewReader(inputJSON)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -444,53 +628,32 @@ func pluginDir() string {
 	return filepath.Join(home, ".config", "acore", "plugins")
 }
 
-// executeAction maps action_type + fields to a CLI command and runs it.
-func executeAction(action *denote.Action) ([]byte, error) {
+// executeAction runs an approved action. A plugin for the action type
+// takes precedence; otherwise the built-in task types run in-process
+// through the task service, and the types owned by other apps (anote,
+// apeople) run their CLI. The output is JSON: the task for built-in
+// types, the plugin's or command's output otherwise.
+func executeAction(cfg *config.Config, op *denote.Op, action *denote.Action) ([]byte, error) {
 	// Try plugin first
 	if dir := pluginDir(); dir != "" {
 		pluginPath := filepath.Join(dir, action.ActionType)
 		if info, err := os.Stat(pluginPath); err == nil && !info.IsDir() {
-			return executePlugin(pluginPath, action)
+			return executePlugin(op, pluginPath, action)
 		}
 	}
 
+	if task.IsBuiltinAction(action.ActionType) {
+		result, err := task.NewService(cfg.NotesDirectory, op).RunAction(action)
+		if err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(result.Task, "", "  ")
+	}
+
 	var bin string
 	var args []string
 
 	switch action.ActionType {
-	case denote.ActionTypeTaskCreate:
-		bin = "atask"
-		title := action.Fields["title"]
-		if title == "" {
-			title = action.Title
-		}
-		args = []string{"new", title}
-		addFieldFlag(action.Fields, &args, "priority", "--priority")
-		addFieldFlag(action.Fields, &args, "due", "--due")
-		addFieldFlag(action.Fields, &args, "area", "--area")
-		addFieldFlag(action.Fields, &args, "project", "--project")
-		addFieldFlag(action.Fields, &args, "tags", "--tags")
-		addFieldFlag(action.Fields, &args, "estimate", "--estimate")
-		addFieldFlag(action.Fields, &args, "recur", "--recur")
-		// add_person handled as post-creation step below
-
-	case denote.ActionTypeTaskUpdate:
-		bin = "atask"
-		targetID := action.Fields["target_id"]
-		if targetID == "" {
-			return nil, fmt.Errorf("task_update requires target_id field")
-		}
-		args = []string{"update"}
-		addFieldFlag(action.Fields, &args, "title", "--title")
-		addFieldFlag(action.Fields, &args, "status", "--status")
-		addFieldFlag(action.Fields, &args, "priority", "--priority")
-		addFieldFlag(action.Fields, &args, "due", "--due")
-		addFieldFlag(action.Fields, &args, "area", "--area")
-		addFieldFlag(action.Fields, &args, "project", "--project")
-		addFieldFlag(action.Fields, &args, "plan_for", "--plan-for")
-		addFieldFlag(action.Fields, &args, "add_person", "--add-person")
-		args = append(args, targetID)
-
 	case denote.ActionTypeIdeaCreate:
 		bin = "anote"
 		title := action.Fields["title"]
@@ -542,58 +705,33 @@ func executeAction(action *denote.Action) ([]byte, error) {
 
 	args = append(args, "--json", "--quiet")
 	c := exec.Command(bin, args...)
+	c.Env = append(os.Environ(), op.Env()...)
 	output, err := c.CombinedOutput()
 	if err != nil {
 		return nil, fmt.Errorf("command failed: %s\nOutput: %s", err, string(output))
 	}
-
-	// For task_create: if add_person is set, run a follow-up update to link people
-	// (atask new doesn't support --add-person, only atask update does)
-	if action.ActionType == denote.ActionTypeTaskCreate {
-		if addPerson, ok := action.Fields["add_person"]; ok && addPerson != "" {
-			// Parse index_id from the created task's JSON output
-			var created struct {
-				IndexID int `json:"index_id"`
-			}
-			if err := json.Unmarshal(output, &created); err == nil && created.IndexID > 0 {
-				updateArgs := []string{"update"}
-				addFieldFlag(action.Fields, &updateArgs, "add_person", "--add-person")
-				updateArgs = append(updateArgs, fmt.Sprintf("%d", created.IndexID), "--json", "--quiet")
-				uc := exec.Command("atask", updateArgs...)
-				if updateOut, updateErr := uc.CombinedOutput(); updateErr != nil {
-					// Non-fatal: task was created but linking failed
-					return output, fmt.Errorf("task created but linking people failed: %s\nOutput: %s", updateErr, string(updateOut))
-				}
-			}
-		}
-	}
-
 	return output, nil
 }
 
 func addFieldFlag(fields map[string]string, args *[]string, fieldName, flagName string) {
 	if v, ok := fields[fieldName]; ok && v != "" {
-		// Support comma-separated values for repeatable flags (e.g. add_person)
-		if strings.Contains(v, ",") && strings.HasPrefix(flagName, "--add-") {
-			for _, part := range strings.Split(v, ",") {
-				part = strings.TrimSpace(part)
-				if part != "" {
-					*args = append(*args, flagName, part)
-				}
-			}
-		} else {
-			*args = append(*args, flagName, v)
-		}
+		*args = append(*args, flagName, v)
 	}
 }
 
-func appendToBody(filepath string, text string) {
+func appendToBody(op *denote.Op, filepath string, text string) error {
+	unlock, err := denote.LockFile(filepath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	content, err := os.ReadFile(filepath)
 	if err != nil {
-		return
+		return err
 	}
 	content = append(content, []byte(text)...)
-	os.WriteFile(filepath, content, 0644)
+	return denote.WriteFileAtomic(op, filepath, content, 0644)
 }
 
 func formatAge(proposedAt string) string {
@@ -622,20 +760,25 @@ func formatAge(proposedAt string) string {
 	return fmt.Sprintf("%dd ago", days)
 }
 
-func printActionJSON(action *denote.Action) error {
+// printActionJSON prints an action, with the output or error of executing
+// it if it was run.
+func printActionJSON(action *denote.Action, result []byte, execErr error) error {
 	type jsonAction struct {
-		ID         string            `json:"id"`
-		IndexID    int               `json:"index_id"`
-		Title      string            `json:"title"`
-		Type       string            `json:"type"`
-		ActionType string            `json:"action_type"`
-		Status     string            `json:"status"`
-		ProposedAt string            `json:"proposed_at"`
-		ProposedBy string            `json:"proposed_by"`
-		Fields     map[string]string `json:"fields"`
-		Content    string            `json:"content,omitempty"`
-		Created    string            `json:"created,omitempty"`
-		Modified   string            `json:"modified,omitempty"`
+		ID         string               `json:"id"`
+		IndexID    int                  `json:"index_id"`
+		Title      string               `json:"title"`
+		Type       string               `json:"type"`
+		ActionType string               `json:"action_type"`
+		Status     string               `json:"status"`
+		ProposedAt string               `json:"proposed_at"`
+		ProposedBy string               `json:"proposed_by"`
+		Fields     map[string]string    `json:"fields"`
+		Policy     *denote.ActionPolicy `json:"policy,omitempty"`
+		Result     string               `json:"result,omitempty"`
+		Error      string               `json:"error,omitempty"`
+		Content    string               `json:"content,omitempty"`
+		Created    string               `json:"created,omitempty"`
+		Modified   string               `json:"modified,omitempty"`
 	}
 
 	ja := jsonAction{
@@ -648,10 +791,15 @@ func printActionJSON(action *denote.Action) error {
 		ProposedAt: action.ProposedAt,
 		ProposedBy: action.ProposedBy,
 		Fields:     action.Fields,
+		Policy:     action.Policy,
+		Result:     string(result),
 		Content:    action.Content,
 		Created:    action.Created,
 		Modified:   action.Modified,
 	}
+	if execErr != nil {
+		ja.Error = execErr.Error()
+	}
 
 	data, err := json.MarshalIndent(ja, "", "  ")
 	if err != nil {
@@ -661,38 +809,45 @@ func printActionJSON(action *denote.Action) error {
 	return nil
 }
 
+// actionListJSON is the JSON form of an action in list output.
+type actionListJSON struct {
+	ID         string               `json:"id"`
+	IndexID    int                  `json:"index_id"`
+	Title      string               `json:"title"`
+	Type       string               `json:"type"`
+	ActionType string               `json:"action_type"`
+	Status     string               `json:"status"`
+	ProposedAt string               `json:"proposed_at"`
+	ProposedBy string               `json:"proposed_by"`
+	Fields     map[string]string    `json:"fields"`
+	Policy     *denote.ActionPolicy `json:"policy,omitempty"`
+	Content    string               `json:"content,omitempty"`
+}
+
+func newActionListJSON(a *denote.Action) actionListJSON {
+	return actionListJSON{
+		ID:         a.ID,
+		IndexID:    a.IndexID,
+		Title:      a.Title,
+		Type:       a.Type,
+		ActionType: a.ActionType,
+		Status:     a.Status,
+		ProposedAt: a.ProposedAt,
+		ProposedBy: a.ProposedBy,
+		Fields:     a.Fields,
+		Policy:     a.Policy,
+		Content:    a.Content,
+	}
+}
+
 func printActionsJSON(actions []*denote.Action) error {
-	type jsonAction struct {
-		ID         string            `json:"id"`
-		IndexID    int               `json:"index_id"`
-		Title      string            `json:"title"`
-		Type       string            `json:"type"`
-		ActionType string            `json:"action_type"`
-		Status     string            `json:"status"`
-		ProposedAt string            `json:"proposed_at"`
-		ProposedBy string            `json:"proposed_by"`
-		Fields     map[string]string `json:"fields"`
-		Content    string            `json:"content,omitempty"`
-	}
-
-	var items []jsonAction
+	var items []actionListJSON
 	for _, a := range actions {
-		items = append(items, jsonAction{
-			ID:         a.ID,
-			IndexID:    a.IndexID,
-			Title:      a.Title,
-			Type:       a.Type,
-			ActionType: a.ActionType,
-			Status:     a.Status,
-			ProposedAt: a.ProposedAt,
-			ProposedBy: a.ProposedBy,
-			Fields:     a.Fields,
-			Content:    a.Content,
-		})
+		items = append(items, newActionListJSON(a))
 	}
 
 	if items == nil {
-		items = []jsonAction{}
+		items = []actionListJSON{}
 	}
 
 	data, err := json.MarshalIndent(items, "", "  ")
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/task"
+)
+
+// ArchiveCommand returns the archive command
+func ArchiveCommand(cfg *config.Config, op *denote.Op) *Command {
+	fs := flag.NewFlagSet("archive", flag.ContinueOnError)
+	olderThan := fs.String("older-than", "90d", "Only archive items finished longer ago than this (e.g. 90d, 12w)")
+	dryRun := fs.Bool("dry-run", false, "Show what would be archived without moving anything")
+
+	return &Command{
+		Name:  "archive",
+		Usage: "atask archive [--older-than 90d] [--dry-run]",
+		Description: `Move finished tasks and projects into archive/YYYY/
+
+Done and dropped tasks, and completed and cancelled projects, that were
+finished longer ago than --older-than (by completed_at, or the modified
+time when that is not recorded) are moved out of the main directory. Archived items keep their IDs: show, update and done
+still find them, and 'atask query --include-archived' searches them.`,
+		Flags: fs,
+		Run: func(cmd *Command, args []string) error {
+			age, err := parseAge(*olderThan)
+			if err != nil {
+				return err
+			}
+
+			files, err := task.ArchiveCompleted(op, cfg.NotesDirectory, age, *dryRun)
+			if err != nil {
+				return err
+			}
+
+			if globalFlags.JSON {
+				if files == nil {
+					files = []task.ArchivedFile{}
+				}
+				data, _ := json.MarshalIndent(map[string]interface{}{
+					"archived": files,
+					"count":    len(files),
+					"dry_run":  *dryRun,
+				}, "", "  ")
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if globalFlags.Quiet {
+				return nil
+			}
+
+			if len(files) == 0 {
+				fmt.Println("Nothing to archive.")
+				return nil
+			}
+
+			verb := "Archived"
+			if *dryRun {
+				verb = "Would archive"
+			}
+			fmt.Printf("%s %d items:\n\n", verb, len(files))
+			for _, f := range files {
+				fmt.Printf("  %-4d %-8s %-10s %s  %s\n", f.IndexID, f.Type, f.Status, f.CompletedAt.Format("2006-01-02"), f.Title)
+			}
+			return nil
+		},
+	}
+}
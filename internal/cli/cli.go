@@ -3,8 +3,12 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/query"
+	"github.com/mph-llm-experiments/atask/internal/recurrence"
 	"github.com/mph-llm-experiments/atask/internal/tui"
 )
 
@@ -30,6 +34,18 @@ func Run(cfg *config.Config, args []string) error {
 		cfg.NotesDirectory = globalFlags.Dir
 	}
 
+	// A broken saved query is reported up front rather than when it is used
+	if err := query.ValidateSaved(cfg); err != nil {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
+
+	for _, w := range cfg.Warnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+
+	// Workday recurrence modifiers follow the [calendar] section
+	recurrence.SetCalendar(cfg.WorkCalendar())
+
 	// Sync on startup/shutdown — skip for --json (programmatic/aweb use)
 	if !globalFlags.JSON {
 		SyncOnStartup(cfg)
@@ -58,11 +74,13 @@ Task Commands (implicit):
   show       Show task details
   update     Update task metadata
   done       Mark tasks as done
+  skip       Skip a recurring task to its next occurrence
   log        Add log entry to task
 
 Project Commands:
   project new      Create a new project
   project list     List projects
+  project query    Query projects with filter expressions
   project show     Show project details
   project update   Update project metadata
   project tasks    Show tasks for a project
@@ -70,14 +88,26 @@ Project Commands:
 Action Queue Commands:
   action new       Create a proposed action
   action list      List pending actions
+  action query     Query actions with filter expressions
   action show      Show action details
   action update    Modify action fields
   action approve   Approve and execute an action
   action reject    Reject an action
 
 Other Commands:
-  sync        Sync files with Cloudflare R2
-  completion  Generate shell completions
+  search         Full-text search of tasks and projects
+  series show    Show a recurring task's history and streak
+  upcoming       Forecast dated and recurring tasks by day
+  doctor         Check the task directory for invalid metadata
+  index rebuild  Rebuild the metadata and search indexes
+  archive        Move finished tasks and projects to archive/
+  undo [n]       Undo the last n changes
+  history        Show the undo journal
+  trash list     List deleted tasks and projects
+  trash empty    Permanently remove items from the trash
+  restore        Restore a deleted task or project
+  sync           Sync files with Cloudflare R2
+  completion     Generate shell completions
 
 Global Options:
   --tui, -t      Launch TUI interface
@@ -89,19 +119,33 @@ Global Options:
   --quiet, -q    Minimal output`,
 	}
 
+	// Every write made by this invocation is journaled as one undoable
+	// operation (see 'atask undo')
+	op := denote.NewOp(cfg.NotesDirectory, "atask "+strings.Join(remaining, " "))
+
 	// Get task commands and add them directly to root
-	taskCmd := TaskCommand(cfg)
+	taskCmd := TaskCommand(cfg, op)
 	for _, cmd := range taskCmd.Subcommands {
 		root.Subcommands = append(root.Subcommands, cmd)
 	}
 	
-	// Add project, action, sync, completion, and migrate commands
+	// Add project, action, doctor, index, search, series, upcoming, archive, trash, undo, history, sync, completion, and migrate commands
 	root.Subcommands = append(root.Subcommands,
-		ProjectCommand(cfg),
-		ActionCommand(cfg),
+		ProjectCommand(cfg, op),
+		ActionCommand(cfg, op),
+		DoctorCommand(cfg, op),
+		IndexCommand(cfg),
+		SearchCommand(cfg),
+		SeriesCommand(cfg),
+		UpcomingCommand(cfg),
+		ArchiveCommand(cfg, op),
+		TrashCommand(cfg),
+		UndoCommand(cfg),
+		HistoryCommand(cfg),
+		RestoreCommand(cfg, op),
 		SyncCommand(cfg),
 		CompletionCommand(cfg),
-		MigrateCommand(cfg),
+		MigrateCommand(cfg, op),
 	)
 
 	// Execute command
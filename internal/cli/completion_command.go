@@ -3,9 +3,11 @@ package cli
 import (
 	"flag"
 	"fmt"
+	"path/filepath"
 	"sort"
 	"strconv"
 
+	"github.com/mph-llm-experiments/acore"
 	"github.com/mph-llm-experiments/atask/internal/config"
 	"github.com/mph-llm-experiments/atask/internal/denote"
 )
@@ -23,20 +25,25 @@ func CompletionCommand(cfg *config.Config) *Command {
 			}
 
 			scanner := denote.NewScanner(cfg.NotesDirectory)
-			files, err := scanner.FindAllTaskAndProjectFiles()
+			scanner.FrontmatterOnly = true
+			tasks, err := scanner.FindTasks()
+			if err != nil {
+				return fmt.Errorf("failed to scan directory: %v", err)
+			}
+			projects, err := scanner.FindProjects()
 			if err != nil {
 				return fmt.Errorf("failed to scan directory: %v", err)
 			}
 
 			switch args[0] {
 			case "task-ids":
-				return outputTaskIDs(files)
+				return outputTaskIDs(tasks)
 			case "project-ids":
-				return outputProjectIDs(files)
+				return outputProjectIDs(projects)
 			case "areas":
-				return outputAreas(files)
+				return outputAreas(tasks, projects)
 			case "tags":
-				return outputTags(files)
+				return outputTags(tasks, projects)
 			default:
 				return fmt.Errorf("unknown completion type: %s", args[0])
 			}
@@ -46,19 +53,14 @@ func CompletionCommand(cfg *config.Config) *Command {
 	return cmd
 }
 
-func outputTaskIDs(files []denote.File) error {
+func outputTaskIDs(tasks []*denote.Task) error {
 	var ids []int
 	seen := make(map[int]bool)
 
-	for _, file := range files {
-		if file.IsTask() {
-			task, err := denote.ParseTaskFile(file.Path)
-			if err == nil && task.IndexID > 0 {
-				if !seen[task.IndexID] {
-					ids = append(ids, task.IndexID)
-					seen[task.IndexID] = true
-				}
-			}
+	for _, task := range tasks {
+		if task.IndexID > 0 && !seen[task.IndexID] {
+			ids = append(ids, task.IndexID)
+			seen[task.IndexID] = true
 		}
 	}
 
@@ -69,52 +71,37 @@ func outputTaskIDs(files []denote.File) error {
 	return nil
 }
 
-func outputProjectIDs(files []denote.File) error {
-	type projectInfo struct {
-		indexID int
-		title   string
-	}
-	var projects []projectInfo
-
-	for _, file := range files {
-		if file.IsProject() {
-			project, err := denote.ParseProjectFile(file.Path)
-			if err == nil {
-				title := project.Title
-				if title == "" {
-					title = file.Title
-				}
-				projects = append(projects, projectInfo{indexID: project.IndexID, title: title})
-			}
-		}
-	}
-
+func outputProjectIDs(projects []*denote.Project) error {
 	// Sort by index_id
 	sort.Slice(projects, func(i, j int) bool {
-		return projects[i].indexID < projects[j].indexID
+		return projects[i].IndexID < projects[j].IndexID
 	})
 
 	// Output as "index_id:Title" for richer completion
 	for _, p := range projects {
-		fmt.Printf("%s:%s\n", strconv.Itoa(p.indexID), p.title)
+		title := p.Title
+		if title == "" {
+			// Untitled projects complete by their filename slug
+			if _, slug, _, err := acore.ParseFilename(filepath.Base(p.FilePath)); err == nil {
+				title = slug
+			}
+		}
+		fmt.Printf("%s:%s\n", strconv.Itoa(p.IndexID), title)
 	}
 	return nil
 }
 
-func outputAreas(files []denote.File) error {
+func outputAreas(tasks []*denote.Task, projects []*denote.Project) error {
 	areas := make(map[string]bool)
 
-	for _, file := range files {
-		if file.IsTask() {
-			task, err := denote.ParseTaskFile(file.Path)
-			if err == nil && task.TaskMetadata.Area != "" {
-				areas[task.TaskMetadata.Area] = true
-			}
-		} else if file.IsProject() {
-			project, err := denote.ParseProjectFile(file.Path)
-			if err == nil && project.ProjectMetadata.Area != "" {
-				areas[project.ProjectMetadata.Area] = true
-			}
+	for _, task := range tasks {
+		if task.TaskMetadata.Area != "" {
+			areas[task.TaskMetadata.Area] = true
+		}
+	}
+	for _, project := range projects {
+		if project.ProjectMetadata.Area != "" {
+			areas[project.ProjectMetadata.Area] = true
 		}
 	}
 
@@ -131,11 +118,18 @@ func outputAreas(files []denote.File) error {
 	return nil
 }
 
-func outputTags(files []denote.File) error {
+func outputTags(tasks []*denote.Task, projects []*denote.Project) error {
 	tags := make(map[string]bool)
 
-	for _, file := range files {
-		for _, tag := range file.Tags {
+	var allTags [][]string
+	for _, task := range tasks {
+		allTags = append(allTags, task.Tags)
+	}
+	for _, project := range projects {
+		allTags = append(allTags, project.Tags)
+	}
+	for _, fileTags := range allTags {
+		for _, tag := range fileTags {
 			// Skip special tags
 			if tag != "task" && tag != "project" {
 				tags[tag] = true
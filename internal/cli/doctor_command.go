@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/doctor"
+)
+
+// DoctorCommand returns the doctor command
+func DoctorCommand(cfg *config.Config, op *denote.Op) *Command {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fix := fs.Bool("fix", false, "Apply safe repairs")
+	clearDangling := fs.Bool("clear-dangling", false, "With --fix, also clear project_id values that point at missing projects and renumber projects whose tasks can't be told apart")
+	reportPath := fs.String("report", "", "Write the JSON report to this file (default with --fix: "+doctor.ReportFileName+" in the notes directory)")
+
+	return &Command{
+		Name:  "doctor",
+		Usage: "atask doctor [--fix [--clear-dangling]] [--report <path>]",
+		Description: `Check the task directory for invalid metadata
+
+Reports invalid statuses and priorities, malformed dates, non-Fibonacci
+estimates, project_id values pointing at missing projects, duplicate
+index_ids, filenames that no longer match their title, and recurring tasks
+without a due date. With --fix, safe repairs are applied and the report is
+saved to .atask-doctor-report.json in the notes directory (or --report). A
+dangling project_id is only cleared with --clear-dangling, since the project
+may just not have synced yet. A renumbered project takes the tasks that
+reference it along; when two projects share an ID, renumbering one also
+waits for --clear-dangling, as their tasks can't be told apart.`,
+		Flags: fs,
+		Run: func(cmd *Command, args []string) error {
+			report, err := doctor.Check(cfg.NotesDirectory)
+			if err != nil {
+				return err
+			}
+
+			fixed := 0
+			if *fix {
+				fixed = report.Fix(op, *clearDangling)
+				if *reportPath == "" {
+					*reportPath = filepath.Join(cfg.NotesDirectory, doctor.ReportFileName)
+				}
+			}
+			if *reportPath != "" {
+				if err := report.Write(*reportPath); err != nil {
+					return fmt.Errorf("failed to write report: %w", err)
+				}
+			}
+
+			if globalFlags.JSON {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if globalFlags.Quiet {
+				return nil
+			}
+
+			fmt.Printf("Checked %d tasks and %d projects: ", report.Tasks, report.Projects)
+			if len(report.Issues) == 0 {
+				fmt.Println("no issues found.")
+				return nil
+			}
+			fmt.Printf("%d issues\n\n", len(report.Issues))
+
+			for _, issue := range report.Issues {
+				id := "   "
+				if issue.IndexID > 0 {
+					id = fmt.Sprintf("#%d", issue.IndexID)
+				}
+				fmt.Printf("  %-18s %-5s %s\n", issue.Check, id, filepath.Base(issue.Path))
+				fmt.Printf("  %-18s %-5s %s\n", "", "", issue.Message)
+				switch {
+				case issue.Fixed:
+					fmt.Printf("  %-18s %-5s fixed: %s\n", "", "", issue.Fix)
+				case issue.FixError != "":
+					fmt.Printf("  %-18s %-5s fix failed: %s\n", "", "", issue.FixError)
+				case issue.Lossy:
+					fmt.Printf("  %-18s %-5s fix (with --clear-dangling): %s\n", "", "", issue.Fix)
+				case issue.Fix != "":
+					fmt.Printf("  %-18s %-5s fix: %s\n", "", "", issue.Fix)
+				}
+			}
+
+			fmt.Println()
+			if *fix {
+				fmt.Printf("Applied %d repairs; %d issues need manual attention.\n", fixed, len(report.Issues)-fixed)
+			} else if n := report.Fixable(); n > 0 {
+				fmt.Printf("Run 'atask doctor --fix' to apply %d safe repairs.\n", n)
+			}
+			if *reportPath != "" {
+				fmt.Printf("Report written to %s\n", *reportPath)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/search"
+)
+
+// IndexCommand returns the metadata index command
+func IndexCommand(cfg *config.Config) *Command {
+	cmd := &Command{
+		Name:        "index",
+		Usage:       "atask index <command>",
+		Description: "Manage the metadata index",
+	}
+
+	cmd.Subcommands = []*Command{
+		indexRebuildCommand(cfg),
+	}
+
+	return cmd
+}
+
+func indexRebuildCommand(cfg *config.Config) *Command {
+	return &Command{
+		Name:        "rebuild",
+		Usage:       "atask index rebuild",
+		Description: "Discard the metadata and search indexes and re-parse every file",
+		Flags:       flag.NewFlagSet("index-rebuild", flag.ContinueOnError),
+		Run: func(cmd *Command, args []string) error {
+			idx, err := denote.RebuildIndex(cfg.NotesDirectory)
+			if err != nil {
+				return fmt.Errorf("failed to rebuild index: %w", err)
+			}
+			searchIdx, err := search.Rebuild(cfg.NotesDirectory)
+			if err != nil {
+				return fmt.Errorf("failed to rebuild search index: %w", err)
+			}
+
+			path := filepath.Join(cfg.NotesDirectory, denote.IndexFileName)
+			tasks := idx.Count(denote.TypeTask)
+			projects := idx.Count(denote.TypeProject)
+			actions := idx.Count(denote.TypeAction)
+
+			if globalFlags.JSON {
+				data, _ := json.MarshalIndent(map[string]interface{}{
+					"path":     path,
+					"tasks":    tasks,
+					"projects": projects,
+					"actions":  actions,
+					"search": map[string]interface{}{
+						"path":      filepath.Join(cfg.NotesDirectory, search.IndexFileName),
+						"documents": searchIdx.Count(),
+					},
+				}, "", "  ")
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if !globalFlags.Quiet {
+				fmt.Printf("Indexed %d tasks, %d projects, %d actions in %s\n", tasks, projects, actions, path)
+				fmt.Printf("Indexed %d documents for search in %s\n", searchIdx.Count(), filepath.Join(cfg.NotesDirectory, search.IndexFileName))
+			}
+			return nil
+		},
+	}
+}
@@ -14,7 +14,7 @@ import (
 )
 
 // MigrateCommand creates the migrate command
-func MigrateCommand(cfg *config.Config) *Command {
+func MigrateCommand(cfg *config.Config, op *denote.Op) *Command {
 	cmd := &Command{
 		Name:        "migrate",
 		Usage:       "atask migrate <migration-name>",
@@ -23,7 +23,7 @@ func MigrateCommand(cfg *config.Config) *Command {
 
 	cmd.Subcommands = []*Command{
 		migrateAcoreCommand(cfg),
-		migrateProjectIDCommand(cfg),
+		migrateProjectIDCommand(cfg, op),
 	}
 
 	return cmd
@@ -133,7 +133,7 @@ func migrateAcoreCommand(cfg *config.Config) *Command {
 }
 
 // migrateProjectIDCommand migrates project_id from Denote ID to index_id
-func migrateProjectIDCommand(cfg *config.Config) *Command {
+func migrateProjectIDCommand(cfg *config.Config, op *denote.Op) *Command {
 	var dryRun bool
 
 	cmd := &Command{
@@ -213,7 +213,7 @@ func migrateProjectIDCommand(cfg *config.Config) *Command {
 
 			// Update the task
 			t.TaskMetadata.ProjectID = indexIDStr
-			if err := task.UpdateTaskFile(t.FilePath, t); err != nil {
+			if err := task.UpdateTaskFile(op, t.FilePath, t); err != nil {
 				fmt.Printf("  ERROR: Failed to update task %d: %v\n", t.IndexID, err)
 				skipped++
 				continue
@@ -13,11 +13,13 @@ import (
 	"github.com/mph-llm-experiments/acore"
 	"github.com/mph-llm-experiments/atask/internal/config"
 	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/query"
+	searchpkg "github.com/mph-llm-experiments/atask/internal/search"
 	"github.com/mph-llm-experiments/atask/internal/task"
 )
 
 // ProjectCommand creates the project command with all subcommands
-func ProjectCommand(cfg *config.Config) *Command {
+func ProjectCommand(cfg *config.Config, op *denote.Op) *Command {
 	cmd := &Command{
 		Name:        "project",
 		Usage:       "atask project <command> [options]",
@@ -25,12 +27,13 @@ func ProjectCommand(cfg *config.Config) *Command {
 	}
 
 	cmd.Subcommands = []*Command{
-		projectNewCommand(cfg),
+		projectNewCommand(cfg, op),
 		projectListCommand(cfg),
+		projectQueryCommand(cfg),
 		projectShowCommand(cfg),
 		projectTasksCommand(cfg),
-		projectUpdateCommand(cfg),
-		projectLogCommand(cfg),
+		projectUpdateCommand(cfg, op),
+		projectLogCommand(cfg, op),
 	}
 
 	return cmd
@@ -137,7 +140,7 @@ func projectShowCommand(cfg *config.Config) *Command {
 }
 
 // projectNewCommand creates a new project
-func projectNewCommand(cfg *config.Config) *Command {
+func projectNewCommand(cfg *config.Config, op *denote.Op) *Command {
 	var (
 		priority  string
 		due       string
@@ -177,7 +180,7 @@ func projectNewCommand(cfg *config.Config) *Command {
 		}
 
 		// Create the project
-		projectFile, err := task.CreateProject(cfg.NotesDirectory, title, "", tagList)
+		projectFile, err := task.CreateProject(op, cfg.NotesDirectory, title, "", tagList)
 		if err != nil {
 			return fmt.Errorf("failed to create project: %v", err)
 		}
@@ -211,7 +214,7 @@ func projectNewCommand(cfg *config.Config) *Command {
 
 		// Write back if we have updates
 		if needsUpdate {
-			if err := denote.UpdateProjectFile(projectFile.FilePath, projectFile); err != nil {
+			if err := denote.UpdateProjectFile(op, projectFile.FilePath, projectFile); err != nil {
 				return fmt.Errorf("failed to update project metadata: %v", err)
 			}
 		}
@@ -274,11 +277,21 @@ func projectListCommand(cfg *config.Config) *Command {
 
 		// Get all projects
 		scanner := denote.NewScanner(cfg.NotesDirectory)
+		scanner.FrontmatterOnly = true
 		projects, err := scanner.FindProjects()
 		if err != nil {
 			return fmt.Errorf("failed to scan directory: %v", err)
 		}
 
+		var searchHits map[string]bool
+		if search != "" {
+			idx, err := searchpkg.Open(cfg.NotesDirectory)
+			if err != nil {
+				return fmt.Errorf("failed to open search index: %w", err)
+			}
+			searchHits = idx.Matches(search)
+		}
+
 		// Apply filters
 		var filtered []*denote.Project
 		for _, p := range projects {
@@ -306,10 +319,8 @@ func projectListCommand(cfg *config.Config) *Command {
 
 
 		// Content search
-		if search != "" {
-			if !strings.Contains(strings.ToLower(p.Content), strings.ToLower(search)) {
-				continue
-			}
+		if search != "" && !searchHits[p.FilePath] {
+			continue
 		}
 			filtered = append(filtered, p)
 		}
@@ -362,110 +373,236 @@ func projectListCommand(cfg *config.Config) *Command {
 			return nil
 		}
 
-		// Color setup
-		if globalFlags.NoColor || color.NoColor {
-			color.NoColor = true
-		}
-
-		// Status colors
-		completedColor := color.New(color.FgGreen)
-		pausedColor := color.New(color.FgYellow)
-		cancelledColor := color.New(color.FgRed, color.Faint)
-		priorityHighColor := color.New(color.FgRed, color.Bold)
-		priorityMedColor := color.New(color.FgYellow)
-
 		// Display header
 		if !globalFlags.Quiet {
 			fmt.Printf("Projects (%d):\n\n", len(filtered))
 		}
 
-		// Display projects
-		for _, p := range filtered {
-			// Status icon
-			status := "◆"
-			switch p.ProjectMetadata.Status {
-			case denote.ProjectStatusCompleted:
-				status = "✓"
-			case denote.ProjectStatusPaused:
-				status = "⏸"
-			case denote.ProjectStatusCancelled:
-				status = "⨯"
-			}
-
-			// Priority with padding
-			priority := "    " // 4 spaces for alignment
-			if p.ProjectMetadata.Priority != "" {
-				pStr := fmt.Sprintf("[%s]", p.ProjectMetadata.Priority)
-				switch p.ProjectMetadata.Priority {
-				case "p1":
-					priority = priorityHighColor.Sprint(pStr)
-				case "p2":
-					priority = priorityMedColor.Sprint(pStr)
-				default:
-					priority = pStr
-				}
+		printProjectLines(filtered, taskCounts)
+
+		return nil
+	}
+
+	return cmd
+}
+
+// projectQueryCommand filters projects with a query expression
+func projectQueryCommand(cfg *config.Config) *Command {
+	var includeArchived bool
+
+	cmd := &Command{
+		Name:        "query",
+		Usage:       "atask project query <expression> [ORDER BY ...] [LIMIT n] [FIELDS ...] [options]",
+		Description: "Query projects with filter expressions",
+		Flags:       flag.NewFlagSet("project-query", flag.ExitOnError),
+	}
+
+	cmd.Flags.BoolVar(&includeArchived, "include-archived", false, "Also search projects in archive/")
+
+	cmd.Run = func(c *Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("query expression required\n\nExamples:\n  atask project query \"status:active AND due:overdue\"\n  atask project query \"area:work ORDER BY due LIMIT 5\"")
+		}
+
+		q, err := query.ParseFor(args[0], cfg, query.ProjectRecords)
+		if err != nil {
+			return queryError("query parse error", err)
+		}
+
+		scanner := denote.NewScanner(cfg.NotesDirectory)
+		scanner.IncludeArchived = includeArchived
+		projects, err := scanner.FindProjects()
+		if err != nil {
+			return fmt.Errorf("failed to scan directory: %v", err)
+		}
+
+		var filtered []*denote.Project
+		for _, p := range projects {
+			if q.Match(p, cfg) {
+				filtered = append(filtered, p)
 			}
+		}
 
-			// Due date with fixed width
-			due := "            " // 12 spaces for alignment
-			if p.ProjectMetadata.DueDate != "" {
-				dueStr := fmt.Sprintf("[%s]", p.ProjectMetadata.DueDate)
-				if denote.IsOverdue(p.ProjectMetadata.DueDate) && p.ProjectMetadata.Status == denote.ProjectStatusActive {
-					due = color.New(color.FgRed, color.Bold).Sprint(dueStr)
-				} else {
-					due = dueStr
-				}
+		// ORDER BY takes precedence over the default most-recently-modified order
+		sortProjects(filtered, "modified", false)
+		q.SortSlice(filtered, func(i int) query.Record { return filtered[i] })
+		total := len(filtered)
+		if q.Limit > 0 && len(filtered) > q.Limit {
+			filtered = filtered[:q.Limit]
+		}
+
+		tasks, _ := scanner.FindTasks()
+		taskCounts := make(map[string]int)
+		for _, t := range tasks {
+			if t.TaskMetadata.ProjectID != "" {
+				taskCounts[t.TaskMetadata.ProjectID]++
 			}
+		}
 
-			// Title - truncate to 40 chars
-			title := p.Title
-			if len(title) > 40 {
-				title = title[:37] + "..."
+		type ProjectJSON struct {
+			denote.Project
+			TaskCount int `json:"task_count"`
+		}
+		jsonProjects := make([]ProjectJSON, len(filtered))
+		for i, p := range filtered {
+			jsonProjects[i] = ProjectJSON{
+				Project:   *p,
+				TaskCount: taskCounts[strconv.Itoa(p.IndexID)],
 			}
+		}
 
-			// Area - truncate to 10 chars
-			area := ""
-			if p.ProjectMetadata.Area != "" {
-				area = p.ProjectMetadata.Area
-				if len(area) > 10 {
-					area = area[:7] + "..."
+		var rows []query.Row
+		if len(q.Fields) > 0 {
+			rows = make([]query.Row, len(jsonProjects))
+			for i, p := range jsonProjects {
+				row, err := q.Project(p)
+				if err != nil {
+					return fmt.Errorf("failed to project fields: %w", err)
 				}
+				rows[i] = row
 			}
+		}
 
-			// Task count
-			taskCount := taskCounts[strconv.Itoa(p.IndexID)]
-			taskStr := fmt.Sprintf("(%d tasks)", taskCount)
+		if globalFlags.JSON {
+			type Output struct {
+				Projects interface{} `json:"projects"`
+				Count    int         `json:"count"`
+				Total    int         `json:"total,omitempty"`
+			}
 
-			// Build the line with fixed-width columns
-			line := fmt.Sprintf("%3d %s %s %s  %-40s %-10s %s",
-				p.IndexID,
-				status,
-				priority,
-				due,
-				title,
-				area,
-				taskStr,
-			)
+			output := Output{Projects: jsonProjects, Count: len(filtered)}
+			if rows != nil {
+				output.Projects = rows
+			}
+			if q.Limit > 0 {
+				output.Total = total
+			}
 
-			// Apply line coloring for different statuses
-			switch p.ProjectMetadata.Status {
-			case denote.ProjectStatusCompleted:
-				fmt.Println(completedColor.Sprint(line))
-			case denote.ProjectStatusPaused:
-				fmt.Println(pausedColor.Sprint(line))
-			case denote.ProjectStatusCancelled:
-				fmt.Println(cancelledColor.Sprint(line))
-			default:
-				fmt.Println(line)
+			jsonBytes, err := json.MarshalIndent(output, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal JSON: %w", err)
 			}
+			fmt.Println(string(jsonBytes))
+			return nil
 		}
 
+		if !globalFlags.Quiet {
+			if total > len(filtered) {
+				fmt.Printf("Projects (%d of %d):\n\n", len(filtered), total)
+			} else {
+				fmt.Printf("Projects (%d):\n\n", len(filtered))
+			}
+		}
+
+		if rows != nil {
+			printRows(q.Fields, rows)
+			return nil
+		}
+
+		printProjectLines(filtered, taskCounts)
 		return nil
 	}
 
 	return cmd
 }
 
+// printProjectLines prints one line per project with its status,
+// priority, due date, area and task count.
+func printProjectLines(projects []*denote.Project, taskCounts map[string]int) {
+	// Color setup
+	if globalFlags.NoColor || color.NoColor {
+		color.NoColor = true
+	}
+
+	// Status colors
+	completedColor := color.New(color.FgGreen)
+	pausedColor := color.New(color.FgYellow)
+	cancelledColor := color.New(color.FgRed, color.Faint)
+	priorityHighColor := color.New(color.FgRed, color.Bold)
+	priorityMedColor := color.New(color.FgYellow)
+
+	// Display projects
+	for _, p := range projects {
+		// Status icon
+		status := "◆"
+		switch p.ProjectMetadata.Status {
+		case denote.ProjectStatusCompleted:
+			status = "✓"
+		case denote.ProjectStatusPaused:
+			status = "⏸"
+		case denote.ProjectStatusCancelled:
+			status = "⨯"
+		}
+
+		// Priority with padding
+		priority := "    " // 4 spaces for alignment
+		if p.ProjectMetadata.Priority != "" {
+			pStr := fmt.Sprintf("[%s]", p.ProjectMetadata.Priority)
+			switch p.ProjectMetadata.Priority {
+			case "p1":
+				priority = priorityHighColor.Sprint(pStr)
+			case "p2":
+				priority = priorityMedColor.Sprint(pStr)
+			default:
+				priority = pStr
+			}
+		}
+
+		// Due date with fixed width
+		due := "            " // 12 spaces for alignment
+		if p.ProjectMetadata.DueDate != "" {
+			dueStr := fmt.Sprintf("[%s]", p.ProjectMetadata.DueDate)
+			if denote.IsOverdue(p.ProjectMetadata.DueDate) && p.ProjectMetadata.Status == denote.ProjectStatusActive {
+				due = color.New(color.FgRed, color.Bold).Sprint(dueStr)
+			} else {
+				due = dueStr
+			}
+		}
+
+		// Title - truncate to 40 chars
+		title := p.Title
+		if len(title) > 40 {
+			title = title[:37] + "..."
+		}
+
+		// Area - truncate to 10 chars
+		area := ""
+		if p.ProjectMetadata.Area != "" {
+			area = p.ProjectMetadata.Area
+			if len(area) > 10 {
+				area = area[:7] + "..."
+			}
+		}
+
+		// Task count
+		taskCount := taskCounts[strconv.Itoa(p.IndexID)]
+		taskStr := fmt.Sprintf("(%d tasks)", taskCount)
+
+		// Build the line with fixed-width columns
+		line := fmt.Sprintf("%3d %s %s %s  %-40s %-10s %s",
+			p.IndexID,
+			status,
+			priority,
+			due,
+			title,
+			area,
+			taskStr,
+		)
+
+		// Apply line coloring for different statuses
+		switch p.ProjectMetadata.Status {
+		case denote.ProjectStatusCompleted:
+			fmt.Println(completedColor.Sprint(line))
+		case denote.ProjectStatusPaused:
+			fmt.Println(pausedColor.Sprint(line))
+		case denote.ProjectStatusCancelled:
+			fmt.Println(cancelledColor.Sprint(line))
+		default:
+			fmt.Println(line)
+		}
+	}
+}
+
 // projectTasksCommand shows tasks for a specific project
 func projectTasksCommand(cfg *config.Config) *Command {
 	var (
@@ -657,7 +794,7 @@ func projectTasksCommand(cfg *config.Config) *Command {
 }
 
 // projectUpdateCommand updates project metadata
-func projectUpdateCommand(cfg *config.Config) *Command {
+func projectUpdateCommand(cfg *config.Config, op *denote.Op) *Command {
 	var (
 		title        string
 		priority     string
@@ -803,7 +940,7 @@ func projectUpdateCommand(cfg *config.Config) *Command {
 			}
 
 			if changed {
-				if err := denote.UpdateProjectFile(p.FilePath, p); err != nil {
+				if err := denote.UpdateProjectFile(op, p.FilePath, p); err != nil {
 					fmt.Fprintf(os.Stderr, "Failed to update project ID %d: %v\n", id, err)
 					continue
 				}
@@ -825,7 +962,7 @@ func projectUpdateCommand(cfg *config.Config) *Command {
 }
 
 // projectLogCommand adds or deletes a timestamped log entry on a project
-func projectLogCommand(cfg *config.Config) *Command {
+func projectLogCommand(cfg *config.Config, op *denote.Op) *Command {
 	var deleteLine string
 
 	cmd := &Command{
@@ -848,7 +985,7 @@ func projectLogCommand(cfg *config.Config) *Command {
 		}
 
 		if deleteLine != "" {
-			if err := denote.DeleteLogEntry(p.FilePath, deleteLine); err != nil {
+			if err := denote.DeleteLogEntry(op, p.FilePath, deleteLine); err != nil {
 				return fmt.Errorf("failed to delete log entry: %v", err)
 			}
 			if !globalFlags.Quiet {
@@ -863,7 +1000,7 @@ func projectLogCommand(cfg *config.Config) *Command {
 
 		message := strings.Join(args[1:], " ")
 
-		if err := denote.AddLogEntry(p.FilePath, message); err != nil {
+		if err := denote.AddLogEntry(op, p.FilePath, message); err != nil {
 			return fmt.Errorf("failed to add log entry: %v", err)
 		}
 		if !globalFlags.Quiet {
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/search"
+)
+
+// SearchCommand returns the full-text search command
+func SearchCommand(cfg *config.Config) *Command {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	limit := fs.Int("limit", 20, "Maximum number of results (0 for all)")
+	fileType := fs.String("type", "", "Only search tasks or projects (task, project)")
+	includeArchived := fs.Bool("include-archived", false, "Also search archived tasks and projects")
+
+	return &Command{
+		Name:        "search",
+		Usage:       "atask search <terms> [--limit n] [--type task|project] [--include-archived]",
+		Description: "Full-text search of titles, tags and bodies, best matches first",
+		Flags:       fs,
+		Run: func(cmd *Command, args []string) error {
+			text := strings.TrimSpace(strings.Join(args, " "))
+			if text == "" {
+				return fmt.Errorf("search terms required")
+			}
+			if *fileType != "" && *fileType != denote.TypeTask && *fileType != denote.TypeProject {
+				return fmt.Errorf("invalid type %q (use task or project)", *fileType)
+			}
+			if len(search.Tokenize(text)) == 0 {
+				return fmt.Errorf("no searchable terms in %q (common words like \"the\" are not indexed)", text)
+			}
+
+			idx, err := search.Open(cfg.NotesDirectory)
+			if err != nil {
+				return fmt.Errorf("failed to open search index: %w", err)
+			}
+
+			results := []search.Result{}
+			for _, r := range idx.Search(text) {
+				if *fileType != "" && r.Type != *fileType {
+					continue
+				}
+				if r.Archived && !*includeArchived {
+					continue
+				}
+				results = append(results, r)
+			}
+			total := len(results)
+			if *limit > 0 && len(results) > *limit {
+				results = results[:*limit]
+			}
+
+			if globalFlags.JSON {
+				data, err := json.MarshalIndent(map[string]interface{}{
+					"query":   text,
+					"results": results,
+					"count":   total,
+				}, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if len(results) == 0 {
+				if !globalFlags.Quiet {
+					fmt.Printf("No matches for %q\n", text)
+				}
+				return nil
+			}
+
+			if globalFlags.NoColor || color.NoColor {
+				color.NoColor = true
+			}
+			matchColor := color.New(color.FgYellow, color.Bold)
+			mark := func(s string) string {
+				if color.NoColor {
+					return "**" + s + "**"
+				}
+				return matchColor.Sprint(s)
+			}
+
+			if !globalFlags.Quiet {
+				if total > len(results) {
+					fmt.Printf("Matches for %q (%d of %d):\n\n", text, len(results), total)
+				} else {
+					fmt.Printf("Matches for %q (%d):\n\n", text, total)
+				}
+			}
+			for _, r := range results {
+				title := r.Title
+				if r.Archived {
+					title += " (archived)"
+				}
+				fmt.Printf("%3d %-8s %-50s %6.2f\n", r.ID, r.Type, title, r.Score)
+				if r.Snippet != "" && !globalFlags.Quiet {
+					fmt.Printf("    %s\n\n", search.Highlight(r.Snippet, r.Highlights, mark))
+				}
+			}
+			return nil
+		},
+	}
+}
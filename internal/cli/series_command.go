@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/task"
+)
+
+// SeriesCommand returns the recurring series command
+func SeriesCommand(cfg *config.Config) *Command {
+	cmd := &Command{
+		Name:        "series",
+		Usage:       "atask series <command>",
+		Description: "Inspect recurring task series",
+	}
+
+	cmd.Subcommands = []*Command{
+		seriesShowCommand(cfg),
+	}
+
+	return cmd
+}
+
+func seriesShowCommand(cfg *config.Config) *Command {
+	return &Command{
+		Name:        "show",
+		Usage:       "atask series show <task-id|series-id>",
+		Description: "Show the history, on-time rate and streak of a recurring task",
+		Flags:       flag.NewFlagSet("series-show", flag.ContinueOnError),
+		Run: func(cmd *Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("task ID or series ID required")
+			}
+
+			// Any instance identifies its series; otherwise take the
+			// argument as a series ID, which outlives archived instances.
+			seriesID := args[0]
+			if t, err := lookupTask(cfg.NotesDirectory, args[0]); err == nil {
+				if t.TaskMetadata.Recur == "" && t.TaskMetadata.SeriesID == "" {
+					return fmt.Errorf("task %d is not part of a recurring series", t.IndexID)
+				}
+				seriesID = t.Series()
+			}
+
+			s, err := task.FindSeries(cfg.NotesDirectory, seriesID)
+			if err != nil {
+				return err
+			}
+
+			if globalFlags.JSON {
+				data, err := json.MarshalIndent(s, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if globalFlags.NoColor || color.NoColor {
+				color.NoColor = true
+			}
+			outcomeColors := map[string]*color.Color{
+				task.OutcomeOnTime:  color.New(color.FgGreen),
+				task.OutcomeLate:    color.New(color.FgYellow),
+				task.OutcomeSkipped: color.New(color.FgHiBlack),
+				task.OutcomePending: color.New(color.FgCyan),
+			}
+
+			fmt.Printf("Series: %s", s.Title)
+			if s.Recur != "" {
+				fmt.Printf(" (%s)", s.Recur)
+			}
+			fmt.Printf("\nSeries ID: %s\n\n", s.ID)
+
+			fmt.Printf("  %-4s %-10s  %-10s  %s\n", "ID", "Due", "Completed", "Outcome")
+			for _, e := range s.Entries {
+				outcome := e.Outcome
+				if e.Outcome == task.OutcomeLate {
+					outcome = fmt.Sprintf("late (%dd)", e.DaysLate)
+				} else if e.Outcome == task.OutcomePending {
+					outcome = e.Status
+				}
+				completed := e.CompletedAt
+				if completed == "" {
+					completed = "-"
+				}
+				fmt.Printf("  %-4d %-10s  %-10s  %s\n", e.IndexID, e.DueDate, completed,
+					outcomeColors[e.Outcome].Sprint(outcome))
+			}
+
+			finished := s.OnTime + s.Late + s.Skipped
+			fmt.Println()
+			if finished == 0 {
+				fmt.Println("No finished instances yet.")
+				return nil
+			}
+			fmt.Printf("On time: %d of %d (%.0f%%), %d late, %d skipped\n",
+				s.OnTime, finished, s.OnTimeRate*100, s.Late, s.Skipped)
+			fmt.Printf("Streak:  %d on time in a row\n", s.Streak)
+			return nil
+		},
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/mph-llm-experiments/acore"
 	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/search"
 )
 
 func SyncCommand(cfg *config.Config) *Command {
@@ -44,6 +45,9 @@ func SyncCommand(cfg *config.Config) *Command {
 			if err != nil {
 				return fmt.Errorf("sync failed: %w", err)
 			}
+			if direction == "pull" {
+				refreshSearch(cfg, result)
+			}
 
 			if !globalFlags.Quiet {
 				printSyncResult(result, direction)
@@ -91,8 +95,21 @@ func SyncOnStartup(cfg *config.Config) {
 		return
 	}
 
-	if _, err := acore.SyncApp(local, remote, "pull", acore.SyncOpts{Delete: false}); err != nil {
+	result, err := acore.SyncApp(local, remote, "pull", acore.SyncOpts{Delete: false})
+	if err != nil {
 		log.Printf("sync pull: %v", err)
+		return
+	}
+	refreshSearch(cfg, result)
+}
+
+// refreshSearch reindexes the files a pull wrote or deleted. Pulled files
+// bypass the undo journal, which is how the search index otherwise learns
+// about changes.
+func refreshSearch(cfg *config.Config, result *acore.SyncResult) {
+	paths := append(append([]string{}, result.Pushed...), result.Deleted...)
+	if err := search.Refresh(cfg.NotesDirectory, paths); err != nil {
+		log.Printf("search index: %v", err)
 	}
 }
 
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/fatih/color"
@@ -17,11 +19,12 @@ import (
 	"github.com/mph-llm-experiments/atask/internal/denote"
 	"github.com/mph-llm-experiments/atask/internal/query"
 	"github.com/mph-llm-experiments/atask/internal/recurrence"
+	searchpkg "github.com/mph-llm-experiments/atask/internal/search"
 	"github.com/mph-llm-experiments/atask/internal/task"
 )
 
 // TaskCommand creates the task command with all subcommands
-func TaskCommand(cfg *config.Config) *Command {
+func TaskCommand(cfg *config.Config, op *denote.Op) *Command {
 	cmd := &Command{
 		Name:        "task",
 		Usage:       "atask task <command> [options]",
@@ -29,16 +32,17 @@ func TaskCommand(cfg *config.Config) *Command {
 	}
 
 	cmd.Subcommands = []*Command{
-		taskNewCommand(cfg),
+		taskNewCommand(cfg, op),
 		taskListCommand(cfg),
 		taskShowCommand(cfg),
 		taskQueryCommand(cfg),
-		taskUpdateCommand(cfg),
-		taskBatchUpdateCommand(cfg),
-		taskDoneCommand(cfg),
-		taskLogCommand(cfg),
-		taskEditCommand(cfg),
-		taskDeleteCommand(cfg),
+		taskUpdateCommand(cfg, op),
+		taskBatchUpdateCommand(cfg, op),
+		taskDoneCommand(cfg, op),
+		taskSkipCommand(cfg, op),
+		taskLogCommand(cfg, op),
+		taskEditCommand(cfg, op),
+		taskDeleteCommand(cfg, op),
 	}
 
 	return cmd
@@ -55,7 +59,7 @@ func lookupTask(dir string, identifier string) (*denote.Task, error) {
 }
 
 // taskNewCommand creates a new task
-func taskNewCommand(cfg *config.Config) *Command {
+func taskNewCommand(cfg *config.Config, op *denote.Op) *Command {
 	var (
 		priority string
 		due      string
@@ -80,7 +84,7 @@ func taskNewCommand(cfg *config.Config) *Command {
 	cmd.Flags.StringVar(&project, "project", "", "Project name or ID")
 	cmd.Flags.IntVar(&estimate, "estimate", 0, "Time estimate")
 	cmd.Flags.StringVar(&tags, "tags", "", "Comma-separated tags")
-	cmd.Flags.StringVar(&recur, "recur", "", "Recurrence pattern (daily, weekly, monthly, yearly, every Nd/Nw/Nm/Ny, every mon,wed,fri)")
+	cmd.Flags.StringVar(&recur, "recur", "", "Recurrence pattern (daily, weekly, monthly, yearly, every Nd/Nw/Nm/Ny, every mon,wed,fri, every weekday, every 2nd tue, monthly on the 15th, every quarter, after 3d, or RRULE:...; add workdays or shift to next workday, and end with until:YYYY-MM-DD or count:N to limit)")
 
 	cmd.Run = func(c *Command, args []string) error {
 		if len(args) == 0 {
@@ -98,75 +102,19 @@ func taskNewCommand(cfg *config.Config) *Command {
 			}
 		}
 
-		// Validate recurrence pattern if provided
-		var recurPattern string
-		if recur != "" {
-			if due == "" {
-				return fmt.Errorf("--due is required when --recur is set")
-			}
-			var err error
-			recurPattern, err = recurrence.ParsePattern(recur)
-			if err != nil {
-				return fmt.Errorf("invalid recurrence pattern: %v", err)
-			}
-		}
-
-		// Parse due date if provided
-		var dueDate string
-		if due != "" {
-			parsed, err := denote.ParseNaturalDate(due)
-			if err != nil {
-				return fmt.Errorf("invalid due date: %v", err)
-			}
-			dueDate = parsed
-		}
-
 		// Create the task (use global area flag)
-		taskFile, err := task.CreateTask(cfg.NotesDirectory, title, "", tagList, globalFlags.Area)
+		final, err := task.NewService(cfg.NotesDirectory, op).Create(task.CreateRequest{
+			Title:    title,
+			Priority: priority,
+			Due:      due,
+			Area:     globalFlags.Area,
+			Project:  project,
+			Tags:     tagList,
+			Estimate: estimate,
+			Recur:    recur,
+		})
 		if err != nil {
-			return fmt.Errorf("failed to create task: %v", err)
-		}
-
-		// Update metadata if provided
-		if priority != "" || dueDate != "" || project != "" || estimate > 0 || recurPattern != "" {
-			t, err := denote.ParseTaskFile(taskFile.FilePath)
-			if err != nil {
-				return fmt.Errorf("failed to read created task: %v", err)
-			}
-
-			if priority != "" {
-				t.TaskMetadata.Priority = priority
-			}
-			if dueDate != "" {
-				t.TaskMetadata.DueDate = dueDate
-			}
-			if project != "" {
-				projectNum, err := strconv.Atoi(project)
-				if err != nil {
-					return fmt.Errorf("invalid project ID: %s (must be a numeric index_id)", project)
-				}
-				p, err := task.FindProjectByID(cfg.NotesDirectory, projectNum)
-				if err != nil {
-					return fmt.Errorf("project %d not found", projectNum)
-				}
-				t.TaskMetadata.ProjectID = strconv.Itoa(p.IndexID)
-			}
-			if estimate > 0 {
-				t.TaskMetadata.Estimate = estimate
-			}
-			if recurPattern != "" {
-				t.TaskMetadata.Recur = recurPattern
-			}
-
-			if err := task.UpdateTaskFile(t.FilePath, t); err != nil {
-				return fmt.Errorf("failed to update task metadata: %v", err)
-			}
-		}
-
-		// Reload to get final state (after any metadata updates)
-		final, err := denote.ParseTaskFile(taskFile.FilePath)
-		if err != nil {
-			final = taskFile
+			return err
 		}
 
 		if globalFlags.JSON {
@@ -228,6 +176,9 @@ func taskShowCommand(cfg *config.Config) *Command {
 				if denote.IsOverdue(t.TaskMetadata.DueDate) && t.TaskMetadata.Status != denote.TaskStatusDone {
 					dueStr += " (OVERDUE)"
 				}
+				if t.TaskMetadata.ScheduledDate != "" {
+					dueStr += " (shifted from " + t.TaskMetadata.ScheduledDate + ")"
+				}
 				fmt.Printf("  Due:      %s\n", dueStr)
 			}
 			if t.TaskMetadata.StartDate != "" {
@@ -255,6 +206,9 @@ func taskShowCommand(cfg *config.Config) *Command {
 			if t.TaskMetadata.Recur != "" {
 				fmt.Printf("  Recur:    %s\n", t.TaskMetadata.Recur)
 			}
+			if t.TaskMetadata.SeriesID != "" {
+				fmt.Printf("  Series:   %s\n", t.TaskMetadata.SeriesID)
+			}
 			fmt.Println()
 
 			if t.Created != "" {
@@ -263,6 +217,15 @@ func taskShowCommand(cfg *config.Config) *Command {
 			if t.Modified != "" {
 				fmt.Printf("  Modified: %s\n", t.Modified)
 			}
+			if t.TaskMetadata.CompletedAt != "" {
+				fmt.Printf("  Done at:  %s\n", t.TaskMetadata.CompletedAt)
+			}
+			if len(t.TaskMetadata.StatusHistory) > 0 {
+				fmt.Printf("\n  History:\n")
+				for _, change := range t.TaskMetadata.StatusHistory {
+					fmt.Printf("    %s  %s\n", change.At, change.Status)
+				}
+			}
 
 			var tagStrs []string
 			for _, tag := range t.Tags {
@@ -315,8 +278,8 @@ func taskListCommand(cfg *config.Config) *Command {
 
 	cmd := &Command{
 		Name:        "list",
-		Usage:       "atask task list [options]",
-		Description: "List tasks",
+		Usage:       "atask task list [options] [@saved-query]",
+		Description: "List tasks, or the results of a saved query",
 		Flags:       flag.NewFlagSet("task-list", flag.ExitOnError),
 	}
 
@@ -331,7 +294,7 @@ func taskListCommand(cfg *config.Config) *Command {
 	cmd.Flags.StringVar(&search, "search", "", "Search in task content (full-text)")
 	cmd.Flags.StringVar(&plannedFor, "planned-for", "", "Filter by planned_for date (today, YYYY-MM-DD, or any)")
 	cmd.Flags.StringVar(&tag, "tag", "", "Filter by tag")
-	cmd.Flags.StringVar(&sortBy, "sort", "modified", "Sort by: modified, priority, due, created")
+	cmd.Flags.StringVar(&sortBy, "sort", "modified", "Sort by: modified, priority, due, project, estimate, title, created")
 	cmd.Flags.BoolVar(&reverse, "reverse", false, "Reverse sort order")
 
 	cmd.Flags.BoolVar(&all, "a", false, "Show all tasks (short)")
@@ -343,7 +306,31 @@ func taskListCommand(cfg *config.Config) *Command {
 			return fmt.Errorf("TUI integration not yet implemented")
 		}
 
+		// A saved query replaces the filter flags
+		if len(args) > 0 {
+			if !strings.HasPrefix(args[0], "@") {
+				return fmt.Errorf("unexpected argument %q (use @name to list a saved query)", args[0])
+			}
+			q, err := query.ParseFor(strings.Join(args, " "), cfg, query.TaskRecords)
+			if err != nil {
+				return queryError("query parse error", err)
+			}
+			sortBy, reverse = savedQuerySort(cfg, q, c.Flags, sortBy, reverse)
+			return runTaskQuery(cfg, q, sortBy, reverse, false)
+		}
+
 		scanner := denote.NewScanner(cfg.NotesDirectory)
+		// --search is answered by the search index, so bodies aren't needed
+		scanner.FrontmatterOnly = true
+
+		var searchHits map[string]bool
+		if search != "" {
+			idx, err := searchpkg.Open(cfg.NotesDirectory)
+			if err != nil {
+				return fmt.Errorf("failed to open search index: %w", err)
+			}
+			searchHits = idx.Matches(search)
+		}
 
 		// Get all projects for name lookup and hidden status
 		projects, _ := scanner.FindProjects()
@@ -400,10 +387,8 @@ func taskListCommand(cfg *config.Config) *Command {
 			if tag != "" && !t.HasTag(tag) {
 				continue
 			}
-			if search != "" {
-				if !strings.Contains(strings.ToLower(t.Content), strings.ToLower(search)) {
-					continue
-				}
+			if search != "" && !searchHits[t.FilePath] {
+				continue
 			}
 			if plannedFor != "" {
 				switch strings.ToLower(plannedFor) {
@@ -424,7 +409,7 @@ func taskListCommand(cfg *config.Config) *Command {
 			tasks = append(tasks, *t)
 		}
 
-		sortTasks(tasks, sortBy, reverse)
+		sortTasks(tasks, sortBy, reverse, projectNames)
 
 		if globalFlags.JSON {
 			type TaskJSON struct {
@@ -432,8 +417,9 @@ func taskListCommand(cfg *config.Config) *Command {
 				ProjectName string `json:"project_name,omitempty"`
 			}
 			type Output struct {
-				Tasks []TaskJSON `json:"tasks"`
-				Count int        `json:"count"`
+				Tasks    []TaskJSON          `json:"tasks"`
+				Count    int                 `json:"count"`
+				Warnings []denote.ParseError `json:"warnings,omitempty"`
 			}
 
 			jsonTasks := make([]TaskJSON, len(tasks))
@@ -444,7 +430,7 @@ func taskListCommand(cfg *config.Config) *Command {
 				}
 			}
 
-			output := Output{Tasks: jsonTasks, Count: len(tasks)}
+			output := Output{Tasks: jsonTasks, Count: len(tasks), Warnings: scanner.ParseErrors()}
 			jsonBytes, err := json.MarshalIndent(output, "", "  ")
 			if err != nil {
 				return fmt.Errorf("failed to marshal JSON: %w", err)
@@ -544,14 +530,34 @@ func taskListCommand(cfg *config.Config) *Command {
 			}
 		}
 
+		printParseWarnings(scanner.ParseErrors())
+
 		return nil
 	}
 
 	return cmd
 }
 
-// sortTasks sorts tasks by the specified field
-func sortTasks(tasks []denote.Task, sortBy string, reverse bool) {
+// printParseWarnings tells the user how many files were skipped because they
+// could not be parsed, so a broken file is visible rather than silently lost.
+func printParseWarnings(errs []denote.ParseError) {
+	if len(errs) == 0 || globalFlags.Quiet {
+		return
+	}
+	noun := "files"
+	if len(errs) == 1 {
+		noun = "file"
+	}
+	fmt.Fprintf(os.Stderr, "\n⚠ %d %s could not be parsed (use --json for details):\n", len(errs), noun)
+	for _, pe := range errs {
+		fmt.Fprintf(os.Stderr, "  %s\n", filepath.Base(pe.Path))
+	}
+}
+
+// sortTasks sorts tasks by the specified field. It accepts the same keys as
+// the TUI and saved queries; projectNames maps project IDs to titles for
+// sorting by project.
+func sortTasks(tasks []denote.Task, sortBy string, reverse bool, projectNames map[string]string) {
 	sort.Slice(tasks, func(i, j int) bool {
 		var less bool
 
@@ -574,6 +580,21 @@ func sortTasks(tasks []denote.Task, sortBy string, reverse bool) {
 				less = di < dj
 			}
 
+		case "project":
+			pi := strings.ToLower(projectNames[tasks[i].TaskMetadata.ProjectID])
+			pj := strings.ToLower(projectNames[tasks[j].TaskMetadata.ProjectID])
+			if pi == "" || pj == "" {
+				less = pi != ""
+			} else {
+				less = pi < pj
+			}
+
+		case "estimate":
+			less = tasks[i].TaskMetadata.Estimate < tasks[j].TaskMetadata.Estimate
+
+		case "title":
+			less = strings.ToLower(tasks[i].Title) < strings.ToLower(tasks[j].Title)
+
 		case "created":
 			less = tasks[i].ID < tasks[j].ID
 
@@ -671,7 +692,7 @@ func parseTaskIDs(args []string) ([]int, error) {
 	return intIDs, nil
 }
 
-func taskUpdateCommand(cfg *config.Config) *Command {
+func taskUpdateCommand(cfg *config.Config, op *denote.Op) *Command {
 	var (
 		title        string
 		priority     string
@@ -796,7 +817,7 @@ func taskUpdateCommand(cfg *config.Config) *Command {
 					fmt.Fprintf(os.Stderr, "Invalid due date for task ID %d: %v\n", t.IndexID, err)
 					continue
 				}
-				t.TaskMetadata.DueDate = parsedDue
+				t.SetDueDate(parsedDue)
 				changed = true
 			}
 			if begin != "" {
@@ -831,7 +852,7 @@ func taskUpdateCommand(cfg *config.Config) *Command {
 				changed = true
 			}
 			if status != "" {
-				t.TaskMetadata.Status = status
+				t.SetStatus(status)
 				changed = true
 			}
 			if clearRecur {
@@ -918,7 +939,7 @@ func taskUpdateCommand(cfg *config.Config) *Command {
 			}
 
 			if changed {
-				if err := task.UpdateTaskFile(t.FilePath, t); err != nil {
+				if err := task.UpdateTaskFile(op, t.FilePath, t); err != nil {
 					fmt.Fprintf(os.Stderr, "Failed to update task ID %d: %v\n", t.IndexID, err)
 					continue
 				}
@@ -960,7 +981,7 @@ func taskUpdateCommand(cfg *config.Config) *Command {
 	return cmd
 }
 
-func taskDoneCommand(cfg *config.Config) *Command {
+func taskDoneCommand(cfg *config.Config, op *denote.Op) *Command {
 	cmd := &Command{
 		Name:        "done",
 		Usage:       "atask task done <task-ids>",
@@ -1010,8 +1031,8 @@ func taskDoneCommand(cfg *config.Config) *Command {
 
 		updated := 0
 		for _, t := range tasksToUpdate {
-			t.TaskMetadata.Status = denote.TaskStatusDone
-			if err := task.UpdateTaskFile(t.FilePath, t); err != nil {
+			t.SetStatus(denote.TaskStatusDone)
+			if err := task.UpdateTaskFile(op, t.FilePath, t); err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to mark task %d as done: %v\n", t.IndexID, err)
 				continue
 			}
@@ -1020,7 +1041,7 @@ func taskDoneCommand(cfg *config.Config) *Command {
 				fmt.Printf("✓ Task ID %d marked as done: %s\n", t.IndexID, t.Title)
 			}
 
-			if err := handleRecurrence(cfg, t); err != nil {
+			if err := handleRecurrence(cfg, op, t); err != nil {
 				fmt.Fprintf(os.Stderr, "Warning: failed to create recurring task for ID %d: %v\n", t.IndexID, err)
 			}
 		}
@@ -1035,7 +1056,46 @@ func taskDoneCommand(cfg *config.Config) *Command {
 	return cmd
 }
 
-func taskLogCommand(cfg *config.Config) *Command {
+func taskSkipCommand(cfg *config.Config, op *denote.Op) *Command {
+	cmd := &Command{
+		Name:        "skip",
+		Usage:       "atask task skip <task-id>",
+		Description: "Skip a recurring task to its next occurrence",
+	}
+
+	cmd.Run = func(c *Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("task ID required")
+		}
+
+		t, err := lookupTask(cfg.NotesDirectory, args[0])
+		if err != nil {
+			return err
+		}
+		if t.TaskMetadata.Recur == "" || t.TaskMetadata.DueDate == "" {
+			return fmt.Errorf("task %d is not a recurring task with a due date", t.IndexID)
+		}
+		if t.TaskMetadata.Status == denote.TaskStatusDone || t.TaskMetadata.Status == denote.TaskStatusDropped {
+			return fmt.Errorf("task %d is already %s", t.IndexID, t.TaskMetadata.Status)
+		}
+
+		// The skipped instance is dropped, which its series history
+		// reports as skipped, and the next one is created as on done.
+		t.SetStatus(denote.TaskStatusDropped)
+		if err := task.UpdateTaskFile(op, t.FilePath, t); err != nil {
+			return fmt.Errorf("failed to skip task %d: %v", t.IndexID, err)
+		}
+		if !globalFlags.Quiet {
+			fmt.Printf("↷ Skipped task ID %d: %s (was due %s)\n", t.IndexID, t.Title, t.TaskMetadata.DueDate)
+		}
+
+		return handleRecurrence(cfg, op, t)
+	}
+
+	return cmd
+}
+
+func taskLogCommand(cfg *config.Config, op *denote.Op) *Command {
 	var deleteLine string
 
 	cmd := &Command{
@@ -1058,7 +1118,7 @@ func taskLogCommand(cfg *config.Config) *Command {
 		}
 
 		if deleteLine != "" {
-			if err := denote.DeleteLogEntry(t.FilePath, deleteLine); err != nil {
+			if err := denote.DeleteLogEntry(op, t.FilePath, deleteLine); err != nil {
 				return fmt.Errorf("failed to delete log entry: %v", err)
 			}
 			if !globalFlags.Quiet {
@@ -1073,7 +1133,7 @@ func taskLogCommand(cfg *config.Config) *Command {
 
 		message := strings.Join(args[1:], " ")
 
-		if err := denote.AddLogEntry(t.FilePath, message); err != nil {
+		if err := denote.AddLogEntry(op, t.FilePath, message); err != nil {
 			return fmt.Errorf("failed to add log entry: %v", err)
 		}
 		if !globalFlags.Quiet {
@@ -1085,7 +1145,7 @@ func taskLogCommand(cfg *config.Config) *Command {
 	return cmd
 }
 
-func taskEditCommand(cfg *config.Config) *Command {
+func taskEditCommand(cfg *config.Config, op *denote.Op) *Command {
 	return &Command{
 		Name:        "edit",
 		Usage:       "atask task edit <task-id>",
@@ -1105,20 +1165,28 @@ func taskEditCommand(cfg *config.Config) *Command {
 				editor = "vi"
 			}
 
+			before, err := os.ReadFile(t.FilePath)
+			if err != nil {
+				return fmt.Errorf("failed to read task: %w", err)
+			}
+
 			cmd := exec.Command(editor, t.FilePath)
 			cmd.Stdin = os.Stdin
 			cmd.Stdout = os.Stdout
 			cmd.Stderr = os.Stderr
-			return cmd.Run()
+			if err := cmd.Run(); err != nil {
+				return err
+			}
+			return denote.RecordChange(op, t.FilePath, before)
 		},
 	}
 }
 
-func taskDeleteCommand(cfg *config.Config) *Command {
+func taskDeleteCommand(cfg *config.Config, op *denote.Op) *Command {
 	return &Command{
 		Name:        "delete",
 		Usage:       "atask task delete <task-id> [--confirm]",
-		Description: "Delete a task file",
+		Description: "Move a task to the trash",
 		Run: func(c *Command, args []string) error {
 			if len(args) == 0 {
 				return fmt.Errorf("usage: atask task delete <task-id> [--confirm]")
@@ -1146,7 +1214,8 @@ func taskDeleteCommand(cfg *config.Config) *Command {
 				return fmt.Errorf("use --confirm to delete task '%s' (%s)", t.Title, t.FilePath)
 			}
 
-			if err := os.Remove(t.FilePath); err != nil {
+			entry, err := task.Trash(op, cfg.NotesDirectory, t.FilePath)
+			if err != nil {
 				return fmt.Errorf("failed to delete task: %w", err)
 			}
 
@@ -1156,6 +1225,7 @@ func taskDeleteCommand(cfg *config.Config) *Command {
 					"index_id": t.IndexID,
 					"title":    t.Title,
 					"file":     t.FilePath,
+					"trash":    entry.Path,
 				}
 				data, _ := json.MarshalIndent(result, "", "  ")
 				fmt.Println(string(data))
@@ -1163,7 +1233,7 @@ func taskDeleteCommand(cfg *config.Config) *Command {
 			}
 
 			if !globalFlags.Quiet {
-				fmt.Printf("Deleted task #%d: %s\n", t.IndexID, t.Title)
+				fmt.Printf("Deleted task #%d: %s (restore with 'atask restore %d')\n", t.IndexID, t.Title, t.IndexID)
 			}
 			return nil
 		},
@@ -1173,167 +1243,275 @@ func taskDeleteCommand(cfg *config.Config) *Command {
 func taskQueryCommand(cfg *config.Config) *Command {
 	var sortBy string
 	var reverse bool
+	var includeArchived bool
 
 	cmd := &Command{
 		Name:        "query",
-		Usage:       "atask query <expression> [options]",
+		Usage:       "atask query <expression> [ORDER BY ...] [LIMIT n] [FIELDS ...] [options]",
 		Description: "Query tasks with complex filter expressions",
 		Flags:       flag.NewFlagSet("task-query", flag.ExitOnError),
 	}
 
-	cmd.Flags.StringVar(&sortBy, "sort", "modified", "Sort by: priority, due, created, modified")
+	cmd.Flags.StringVar(&sortBy, "sort", "modified", "Sort by: priority, due, project, estimate, title, created, modified")
 	cmd.Flags.BoolVar(&reverse, "r", false, "Reverse sort order")
 	cmd.Flags.BoolVar(&reverse, "reverse", false, "Reverse sort order")
+	cmd.Flags.BoolVar(&includeArchived, "include-archived", false, "Also search tasks in archive/")
 
 	cmd.Run = func(c *Command, args []string) error {
 		if len(args) == 0 {
-			return fmt.Errorf("query expression required\n\nExamples:\n  atask query \"status:open AND priority:p1\"\n  atask query \"area:work AND (priority:p1 OR priority:p2)\"\n  atask query \"due:soon AND NOT status:done\"")
+			return fmt.Errorf("query expression required\n\nExamples:\n  atask query @next\n  atask query \"status:open AND priority:p1\"\n  atask query \"area:work AND (priority:p1 OR priority:p2)\"\n  atask query \"due:soon AND NOT status:done\"\n  atask query \"status:open ORDER BY due, priority LIMIT 10 FIELDS index_id,title,due\"")
 		}
 
-		queryStr := args[0]
-
-		ast, err := query.Parse(queryStr)
+		q, err := query.ParseFor(args[0], cfg, query.TaskRecords)
 		if err != nil {
-			return fmt.Errorf("query parse error: %v", err)
+			return queryError("query parse error", err)
 		}
+		sortBy, reverse = savedQuerySort(cfg, q, c.Flags, sortBy, reverse)
+		return runTaskQuery(cfg, q, sortBy, reverse, includeArchived)
+	}
 
-		scanner := denote.NewScanner(cfg.NotesDirectory)
-		allTasks, err := scanner.FindTasks()
-		if err != nil {
-			return fmt.Errorf("failed to find tasks: %v", err)
-		}
+	return cmd
+}
 
-		projects, _ := scanner.FindProjects()
-		projectNames := make(map[string]string)
-		for _, p := range projects {
-			projectNames[strconv.Itoa(p.IndexID)] = p.Title
-		}
+// savedQuerySort returns the sort settings for q: those of its saved query,
+// unless the sort flags were given explicitly.
+func savedQuerySort(cfg *config.Config, q *query.Query, fs *flag.FlagSet, sortBy string, reverse bool) (string, bool) {
+	if q.Saved == "" {
+		return sortBy, reverse
+	}
+	saved := cfg.Queries[q.Saved]
+	passed := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { passed[f.Name] = true })
+	if saved.Sort != "" && !passed["sort"] && !passed["s"] {
+		sortBy = saved.Sort
+	}
+	if !passed["reverse"] && !passed["r"] {
+		reverse = saved.Reverse
+	}
+	return sortBy, reverse
+}
 
-		var tasks []denote.Task
-		for _, t := range allTasks {
-			if ast.Evaluate(t, cfg) {
-				tasks = append(tasks, *t)
-			}
-		}
+// runTaskQuery prints the tasks matching q, as used by 'atask query' and
+// 'atask list @name'.
+func runTaskQuery(cfg *config.Config, q *query.Query, sortBy string, reverse bool, includeArchived bool) error {
+	scanner := denote.NewScanner(cfg.NotesDirectory)
+	scanner.IncludeArchived = includeArchived
+	allTasks, err := scanner.FindTasks()
+	if err != nil {
+		return fmt.Errorf("failed to find tasks: %v", err)
+	}
+
+	projects, _ := scanner.FindProjects()
+	projectNames := make(map[string]string)
+	for _, p := range projects {
+		projectNames[strconv.Itoa(p.IndexID)] = p.Title
+	}
+	projectsByID := query.ProjectsByID(projects)
 
-		sortTasks(tasks, sortBy, reverse)
+	var tasks []denote.Task
+	for _, t := range allTasks {
+		if q.Match(query.NewTaskRecord(t, projectsByID), cfg) {
+			tasks = append(tasks, *t)
+		}
+	}
 
-		if globalFlags.JSON {
-			type TaskJSON struct {
-				denote.Task
-				ProjectName string `json:"project_name,omitempty"`
-			}
-			type Output struct {
-				Tasks []TaskJSON `json:"tasks"`
-				Count int        `json:"count"`
-			}
+	// ORDER BY takes precedence; the flags break its ties
+	sortTasks(tasks, sortBy, reverse, projectNames)
+	total := len(tasks)
+	tasks = q.Apply(tasks)
 
-			jsonTasks := make([]TaskJSON, len(tasks))
-			for i, t := range tasks {
-				jsonTasks[i] = TaskJSON{
-					Task:        t,
-					ProjectName: projectNames[t.ProjectID],
-				}
-			}
+	type TaskJSON struct {
+		denote.Task
+		ProjectName string `json:"project_name,omitempty"`
+	}
+	jsonTasks := make([]TaskJSON, len(tasks))
+	for i, t := range tasks {
+		jsonTasks[i] = TaskJSON{
+			Task:        t,
+			ProjectName: projectNames[t.ProjectID],
+		}
+	}
 
-			output := Output{Tasks: jsonTasks, Count: len(tasks)}
-			jsonBytes, err := json.MarshalIndent(output, "", "  ")
+	var rows []query.Row
+	if len(q.Fields) > 0 {
+		rows = make([]query.Row, len(jsonTasks))
+		for i, t := range jsonTasks {
+			row, err := q.Project(t)
 			if err != nil {
-				return fmt.Errorf("failed to marshal JSON: %w", err)
+				return fmt.Errorf("failed to project fields: %w", err)
 			}
-			fmt.Println(string(jsonBytes))
-			return nil
+			rows[i] = row
 		}
+	}
 
-		if globalFlags.NoColor || color.NoColor {
-			color.NoColor = true
+	if globalFlags.JSON {
+		type Output struct {
+			Tasks    interface{}         `json:"tasks"`
+			Count    int                 `json:"count"`
+			Total    int                 `json:"total,omitempty"`
+			Warnings []denote.ParseError `json:"warnings,omitempty"`
 		}
 
-		doneColor := color.New(color.FgGreen)
-		overdueColor := color.New(color.FgRed, color.Bold)
-		priorityHighColor := color.New(color.FgRed, color.Bold)
-		priorityMedColor := color.New(color.FgYellow)
+		output := Output{Tasks: jsonTasks, Count: len(tasks), Warnings: scanner.ParseErrors()}
+		if rows != nil {
+			output.Tasks = rows
+		}
+		if q.Limit > 0 {
+			output.Total = total
+		}
+		jsonBytes, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonBytes))
+		return nil
+	}
 
-		if !globalFlags.Quiet {
+	if globalFlags.NoColor || color.NoColor {
+		color.NoColor = true
+	}
+
+	doneColor := color.New(color.FgGreen)
+	overdueColor := color.New(color.FgRed, color.Bold)
+	priorityHighColor := color.New(color.FgRed, color.Bold)
+	priorityMedColor := color.New(color.FgYellow)
+
+	if !globalFlags.Quiet {
+		if total > len(tasks) {
+			fmt.Printf("Tasks (%d of %d):\n\n", len(tasks), total)
+		} else {
 			fmt.Printf("Tasks (%d):\n\n", len(tasks))
 		}
+	}
 
-		for _, t := range tasks {
-			statusIcon := "○"
-			switch t.TaskMetadata.Status {
-			case denote.TaskStatusDone:
-				statusIcon = doneColor.Sprint("✓")
-			case denote.TaskStatusPaused:
-				statusIcon = "⏸"
-			case denote.TaskStatusDropped:
-				statusIcon = "⨯"
-			case denote.TaskStatusDelegated:
-				statusIcon = "→"
-			}
+	if rows != nil {
+		printRows(q.Fields, rows)
+		printParseWarnings(scanner.ParseErrors())
+		return nil
+	}
 
-			priorityStr := "   "
-			if t.TaskMetadata.Priority != "" {
-				switch t.TaskMetadata.Priority {
-				case denote.PriorityP1:
-					priorityStr = priorityHighColor.Sprintf("[%s]", t.TaskMetadata.Priority)
-				case denote.PriorityP2:
-					priorityStr = priorityMedColor.Sprintf("[%s]", t.TaskMetadata.Priority)
-				default:
-					priorityStr = fmt.Sprintf("[%s]", t.TaskMetadata.Priority)
-				}
+	for _, t := range tasks {
+		statusIcon := "○"
+		switch t.TaskMetadata.Status {
+		case denote.TaskStatusDone:
+			statusIcon = doneColor.Sprint("✓")
+		case denote.TaskStatusPaused:
+			statusIcon = "⏸"
+		case denote.TaskStatusDropped:
+			statusIcon = "⨯"
+		case denote.TaskStatusDelegated:
+			statusIcon = "→"
+		}
+
+		priorityStr := "   "
+		if t.TaskMetadata.Priority != "" {
+			switch t.TaskMetadata.Priority {
+			case denote.PriorityP1:
+				priorityStr = priorityHighColor.Sprintf("[%s]", t.TaskMetadata.Priority)
+			case denote.PriorityP2:
+				priorityStr = priorityMedColor.Sprintf("[%s]", t.TaskMetadata.Priority)
+			default:
+				priorityStr = fmt.Sprintf("[%s]", t.TaskMetadata.Priority)
+			}
+		}
+
+		dueStr := "            "
+		if t.TaskMetadata.DueDate != "" {
+			if denote.IsOverdue(t.TaskMetadata.DueDate) && t.TaskMetadata.Status != denote.TaskStatusDone {
+				dueStr = overdueColor.Sprintf("[%s]", t.TaskMetadata.DueDate)
+			} else {
+				dueStr = fmt.Sprintf("[%s]", t.TaskMetadata.DueDate)
 			}
+		}
 
-			dueStr := "            "
-			if t.TaskMetadata.DueDate != "" {
-				if denote.IsOverdue(t.TaskMetadata.DueDate) && t.TaskMetadata.Status != denote.TaskStatusDone {
-					dueStr = overdueColor.Sprintf("[%s]", t.TaskMetadata.DueDate)
-				} else {
-					dueStr = fmt.Sprintf("[%s]", t.TaskMetadata.DueDate)
-				}
-			}
+		title := t.Title
+		if t.TaskMetadata.Recur != "" {
+			title = "↻ " + title
+		}
+		if len(title) > 50 {
+			title = title[:47] + "..."
+		}
 
-			title := t.Title
-			if t.TaskMetadata.Recur != "" {
-				title = "↻ " + title
-			}
-			if len(title) > 50 {
-				title = title[:47] + "..."
-			}
+		areaStr := ""
+		if t.TaskMetadata.Area != "" {
+			areaStr = t.TaskMetadata.Area
+		}
 
-			areaStr := ""
-			if t.TaskMetadata.Area != "" {
-				areaStr = t.TaskMetadata.Area
+		projectName := ""
+		if t.TaskMetadata.ProjectID != "" {
+			if name, ok := projectNames[t.TaskMetadata.ProjectID]; ok && name != "" {
+				projectName = "→ " + name
+			} else {
+				projectName = "→ " + t.TaskMetadata.ProjectID
 			}
+		}
 
-			projectName := ""
-			if t.TaskMetadata.ProjectID != "" {
-				if name, ok := projectNames[t.TaskMetadata.ProjectID]; ok && name != "" {
-					projectName = "→ " + name
-				} else {
-					projectName = "→ " + t.TaskMetadata.ProjectID
-				}
-			}
+		line := fmt.Sprintf("%3d %s %s %s  %-50s %-10s %s",
+			t.IndexID,
+			statusIcon,
+			priorityStr,
+			dueStr,
+			title,
+			areaStr,
+			projectName,
+		)
 
-			line := fmt.Sprintf("%3d %s %s %s  %-50s %-10s %s",
-				t.IndexID,
-				statusIcon,
-				priorityStr,
-				dueStr,
-				title,
-				areaStr,
-				projectName,
-			)
+		fmt.Println(line)
+	}
 
-			fmt.Println(line)
-		}
+	printParseWarnings(scanner.ParseErrors())
 
-		return nil
+	return nil
+}
+
+// queryError reports a query that failed to parse, with a caret under the
+// error position. With --json the error is also printed to stdout as an
+// object, including the position and suggestions, so agents can correct the
+// query and retry.
+func queryError(prefix string, err error) error {
+	pe, ok := err.(*query.ParseError)
+	if globalFlags.JSON {
+		var output interface{} = map[string]interface{}{"error": map[string]string{"message": err.Error()}}
+		if ok {
+			output = map[string]interface{}{"error": pe}
+		}
+		if jsonBytes, jerr := json.MarshalIndent(output, "", "  "); jerr == nil {
+			fmt.Println(string(jsonBytes))
+		}
 	}
+	if !ok {
+		return fmt.Errorf("%s: %v", prefix, err)
+	}
+	caret := "  " + strings.ReplaceAll(pe.Caret(), "\n", "\n  ")
+	return fmt.Errorf("%s: %v\n\n%s", prefix, pe, caret)
+}
 
-	return cmd
+// printRows prints projected query results as aligned columns.
+func printRows(fields []string, rows []query.Row) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(fields, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(row.Keys))
+		for i, k := range row.Keys {
+			switch v := row.Values[k].(type) {
+			case nil:
+				cells[i] = ""
+			case []interface{}:
+				parts := make([]string, len(v))
+				for j, item := range v {
+					parts[j] = fmt.Sprint(item)
+				}
+				cells[i] = strings.Join(parts, ",")
+			default:
+				cells[i] = fmt.Sprint(v)
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	w.Flush()
 }
 
-func taskBatchUpdateCommand(cfg *config.Config) *Command {
+func taskBatchUpdateCommand(cfg *config.Config, op *denote.Op) *Command {
 	var (
 		whereClause string
 		priority    string
@@ -1365,27 +1543,33 @@ func taskBatchUpdateCommand(cfg *config.Config) *Command {
 
 	cmd.Run = func(c *Command, args []string) error {
 		if whereClause == "" {
-			return fmt.Errorf("--where clause required\n\nExample:\n  atask batch-update --where \"status:open AND due:past\" --status paused")
+			return fmt.Errorf("--where clause required\n\nExample:\n  atask batch-update --where \"status:open AND due:overdue\" --status paused")
 		}
 
 		if priority == "" && due == "" && area == "" && project == "" && estimate == -1 && status == "" && recur == "" {
 			return fmt.Errorf("at least one field to update must be specified (--priority, --due, --area, --project, --estimate, --status, or --recur)")
 		}
 
-		ast, err := query.Parse(whereClause)
+		q, err := query.ParseFor(whereClause, cfg, query.TaskRecords)
 		if err != nil {
-			return fmt.Errorf("failed to parse --where clause: %v", err)
+			return queryError("failed to parse --where clause", err)
+		}
+		if q.Filter == nil || len(q.OrderBy) > 0 || q.Limit > 0 || len(q.Fields) > 0 {
+			return fmt.Errorf("--where takes a filter expression without ORDER BY, LIMIT or FIELDS")
 		}
+		ast := q.Filter
 
 		scanner := denote.NewScanner(cfg.NotesDirectory)
 		allTasks, err := scanner.FindTasks()
 		if err != nil {
 			return fmt.Errorf("failed to find tasks: %v", err)
 		}
+		projects, _ := scanner.FindProjects()
+		projectsByID := query.ProjectsByID(projects)
 
 		var matchingTasks []*denote.Task
 		for _, t := range allTasks {
-			if ast.Evaluate(t, cfg) {
+			if ast.Evaluate(query.NewTaskRecord(t, projectsByID), cfg) {
 				matchingTasks = append(matchingTasks, t)
 			}
 		}
@@ -1468,7 +1652,7 @@ func taskBatchUpdateCommand(cfg *config.Config) *Command {
 				changed = true
 			}
 			if due != "" {
-				t.TaskMetadata.DueDate = parsedDue
+				t.SetDueDate(parsedDue)
 				changed = true
 			}
 			if area != "" {
@@ -1494,7 +1678,7 @@ func taskBatchUpdateCommand(cfg *config.Config) *Command {
 				changed = true
 			}
 			if status != "" {
-				t.TaskMetadata.Status = status
+				t.SetStatus(status)
 				changed = true
 			}
 			if clearRecur {
@@ -1506,14 +1690,14 @@ func taskBatchUpdateCommand(cfg *config.Config) *Command {
 			}
 
 			if changed {
-				if err := task.UpdateTaskFile(t.FilePath, t); err != nil {
+				if err := task.UpdateTaskFile(op, t.FilePath, t); err != nil {
 					fmt.Fprintf(os.Stderr, "Failed to update task %d: %v\n", t.IndexID, err)
 					continue
 				}
 				updated++
 
 				if status == denote.TaskStatusDone {
-					if err := handleRecurrence(cfg, t); err != nil {
+					if err := handleRecurrence(cfg, op, t); err != nil {
 						fmt.Fprintf(os.Stderr, "Warning: failed to create recurring task for ID %d: %v\n", t.IndexID, err)
 					}
 				}
@@ -1528,24 +1712,30 @@ func taskBatchUpdateCommand(cfg *config.Config) *Command {
 }
 
 // handleRecurrence checks if a completed task has a recurrence pattern and creates the next instance.
-func handleRecurrence(cfg *config.Config, t *denote.Task) error {
+func handleRecurrence(cfg *config.Config, op *denote.Op, t *denote.Task) error {
 	if t.TaskMetadata.Recur == "" || t.TaskMetadata.DueDate == "" {
 		return nil
 	}
 
-	currentDue, err := time.ParseInLocation("2006-01-02", t.TaskMetadata.DueDate, time.Now().Location())
+	currentDue, err := time.ParseInLocation("2006-01-02", t.RecurrenceDate(), time.Now().Location())
 	if err != nil {
-		return fmt.Errorf("failed to parse due date %q: %w", t.TaskMetadata.DueDate, err)
+		return fmt.Errorf("failed to parse due date %q: %w", t.RecurrenceDate(), err)
 	}
 
-	nextDue, err := recurrence.NextDueDate(t.TaskMetadata.Recur, currentDue)
+	next, err := recurrence.NextInstance(t.TaskMetadata.Recur, currentDue, t.CompletedTime())
+	if err == recurrence.ErrSeriesEnded {
+		if !globalFlags.Quiet {
+			fmt.Printf("↻ Recurring series ended: no further instance of %s\n", t.Title)
+		}
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to compute next due date: %w", err)
 	}
 
-	newDueStr := nextDue.Format("2006-01-02")
+	newDueStr := next.Due.Format("2006-01-02")
 
-	newTask, err := task.CloneTaskForRecurrence(cfg.NotesDirectory, t, newDueStr)
+	newTask, err := task.CloneTaskForRecurrence(op, cfg.NotesDirectory, t, next)
 	if err != nil {
 		return fmt.Errorf("failed to clone task: %w", err)
 	}
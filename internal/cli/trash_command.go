@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/task"
+)
+
+// TrashCommand returns the trash command
+func TrashCommand(cfg *config.Config) *Command {
+	cmd := &Command{
+		Name:        "trash",
+		Usage:       "atask trash <command>",
+		Description: "Manage deleted tasks and projects",
+	}
+
+	cmd.Subcommands = []*Command{
+		trashListCommand(cfg),
+		trashEmptyCommand(cfg),
+	}
+
+	return cmd
+}
+
+func trashListCommand(cfg *config.Config) *Command {
+	return &Command{
+		Name:        "list",
+		Usage:       "atask trash list",
+		Description: "List deleted tasks and projects",
+		Flags:       flag.NewFlagSet("trash-list", flag.ContinueOnError),
+		Run: func(cmd *Command, args []string) error {
+			entries, err := task.ListTrash(cfg.NotesDirectory)
+			if err != nil {
+				return err
+			}
+
+			if globalFlags.JSON {
+				if entries == nil {
+					entries = []task.TrashEntry{}
+				}
+				data, err := json.MarshalIndent(entries, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if globalFlags.Quiet {
+				return nil
+			}
+
+			if len(entries) == 0 {
+				fmt.Println("Trash is empty.")
+				return nil
+			}
+
+			for _, e := range entries {
+				line := fmt.Sprintf("%-4d %-8s %s  %s", e.IndexID, e.Type, e.DeletedAt.Format("2006-01-02 15:04"), e.Title)
+				if len(e.Cleared) > 0 {
+					line += fmt.Sprintf(" (cleared from %d tasks)", len(e.Cleared))
+				}
+				fmt.Println(line)
+			}
+			return nil
+		},
+	}
+}
+
+func trashEmptyCommand(cfg *config.Config) *Command {
+	fs := flag.NewFlagSet("trash-empty", flag.ContinueOnError)
+	olderThan := fs.String("older-than", "", "Only remove items deleted longer ago than this (e.g. 30d, 2w, 12h)")
+
+	return &Command{
+		Name:        "empty",
+		Usage:       "atask trash empty [--older-than 30d]",
+		Description: "Permanently remove items from the trash",
+		Flags:       fs,
+		Run: func(cmd *Command, args []string) error {
+			var age time.Duration
+			if *olderThan != "" {
+				var err error
+				if age, err = parseAge(*olderThan); err != nil {
+					return err
+				}
+			}
+
+			removed, err := task.EmptyTrash(cfg.NotesDirectory, age)
+			if err != nil {
+				return err
+			}
+
+			if globalFlags.JSON {
+				if removed == nil {
+					removed = []task.TrashEntry{}
+				}
+				data, _ := json.MarshalIndent(map[string]interface{}{
+					"removed": removed,
+					"count":   len(removed),
+				}, "", "  ")
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if !globalFlags.Quiet {
+				fmt.Printf("Removed %d items from the trash\n", len(removed))
+			}
+			return nil
+		},
+	}
+}
+
+// RestoreCommand returns the restore command
+func RestoreCommand(cfg *config.Config, op *denote.Op) *Command {
+	return &Command{
+		Name:        "restore",
+		Usage:       "atask restore <id>",
+		Description: "Restore a deleted task or project from the trash",
+		Run: func(cmd *Command, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("usage: atask restore <id>")
+			}
+
+			entry, relinked, err := task.RestoreFromTrash(op, cfg.NotesDirectory, args[0])
+			if err != nil {
+				return err
+			}
+
+			if globalFlags.JSON {
+				if relinked == nil {
+					relinked = []task.ClearedRef{}
+				}
+				data, _ := json.MarshalIndent(map[string]interface{}{
+					"restored": true,
+					"index_id": entry.IndexID,
+					"type":     entry.Type,
+					"title":    entry.Title,
+					"file":     entry.OriginalPath,
+					"relinked": relinked,
+				}, "", "  ")
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if !globalFlags.Quiet {
+				fmt.Printf("Restored %s #%d: %s\n", entry.Type, entry.IndexID, entry.Title)
+				if len(relinked) > 0 {
+					fmt.Printf("Re-linked %d tasks to the project\n", len(relinked))
+				}
+				if skipped := len(entry.Cleared) - len(relinked); skipped > 0 {
+					fmt.Printf("%d tasks were not re-linked (missing or assigned to another project)\n", skipped)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// parseAge parses durations such as 30d, 2w or 12h. Plain Go durations
+// (90m, 1h30m) are accepted too.
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if n := len(s); n > 1 {
+		unit := 24 * time.Hour
+		switch s[n-1] {
+		case 'w':
+			unit *= 7
+			fallthrough
+		case 'd':
+			v, err := strconv.Atoi(s[:n-1])
+			if err == nil && v >= 0 {
+				return time.Duration(v) * unit, nil
+			}
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 30d, 2w, 12h)", s)
+	}
+	return d, nil
+}
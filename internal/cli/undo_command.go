@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+// UndoCommand returns the undo command
+func UndoCommand(cfg *config.Config) *Command {
+	fs := flag.NewFlagSet("undo", flag.ContinueOnError)
+	force := fs.Bool("force", false, "Undo even if files changed since (discards those changes)")
+
+	return &Command{
+		Name:  "undo",
+		Usage: "atask undo [n] [--force]",
+		Description: `Undo the last n changes (default 1)
+
+Every command and TUI action that writes files is journaled in
+.atask-journal. Undo restores the files an operation touched, newest
+operation first; a batch-update counts as one operation. An operation
+whose files were edited afterwards is refused unless --force is given.`,
+		Flags: fs,
+		Run: func(cmd *Command, args []string) error {
+			n := 1
+			if len(args) > 0 {
+				v, err := strconv.Atoi(args[0])
+				if err != nil || v < 1 {
+					return fmt.Errorf("invalid count %q: must be a positive number", args[0])
+				}
+				n = v
+			}
+
+			undone, err := denote.Undo(cfg.NotesDirectory, n, *force)
+
+			if globalFlags.JSON {
+				if undone == nil {
+					undone = []*denote.JournalOp{}
+				}
+				result := map[string]interface{}{
+					"undone": undone,
+					"count":  len(undone),
+				}
+				if err != nil {
+					result["error"] = err.Error()
+				}
+				data, _ := json.MarshalIndent(result, "", "  ")
+				fmt.Println(string(data))
+				return err
+			}
+
+			if !globalFlags.Quiet {
+				for _, op := range undone {
+					fmt.Printf("Undid: %s (%d files)\n", op.Label, len(op.Files))
+				}
+				if err == nil && len(undone) == 0 {
+					fmt.Println("Nothing to undo.")
+				}
+			}
+			return err
+		},
+	}
+}
+
+// HistoryCommand returns the history command
+func HistoryCommand(cfg *config.Config) *Command {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	limit := fs.Int("limit", 20, "Number of operations to show (0 for all)")
+
+	return &Command{
+		Name:        "history",
+		Usage:       "atask history [--limit 20]",
+		Description: "Show journaled changes, newest first",
+		Flags:       fs,
+		Run: func(cmd *Command, args []string) error {
+			ops, err := denote.History(cfg.NotesDirectory)
+			if err != nil {
+				return err
+			}
+
+			// Newest first
+			for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+				ops[i], ops[j] = ops[j], ops[i]
+			}
+			if *limit > 0 && len(ops) > *limit {
+				ops = ops[:*limit]
+			}
+
+			if globalFlags.JSON {
+				if ops == nil {
+					ops = []*denote.JournalOp{}
+				}
+				data, err := json.MarshalIndent(ops, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if globalFlags.Quiet {
+				return nil
+			}
+
+			if len(ops) == 0 {
+				fmt.Println("No changes recorded.")
+				return nil
+			}
+
+			for _, op := range ops {
+				mark := " "
+				if op.Undone {
+					mark = "↶"
+				}
+				fmt.Printf("%s %s  %s\n", mark, op.Time.Format("2006-01-02 15:04:05"), op.Label)
+				for _, f := range op.Files {
+					fmt.Printf("      %s\n", f)
+				}
+			}
+			return nil
+		},
+	}
+}
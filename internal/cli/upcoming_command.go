@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/task"
+)
+
+// UpcomingCommand returns the upcoming forecast command
+func UpcomingCommand(cfg *config.Config) *Command {
+	fs := flag.NewFlagSet("upcoming", flag.ContinueOnError)
+	days := fs.Int("days", 14, "Number of days ahead to show")
+	area := fs.String("area", "", "Only show these areas (comma-separated)")
+
+	return &Command{
+		Name:        "upcoming",
+		Usage:       "atask upcoming [--days n] [--area a,b]",
+		Description: "Forecast dated tasks and future recurring instances by day",
+		Flags:       fs,
+		Run: func(cmd *Command, args []string) error {
+			if *days < 0 {
+				return fmt.Errorf("--days must not be negative")
+			}
+
+			filterArea := *area
+			if filterArea == "" {
+				filterArea = globalFlags.Area
+			}
+			areas := make(map[string]bool)
+			for _, a := range strings.Split(filterArea, ",") {
+				if a = strings.TrimSpace(a); a != "" {
+					areas[a] = true
+				}
+			}
+
+			scanner := denote.NewScanner(cfg.NotesDirectory)
+			scanner.FrontmatterOnly = true
+			allTasks, err := scanner.FindTasks()
+			if err != nil {
+				return fmt.Errorf("failed to scan directory: %v", err)
+			}
+			var tasks []*denote.Task
+			for _, t := range allTasks {
+				if len(areas) == 0 || areas[t.TaskMetadata.Area] {
+					tasks = append(tasks, t)
+				}
+			}
+
+			today := time.Now()
+			overdue, upcoming := task.Forecast(tasks, today, *days)
+
+			if globalFlags.JSON {
+				if overdue == nil {
+					overdue = []task.UpcomingEntry{}
+				}
+				if upcoming == nil {
+					upcoming = []task.UpcomingDay{}
+				}
+				count := len(overdue)
+				for _, d := range upcoming {
+					count += len(d.Entries)
+				}
+				data, err := json.MarshalIndent(map[string]interface{}{
+					"from":    today.Format("2006-01-02"),
+					"to":      today.AddDate(0, 0, *days).Format("2006-01-02"),
+					"overdue": overdue,
+					"days":    upcoming,
+					"count":   count,
+				}, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal JSON: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if len(overdue) == 0 && len(upcoming) == 0 {
+				if !globalFlags.Quiet {
+					fmt.Printf("Nothing due in the next %d days\n", *days)
+				}
+				return nil
+			}
+
+			if globalFlags.NoColor || color.NoColor {
+				color.NoColor = true
+			}
+			headerColor := color.New(color.Bold)
+			overdueColor := color.New(color.FgRed, color.Bold)
+			projectedColor := color.New(color.FgHiBlack)
+
+			if len(overdue) > 0 {
+				overdueColor.Println("Overdue")
+				for _, e := range overdue {
+					printUpcomingEntry(e, true, projectedColor)
+				}
+				fmt.Println()
+			}
+
+			todayStr := today.Format("2006-01-02")
+			for _, d := range upcoming {
+				date, _ := time.ParseInLocation("2006-01-02", d.Date, time.Local)
+				header := date.Format("Mon Jan 2")
+				if d.Date == todayStr {
+					header = "Today - " + header
+				}
+				headerColor.Println(header)
+				for _, e := range d.Entries {
+					printUpcomingEntry(e, false, projectedColor)
+				}
+				fmt.Println()
+			}
+			return nil
+		},
+	}
+}
+
+// printUpcomingEntry prints one forecast line. Projected instances show the
+// ID of the task they repeat in parentheses, dimmed.
+func printUpcomingEntry(e task.UpcomingEntry, showDate bool, projectedColor *color.Color) {
+	id := strconv.Itoa(e.IndexID)
+	if e.Projected {
+		id = "(" + id + ")"
+	}
+	title := e.Title
+	if len(title) > 40 {
+		title = title[:37] + "..."
+	}
+	line := fmt.Sprintf("  %6s  %-40s", id, title)
+	if showDate {
+		line += " due " + e.Date
+	}
+	if e.Status != denote.TaskStatusOpen {
+		line += " [" + e.Status + "]"
+	}
+	if e.Priority != "" {
+		line += " " + e.Priority
+	}
+	if e.Area != "" {
+		line += " @" + e.Area
+	}
+	if e.Recur != "" {
+		line += " ↻ " + e.Recur
+	}
+	if e.Projected {
+		projectedColor.Println(line)
+		return
+	}
+	fmt.Println(line)
+}
@@ -1,21 +1,34 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/BurntSushi/toml"
+	"github.com/mph-llm-experiments/atask/internal/calendar"
+	"github.com/mph-llm-experiments/atask/internal/recurrence"
 )
 
 // Config represents the application configuration
 type Config struct {
-	NotesDirectory string       `toml:"notes_directory"` // Keep name for backward compatibility
-	Editor         string       `toml:"editor"`
-	DefaultArea    string       `toml:"default_area"`
-	SoonHorizon    int          `toml:"soon_horizon"`  // Days for "soon" filter, default 3
-	TUI            TUIConfig    `toml:"tui"`
-	Tasks          TasksConfig  `toml:"tasks"`
+	NotesDirectory string                `toml:"notes_directory"` // Keep name for backward compatibility
+	Editor         string                `toml:"editor"`
+	DefaultArea    string                `toml:"default_area"`
+	SoonHorizon    int                   `toml:"soon_horizon"` // Days for "soon" filter, default 3
+	TUI            TUIConfig             `toml:"tui"`
+	Tasks          TasksConfig           `toml:"tasks"`
+	Queries        map[string]SavedQuery `toml:"queries"` // Saved queries, referenced as @name
+	Calendar       CalendarConfig        `toml:"calendar"`
+
+	workCalendar *calendar.Calendar // built from Calendar by Load
+	warnings     []string           // problems Load worked around
 }
 
 // TUIConfig represents TUI-specific settings
@@ -30,6 +43,77 @@ type TasksConfig struct {
 	DefaultStateFilter string `toml:"default_state_filter"` // incomplete, active, open, paused, done, delegated, dropped, or "" for none
 }
 
+// CalendarConfig sets which days count as workdays, for the "workdays" and
+// "shift to next workday" recurrence modifiers and due:workdays queries:
+//
+//	[calendar]
+//	workdays = ["mon", "tue", "wed", "thu", "fri"]
+//	holidays = ["2026-12-25", "2026-12-26"]
+//	holiday_file = "~/calendars/holidays.ics"
+type CalendarConfig struct {
+	Workdays    []string `toml:"workdays"`     // weekday names; Monday to Friday if empty
+	Holidays    []string `toml:"holidays"`     // YYYY-MM-DD
+	HolidayFile string   `toml:"holiday_file"` // iCalendar file whose events are holidays
+}
+
+// SavedQuery is a named query expression from the [queries] table, used as
+// "atask query @name" or picked from the TUI filter menu. It may be written
+// as a plain string or as a table:
+//
+//	[queries]
+//	waiting = "status:delegated"
+//	next = { query = "status:open AND (due:week OR priority:p1)", sort = "due" }
+type SavedQuery struct {
+	Query   string `toml:"query"`
+	Sort    string `toml:"sort"`    // same values as tasks sort_by; "" keeps the default
+	Reverse bool   `toml:"reverse"` // reverse the sort order
+}
+
+// UnmarshalTOML accepts either a query string or a table with query, sort
+// and reverse keys.
+func (q *SavedQuery) UnmarshalTOML(v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		*q = SavedQuery{Query: v}
+		return nil
+	case map[string]interface{}:
+		*q = SavedQuery{}
+		for key, value := range v {
+			var ok bool
+			switch key {
+			case "query":
+				q.Query, ok = value.(string)
+			case "sort":
+				q.Sort, ok = value.(string)
+			case "reverse":
+				q.Reverse, ok = value.(bool)
+			default:
+				return fmt.Errorf("unknown key %q", key)
+			}
+			if !ok {
+				return fmt.Errorf("invalid value for %s: %v", key, value)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("expected a query string or table, got %v", v)
+	}
+}
+
+// validTaskSorts are the sort keys accepted by tasks sort_by and by saved
+// queries.
+var validTaskSorts = []string{"due", "priority", "project", "estimate", "title", "created", "modified"}
+
+// QueryNames returns the names of the saved queries in sorted order.
+func (c *Config) QueryNames() []string {
+	names := make([]string, 0, len(c.Queries))
+	for name := range c.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // DefaultConfig returns default configuration
 func DefaultConfig() *Config {
 	homeDir, _ := os.UserHomeDir()
@@ -81,6 +165,7 @@ func Load(path string) (*Config, error) {
 
 	// Expand home directory in paths
 	cfg.NotesDirectory = expandHome(cfg.NotesDirectory)
+	cfg.Calendar.HolidayFile = expandHome(cfg.Calendar.HolidayFile)
 	
 	// Ensure SoonHorizon has a sensible default if not set
 	if cfg.SoonHorizon <= 0 {
@@ -92,9 +177,75 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
+	cal, warnings, err := cfg.Calendar.build()
+	if err != nil {
+		return nil, err
+	}
+	cfg.workCalendar = cal
+	cfg.warnings = warnings
+
 	return cfg, nil
 }
 
+// WorkCalendar returns the workday calendar from the [calendar] section, or
+// a Monday to Friday calendar if the config was not loaded from a file.
+func (c *Config) WorkCalendar() *calendar.Calendar {
+	if c.workCalendar == nil {
+		return calendar.Default()
+	}
+	return c.workCalendar
+}
+
+// Warnings returns the problems Load worked around instead of failing,
+// such as a holiday file that does not exist.
+func (c *Config) Warnings() []string {
+	return c.warnings
+}
+
+// holidayYears is how far ahead recurring events in the holiday file are
+// expanded.
+const holidayYears = 10
+
+// build returns the calendar the section describes, reading the holiday
+// file if one is set. A missing holiday file is reported as a warning and
+// the calendar built without it. The section must already be validated.
+func (cc CalendarConfig) build() (*calendar.Calendar, []string, error) {
+	if len(cc.Workdays) == 0 && len(cc.Holidays) == 0 && cc.HolidayFile == "" {
+		return calendar.Default(), nil, nil
+	}
+
+	workdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	if len(cc.Workdays) > 0 {
+		workdays = nil
+		for _, name := range cc.Workdays {
+			wd, _ := calendar.ParseWeekday(name)
+			workdays = append(workdays, wd)
+		}
+	}
+
+	var holidays []time.Time
+	for _, h := range cc.Holidays {
+		d, _ := time.ParseInLocation("2006-01-02", h, time.Local)
+		holidays = append(holidays, d)
+	}
+	var warnings []string
+	if cc.HolidayFile != "" {
+		events, err := calendar.LoadICS(cc.HolidayFile)
+		if errors.Is(err, fs.ErrNotExist) {
+			warnings = append(warnings, fmt.Sprintf("calendar holiday_file %s not found; using the holidays list only", cc.HolidayFile))
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("invalid calendar holiday_file: %w", err)
+		} else {
+			days, err := recurrence.Holidays(events, time.Now().AddDate(holidayYears, 0, 0))
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid calendar holiday_file: %w", err)
+			}
+			holidays = append(holidays, days...)
+		}
+	}
+	return calendar.New(workdays, holidays), warnings, nil
+}
+
 // Save writes configuration to file
 func (c *Config) Save(path string) error {
 	// Ensure directory exists
@@ -151,7 +302,6 @@ func (c *Config) Validate() error {
 
 	// Validate tasks sort options
 	if c.Tasks.SortBy != "" {
-		validTaskSorts := []string{"due", "priority", "project", "estimate", "title", "created", "modified"}
 		valid := false
 		for _, sort := range validTaskSorts {
 			if c.Tasks.SortBy == sort {
@@ -182,9 +332,54 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate calendar
+	for _, name := range c.Calendar.Workdays {
+		if _, ok := calendar.ParseWeekday(name); !ok {
+			return fmt.Errorf("invalid calendar workday: %s (use monday..sunday or mon..sun)", name)
+		}
+	}
+	for _, h := range c.Calendar.Holidays {
+		if _, err := time.ParseInLocation("2006-01-02", h, time.Local); err != nil {
+			return fmt.Errorf("invalid calendar holiday: %s (use YYYY-MM-DD)", h)
+		}
+	}
+
+	// Validate saved queries
+	for _, name := range c.QueryNames() {
+		if !validQueryName(name) {
+			return fmt.Errorf("invalid saved query name: %q (use letters, digits, - and _)", name)
+		}
+		sq := c.Queries[name]
+		if sq.Sort != "" {
+			valid := false
+			for _, sort := range validTaskSorts {
+				if sq.Sort == sort {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return fmt.Errorf("invalid sort for saved query %s: %s (valid: %s)", name, sq.Sort, strings.Join(validTaskSorts, ", "))
+			}
+		}
+	}
+
 	return nil
 }
 
+// validQueryName reports whether name can be written as @name in a query.
+func validQueryName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
 // findConfigFile looks for config in standard locations
 func findConfigFile() string {
 	// Check XDG_CONFIG_HOME first
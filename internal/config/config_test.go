@@ -0,0 +1,57 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a config file using dir as the notes directory.
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "config.toml")
+	content = "notes_directory = \"" + dir + "\"\n" + content
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadHolidayFile(t *testing.T) {
+	dir := t.TempDir()
+	ics := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;VALUE=DATE:20261225\nRRULE:FREQ=YEARLY\nEND:VEVENT\nEND:VCALENDAR\n"
+	if err := os.WriteFile(filepath.Join(dir, "holidays.ics"), []byte(ics), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := writeConfig(t, dir, "[calendar]\nholiday_file = \""+filepath.Join(dir, "holidays.ics")+"\"\n")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	// 2027-12-25 is a Saturday, so check a weekday occurrence
+	if cal := cfg.WorkCalendar(); cal.IsWorkday(time.Date(2028, 12, 25, 0, 0, 0, 0, time.Local)) {
+		t.Error("recurring holiday not expanded past its first date")
+	}
+	if len(cfg.Warnings()) != 0 {
+		t.Errorf("Warnings() = %q, want none", cfg.Warnings())
+	}
+}
+
+func TestLoadMissingHolidayFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, "[calendar]\nholidays = [\"2099-01-07\"]\nholiday_file = \""+filepath.Join(dir, "missing.ics")+"\"\n")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load with a missing holiday file: %v", err)
+	}
+	if w := cfg.Warnings(); len(w) != 1 || !strings.Contains(w[0], "missing.ics") {
+		t.Errorf("Warnings() = %q, want one naming the file", w)
+	}
+	if cfg.WorkCalendar().IsWorkday(time.Date(2099, 1, 7, 0, 0, 0, 0, time.Local)) {
+		t.Error("holidays list ignored when the holiday file is missing")
+	}
+}
@@ -42,7 +42,7 @@ func BuildFilename(id, title, entityType string) string {
 }
 
 // RenameFileForType renames a file to reflect a new type tag.
-func RenameFileForType(oldPath string, newType string) (string, error) {
+func RenameFileForType(op *Op, oldPath string, newType string) (string, error) {
 	dir := filepath.Dir(oldPath)
 	base := filepath.Base(oldPath)
 
@@ -71,7 +71,7 @@ func RenameFileForType(oldPath string, newType string) (string, error) {
 		return "", fmt.Errorf("target file already exists: %s", newPath)
 	}
 
-	if err := os.Rename(oldPath, newPath); err != nil {
+	if err := MoveFile(op, oldPath, newPath); err != nil {
 		return "", fmt.Errorf("failed to rename file: %w", err)
 	}
 
@@ -0,0 +1,191 @@
+package denote
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// IndexFileName is the name of the metadata index kept in the notes directory.
+const IndexFileName = ".atask-index"
+
+// indexVersion is bumped whenever the cached entry layout changes so that
+// older index files are discarded instead of decoded into the wrong shape.
+const indexVersion = 3
+
+// IndexEntry caches the parsed frontmatter of a single file; bodies are not
+// cached, so the index stays small. An entry is valid only while the file's
+// mtime and size still match what was recorded.
+type IndexEntry struct {
+	ModTime int64
+	Size    int64
+	Type    string
+	Task    *Task
+	Project *Project
+	Action  *Action
+}
+
+// Index is an on-disk cache of parsed task, project and action metadata,
+// keyed by path relative to the notes directory.
+type Index struct {
+	Version int
+	Entries map[string]*IndexEntry
+
+	dir   string
+	dirty bool
+}
+
+// LoadIndex reads the index for dir. A missing, unreadable or outdated index
+// is not an error: an empty index is returned and repopulated as files are
+// scanned.
+func LoadIndex(dir string) *Index {
+	idx := &Index{dir: dir}
+
+	f, err := os.Open(filepath.Join(dir, IndexFileName))
+	if err == nil {
+		defer f.Close()
+		if err := gob.NewDecoder(f).Decode(idx); err != nil || idx.Version != indexVersion {
+			idx.Entries = nil
+			idx.dirty = true
+		}
+	}
+
+	idx.Version = indexVersion
+	if idx.Entries == nil {
+		idx.Entries = make(map[string]*IndexEntry)
+	}
+	return idx
+}
+
+// RebuildIndex discards any existing index for dir and re-parses every task,
+// project and action file, including archived ones.
+func RebuildIndex(dir string) (*Index, error) {
+	if err := os.Remove(filepath.Join(dir, IndexFileName)); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove index: %w", err)
+	}
+
+	s := NewScanner(dir)
+	s.IncludeArchived = true
+	s.FrontmatterOnly = true
+	s.index().dirty = true
+	if _, err := s.FindTasks(); err != nil {
+		return nil, err
+	}
+	if _, err := s.FindProjects(); err != nil {
+		return nil, err
+	}
+	if _, err := s.FindActions(); err != nil {
+		return nil, err
+	}
+	if err := s.index().Save(); err != nil {
+		return nil, err
+	}
+	return s.index(), nil
+}
+
+// Count returns the number of cached entries of the given type.
+func (idx *Index) Count(fileType string) int {
+	n := 0
+	for _, e := range idx.Entries {
+		if e.Type == fileType {
+			n++
+		}
+	}
+	return n
+}
+
+// Save writes the index back to disk if it changed since it was loaded.
+// The file is written to a temporary name and renamed into place so readers
+// never see a partial index.
+func (idx *Index) Save() error {
+	if !idx.dirty {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(idx.dir, IndexFileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	if err := gob.NewEncoder(tmp).Encode(idx); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to encode index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(idx.dir, IndexFileName)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	idx.dirty = false
+	return nil
+}
+
+// lookup returns the cached entry for rel if the file on disk still matches
+// the recorded mtime and size.
+func (idx *Index) lookup(rel string, info os.FileInfo) *IndexEntry {
+	e, ok := idx.Entries[rel]
+	if !ok || e.ModTime != info.ModTime().UnixNano() || e.Size != info.Size() {
+		return nil
+	}
+	return e
+}
+
+// store records the metadata of a freshly parsed file, without its body.
+func (idx *Index) store(rel string, info os.FileInfo, e *IndexEntry) {
+	m := e.withContent("")
+	m.ModTime = info.ModTime().UnixNano()
+	m.Size = info.Size()
+	idx.Entries[rel] = m
+	idx.dirty = true
+}
+
+// withContent returns a copy of e whose task, project or action has the
+// given body.
+func (e *IndexEntry) withContent(content string) *IndexEntry {
+	c := *e
+	switch {
+	case e.Task != nil:
+		t := *e.Task
+		t.Content = content
+		c.Task = &t
+	case e.Project != nil:
+		p := *e.Project
+		p.Content = content
+		c.Project = &p
+	case e.Action != nil:
+		a := *e.Action
+		a.Content = content
+		c.Action = &a
+	}
+	return &c
+}
+
+// prune drops entries of fileType under prefix that were not seen in the
+// latest scan, so deleted and renamed files fall out of the index.
+func (idx *Index) prune(fileType, prefix string, seen map[string]bool) {
+	for rel, e := range idx.Entries {
+		if e.Type != fileType || filepath.Dir(rel) != prefix || seen[rel] {
+			continue
+		}
+		delete(idx.Entries, rel)
+		idx.dirty = true
+	}
+}
+
+// names returns the sorted relative paths of entries of fileType under prefix.
+func (idx *Index) names(fileType, prefix string) []string {
+	var names []string
+	for rel, e := range idx.Entries {
+		if e.Type == fileType && filepath.Dir(rel) == prefix {
+			names = append(names, rel)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,256 @@
+package denote
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mph-llm-experiments/acore"
+)
+
+// writeEntity writes a project or action file into dir and returns its path.
+func writeEntity(t *testing.T, dir, title, fileType string, v interface{}) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	filename := acore.BuildFilename(acore.NewID(), title, fileType)
+	if err := acore.WriteFile(acore.NewLocalStore(dir), filename, v, "Notes\n"); err != nil {
+		t.Fatalf("write %s: %v", fileType, err)
+	}
+	return filepath.Join(dir, filename)
+}
+
+// touch bumps a file's mtime so the index sees it as changed.
+func touch(t *testing.T, path string) {
+	t.Helper()
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexServesUnchangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 3)
+
+	tasks, err := NewScanner(dir).FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	changed, unchanged := tasks[0], tasks[1]
+
+	// Tamper with the cached copy of an unchanged file: only a cache hit
+	// can return the tampered title.
+	idx := LoadIndex(dir)
+	rel := filepath.Base(unchanged.FilePath)
+	idx.Entries[rel].Task.Title = "from the index"
+	idx.dirty = true
+	if err := idx.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Same size, new mtime
+	changed.Priority = PriorityP3
+	if err := SaveTask(nil, changed); err != nil {
+		t.Fatalf("SaveTask: %v", err)
+	}
+	touch(t, changed.FilePath)
+
+	got := make(map[string]*Task)
+	tasks, err = NewScanner(dir).FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	for _, task := range tasks {
+		got[task.FilePath] = task
+	}
+	if got[unchanged.FilePath].Title != "from the index" {
+		t.Errorf("unchanged file was re-parsed instead of served from the index")
+	}
+	if got[changed.FilePath].Priority != PriorityP3 {
+		t.Errorf("changed file served stale priority %q", got[changed.FilePath].Priority)
+	}
+	if got[unchanged.FilePath].Content != unchanged.Content {
+		t.Errorf("indexed file's body = %q, want %q", got[unchanged.FilePath].Content, unchanged.Content)
+	}
+
+	// A size change is noticed even when the mtime is restored
+	info, err := os.Stat(changed.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(changed.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(changed.FilePath, append(data, "More notes.\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(changed.FilePath, info.ModTime(), info.ModTime())
+	found, err := NewScanner(dir).LookupTask(func(task *Task) bool { return task.IndexID == changed.IndexID })
+	if err != nil || found == nil {
+		t.Fatalf("LookupTask = %v, %v", found, err)
+	}
+	if found.Content == changed.Content {
+		t.Errorf("size change not noticed: body unchanged")
+	}
+}
+
+func TestIndexKeepsNoBodies(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 3)
+
+	if _, err := NewScanner(dir).FindTasks(); err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	idx := LoadIndex(dir)
+	if len(idx.Entries) != 3 {
+		t.Fatalf("index has %d entries, want 3", len(idx.Entries))
+	}
+	for rel, e := range idx.Entries {
+		if e.Task.Content != "" {
+			t.Errorf("%s: index caches the file body", rel)
+		}
+	}
+}
+
+func TestIndexRecoversFromCorruption(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 4)
+
+	path := filepath.Join(dir, IndexFileName)
+	if err := os.WriteFile(path, []byte("not a gob stream"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner(dir)
+	tasks, err := s.FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks with a corrupt index: %v", err)
+	}
+	if len(tasks) != 4 || len(s.ParseErrors()) != 0 {
+		t.Errorf("got %d tasks and %d parse errors, want 4 and none", len(tasks), len(s.ParseErrors()))
+	}
+
+	// The scan rewrote the index
+	if idx := LoadIndex(dir); len(idx.Entries) != 4 || idx.dirty {
+		t.Errorf("index not rewritten: %d entries, dirty=%v", len(idx.Entries), idx.dirty)
+	}
+}
+
+func TestLookups(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 3)
+
+	project := &Project{}
+	project.Title = "Garden"
+	project.IndexID = 50
+	project.Type = TypeProject
+	writeEntity(t, dir, project.Title, "project", project)
+
+	action := &Action{}
+	action.Title = "Bump"
+	action.IndexID = 7
+	action.Type = TypeAction
+	action.ActionType = ActionTypeTaskUpdate
+	writeEntity(t, filepath.Join(dir, "queue"), action.Title, "action", action)
+
+	// Populate the index
+	s := NewScanner(dir)
+	if _, err := s.FindTasks(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FindProjects(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FindActions(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Hits
+	if task, err := NewScanner(dir).LookupTask(func(t *Task) bool { return t.IndexID == 2 }); err != nil || task == nil || task.Title != "Task number 1" {
+		t.Errorf("LookupTask(#2) = %v, %v", task, err)
+	}
+	if p, err := NewScanner(dir).LookupProject(func(p *Project) bool { return p.IndexID == 50 }); err != nil || p == nil || p.Title != "Garden" {
+		t.Errorf("LookupProject(#50) = %v, %v", p, err)
+	}
+	if a, err := NewScanner(dir).LookupAction(func(a *Action) bool { return a.IndexID == 7 }); err != nil || a == nil || a.Title != "Bump" {
+		t.Errorf("LookupAction(#7) = %v, %v", a, err)
+	}
+
+	// Files written after the index was saved are found by the full scan
+	// the lookups fall through to.
+	late := &Task{}
+	late.Title = "Late task"
+	late.IndexID = 99
+	late.Type = TypeTask
+	late.Status = TaskStatusOpen
+	writeEntity(t, dir, late.Title, "task", late)
+	if task, err := NewScanner(dir).LookupTask(func(t *Task) bool { return t.IndexID == 99 }); err != nil || task == nil || task.Title != "Late task" {
+		t.Errorf("LookupTask(#99) = %v, %v; want the unindexed task", task, err)
+	}
+	lateAction := &Action{}
+	lateAction.Title = "Later"
+	lateAction.IndexID = 8
+	lateAction.Type = TypeAction
+	writeEntity(t, filepath.Join(dir, "queue"), lateAction.Title, "action", lateAction)
+	if a, err := NewScanner(dir).LookupAction(func(a *Action) bool { return a.IndexID == 8 }); err != nil || a == nil {
+		t.Errorf("LookupAction(#8) = %v, %v; want the unindexed action", a, err)
+	}
+
+	// A cached match that no longer holds on disk is not returned
+	stale, err := NewScanner(dir).LookupProject(func(p *Project) bool { return p.IndexID == 50 })
+	if err != nil || stale == nil {
+		t.Fatalf("LookupProject(#50) = %v, %v", stale, err)
+	}
+	stale.IndexID = 51
+	if err := SaveProject(nil, stale); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, stale.FilePath)
+	if p, err := NewScanner(dir).LookupProject(func(p *Project) bool { return p.IndexID == 50 }); err != nil || p != nil {
+		t.Errorf("LookupProject(#50) after renumbering = %v, %v; want nil", p, err)
+	}
+
+	// Misses
+	if task, err := NewScanner(dir).LookupTask(func(t *Task) bool { return t.IndexID == 1000 }); err != nil || task != nil {
+		t.Errorf("LookupTask(#1000) = %v, %v; want nil", task, err)
+	}
+}
+
+func TestRebuildIndex(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 3)
+
+	project := &Project{}
+	project.Title = "Archived project"
+	project.IndexID = 40
+	project.Type = TypeProject
+	archivedPath := writeEntity(t, filepath.Join(dir, ArchiveDir, "2025"), project.Title, "project", project)
+
+	action := &Action{}
+	action.Title = "Queued"
+	action.IndexID = 1
+	action.Type = TypeAction
+	writeEntity(t, filepath.Join(dir, "queue"), action.Title, "action", action)
+
+	// Rebuilding doesn't depend on the old index being readable
+	if err := os.WriteFile(filepath.Join(dir, IndexFileName), []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := RebuildIndex(dir)
+	if err != nil {
+		t.Fatalf("RebuildIndex: %v", err)
+	}
+	if idx.Count(TypeTask) != 3 || idx.Count(TypeProject) != 1 || idx.Count(TypeAction) != 1 {
+		t.Errorf("rebuilt index has %d tasks, %d projects, %d actions; want 3, 1, 1",
+			idx.Count(TypeTask), idx.Count(TypeProject), idx.Count(TypeAction))
+	}
+
+	archived, _ := filepath.Rel(dir, archivedPath)
+	if e := LoadIndex(dir).Entries[archived]; e == nil || e.Project == nil || e.Project.IndexID != 40 {
+		t.Errorf("archived project %s missing from the saved index", archived)
+	}
+}
@@ -0,0 +1,569 @@
+package denote
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mph-llm-experiments/acore"
+)
+
+// JournalFileName is the append-only undo journal kept in the notes
+// directory. Every write made through this package with an Op is recorded
+// with the file's full content before and after.
+const JournalFileName = ".atask-journal"
+
+// Environment variables used to hand the current operation to child atask
+// processes, so their writes are undone together with the parent's.
+const (
+	journalOpEnv    = "ATASK_JOURNAL_OP"
+	journalLabelEnv = "ATASK_JOURNAL_LABEL"
+)
+
+// ErrUndoConflict is returned when a file changed after the operation being
+// undone, so restoring it would discard a later edit.
+var ErrUndoConflict = errors.New("file changed after the operation; undo would discard later edits")
+
+// ErrJournalReplaced is returned by JournalChanges when the journal was
+// compacted or replaced since the position asked for, so the entries
+// since it are unknown.
+var ErrJournalReplaced = errors.New("journal was replaced since the given position")
+
+// The journal keeps the journalKeepOps most recent operations. It is
+// compacted once it grows past journalMaxSize, down to at most half that
+// size, so a run of large operations keeps fewer.
+const (
+	journalKeepOps = 200
+	journalMaxSize = 16 << 20
+)
+
+// journalHeaderLabel labels the first entry of a compacted journal, whose
+// Op names that generation of the journal.
+const journalHeaderLabel = "compacted"
+
+// JournalEntry records one file change. Before is nil if the file did not
+// exist; After is nil if the file was removed. Path is relative to the
+// notes directory. An entry without a Path either marks an undo as
+// complete, since only then is the operation it undoes reported as undone,
+// or heads a compacted journal.
+type JournalEntry struct {
+	Op       string    `json:"op"`
+	Label    string    `json:"label"`
+	Time     time.Time `json:"time"`
+	Path     string    `json:"path"`
+	Before   *string   `json:"before"`
+	After    *string   `json:"after"`
+	Undoes   string    `json:"undoes,omitempty"`
+	Complete bool      `json:"complete,omitempty"`
+}
+
+// JournalOp groups the entries written by one command or TUI action.
+type JournalOp struct {
+	ID      string         `json:"id"`
+	Label   string         `json:"label"`
+	Time    time.Time      `json:"time"`
+	Files   []string       `json:"files"`
+	Undone  bool           `json:"undone"`
+	Undoes  string         `json:"undoes,omitempty"`
+	Entries []JournalEntry `json:"-"`
+}
+
+// Op is one undoable operation, such as a CLI invocation or a TUI key
+// press. Writes made with the same Op are journaled together and undone
+// together. An Op is never modified once created, so it can be shared
+// between goroutines; a nil *Op records nothing.
+type Op struct {
+	dir    string
+	id     string
+	label  string
+	undoes string
+}
+
+// NewOp returns an operation recording writes under dir, described by
+// label. A child process started with the operation's Env joins its
+// parent's operation instead of starting a new one.
+func NewOp(dir, label string) *Op {
+	id := os.Getenv(journalOpEnv)
+	if id != "" {
+		if l := os.Getenv(journalLabelEnv); l != "" {
+			label = l
+		}
+	} else {
+		id = acore.NewID()
+	}
+	return &Op{dir: dir, id: id, label: label}
+}
+
+// Env returns environment entries that make a child atask process record
+// its writes as part of op.
+func (op *Op) Env() []string {
+	if op == nil {
+		return nil
+	}
+	return []string{journalOpEnv + "=" + op.id, journalLabelEnv + "=" + op.label}
+}
+
+// record appends an entry for path. It is called before the change is
+// made, so a crash mid-write still leaves the old content recoverable.
+func (op *Op) record(path string, before, after []byte) error {
+	if op == nil {
+		return nil
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	absDir, err := filepath.Abs(op.dir)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(absDir, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		// Only files inside the notes directory can be undone from it.
+		return nil
+	}
+
+	entry := JournalEntry{Op: op.id, Label: op.label, Time: time.Now(), Path: rel, Undoes: op.undoes}
+	if before != nil {
+		s := string(before)
+		entry.Before = &s
+	}
+	if after != nil {
+		s := string(after)
+		entry.After = &s
+	}
+	return op.append(entry)
+}
+
+// complete records that an undo operation restored every file.
+func (op *Op) complete() error {
+	if op == nil {
+		return nil
+	}
+	return op.append(JournalEntry{Op: op.id, Label: op.label, Time: time.Now(), Undoes: op.undoes, Complete: true})
+}
+
+// append writes entry to the end of the journal.
+func (op *Op) append(entry JournalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	// Entries are appended while the changed file's lock is held, so the
+	// journal has a lock of its own rather than one of the file stripes.
+	journalPath := filepath.Join(op.dir, JournalFileName)
+	unlock, err := lockPath(filepath.Join(op.dir, lockDirName, "journal.lock"))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(journalPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(journalPath); err == nil && info.Size() > journalMaxSize {
+		// Losing old history must not fail the write being recorded.
+		_ = compactJournal(journalPath)
+	}
+	return nil
+}
+
+// compactJournal rewrites the journal at path keeping only the most recent
+// operations, ordered by their last entry so an operation still being
+// written is kept whole. The caller holds the journal lock.
+func compactJournal(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	type opLines struct {
+		last int
+		size int
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	owner := make([]string, len(lines))
+	ops := make(map[string]*opLines)
+	for i, line := range lines {
+		var e JournalEntry
+		if !bytes.HasSuffix(line, []byte("\n")) || json.Unmarshal(line, &e) != nil {
+			continue
+		}
+		if e.Path == "" && !e.Complete {
+			continue // the previous header
+		}
+		o := ops[e.Op]
+		if o == nil {
+			o = &opLines{}
+			ops[e.Op] = o
+		}
+		o.last = i
+		o.size += len(line)
+		owner[i] = e.Op
+	}
+
+	ids := make([]string, 0, len(ops))
+	for id := range ops {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ops[ids[i]].last > ops[ids[j]].last })
+	keep := make(map[string]bool)
+	size := 0
+	for _, id := range ids {
+		if len(keep) == journalKeepOps || (len(keep) > 0 && size+ops[id].size > journalMaxSize/2) {
+			break
+		}
+		keep[id] = true
+		size += ops[id].size
+	}
+
+	header, err := json.Marshal(JournalEntry{Op: acore.NewID(), Label: journalHeaderLabel, Time: time.Now()})
+	if err != nil {
+		return err
+	}
+	out := append(header, '\n')
+	for i, line := range lines {
+		if keep[owner[i]] {
+			out = append(out, line...)
+		}
+	}
+	return WriteFileAtomic(nil, path, out, 0644)
+}
+
+// readCurrent returns the content of path, or nil if it does not exist.
+func readCurrent(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return data, nil
+}
+
+// RecordChange journals under op a change made outside this package, such
+// as a new file written by acore or an external editor session. before is
+// the content prior to the change, or nil if the file did not exist.
+// Nothing is recorded if the file is unchanged.
+func RecordChange(op *Op, path string, before []byte) error {
+	after, err := readCurrent(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if before != nil && after != nil && string(before) == string(after) {
+		return nil
+	}
+	return op.record(path, before, after)
+}
+
+// MoveFile renames from to to, journaling both sides of the move under op.
+func MoveFile(op *Op, from, to string) error {
+	content, err := readCurrent(from)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	existing, err := readCurrent(to)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if err := op.record(from, content, nil); err != nil {
+		return err
+	}
+	if err := op.record(to, existing, content); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
+
+// RemoveFile deletes path, journaling its content under op so the removal
+// can be undone. A missing file is not an error.
+func RemoveFile(op *Op, path string) error {
+	content, err := readCurrent(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if content == nil {
+		return nil
+	}
+	if err := op.record(path, content, nil); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// History returns the journaled operations in dir, oldest first.
+func History(dir string) ([]*JournalOp, error) {
+	f, err := os.Open(filepath.Join(dir, JournalFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	var ops []*JournalOp
+	byID := make(map[string]*JournalOp)
+	undone := make(map[string]bool)
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var e JournalEntry
+			switch {
+			case json.Unmarshal(line, &e) != nil:
+				// A torn final line from an interrupted write is skipped.
+			case e.Path == "":
+				if e.Complete {
+					undone[e.Undoes] = true
+				}
+			default:
+				op := byID[e.Op]
+				if op == nil {
+					op = &JournalOp{ID: e.Op, Label: e.Label, Time: e.Time, Undoes: e.Undoes}
+					byID[e.Op] = op
+					ops = append(ops, op)
+				}
+				op.Entries = append(op.Entries, e)
+				if !containsString(op.Files, e.Path) {
+					op.Files = append(op.Files, e.Path)
+				}
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	for _, op := range ops {
+		op.Undone = undone[op.ID]
+	}
+	return ops, nil
+}
+
+// JournalPos is a position in the journal: a byte offset into one
+// generation of it. Compacting the journal starts a new generation.
+type JournalPos struct {
+	Gen    string
+	Offset int64
+}
+
+// journalGen returns the generation named by the first line of a journal,
+// or "" for a journal that was never compacted.
+func journalGen(first []byte) string {
+	var e JournalEntry
+	if json.Unmarshal(first, &e) == nil && e.Path == "" && e.Label == journalHeaderLabel {
+		return e.Op
+	}
+	return ""
+}
+
+// JournalEnd returns the position after the last complete entry in the
+// journal for dir, for use with JournalChanges.
+func JournalEnd(dir string) (JournalPos, error) {
+	data, err := os.ReadFile(filepath.Join(dir, JournalFileName))
+	if os.IsNotExist(err) {
+		return JournalPos{}, nil
+	}
+	if err != nil {
+		return JournalPos{}, fmt.Errorf("failed to read journal: %w", err)
+	}
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	return JournalPos{Gen: journalGen(first), Offset: int64(bytes.LastIndexByte(data, '\n') + 1)}, nil
+}
+
+// JournalChanges returns the paths, relative to dir, of the files changed
+// by journal entries from pos on, and the position to pass next time. A
+// torn final line from a write still in progress is left for the next
+// call. ErrJournalReplaced is returned if the journal was compacted or
+// replaced since pos.
+func JournalChanges(dir string, pos JournalPos) (paths []string, next JournalPos, err error) {
+	f, err := os.Open(filepath.Join(dir, JournalFileName))
+	if os.IsNotExist(err) {
+		if pos != (JournalPos{}) {
+			return nil, JournalPos{}, ErrJournalReplaced
+		}
+		return nil, pos, nil
+	}
+	if err != nil {
+		return nil, pos, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, pos, fmt.Errorf("failed to read journal: %w", err)
+	}
+	first, _ := bufio.NewReader(f).ReadBytes('\n')
+	if journalGen(first) != pos.Gen || info.Size() < pos.Offset {
+		return nil, JournalPos{}, ErrJournalReplaced
+	}
+	if _, err := f.Seek(pos.Offset, io.SeekStart); err != nil {
+		return nil, pos, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	next = pos
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		next.Offset += int64(len(line))
+		var e JournalEntry
+		if json.Unmarshal(line, &e) == nil && e.Path != "" && !containsString(paths, e.Path) {
+			paths = append(paths, e.Path)
+		}
+	}
+	return paths, next, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Undo reverts the n most recent operations in dir that have not already
+// been undone, newest first. Undo operations themselves are skipped, so
+// repeated undos keep walking back through history. Unless force is set, an
+// operation whose files changed afterwards is refused with ErrUndoConflict
+// and nothing further is undone. The undo is itself journaled.
+func Undo(dir string, n int, force bool) ([]*JournalOp, error) {
+	ops, err := History(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var undone []*JournalOp
+	for i := len(ops) - 1; i >= 0 && len(undone) < n; i-- {
+		op := ops[i]
+		if op.Undone || op.Undoes != "" {
+			continue
+		}
+		if err := undoOp(dir, op, force); err != nil {
+			return undone, err
+		}
+		undone = append(undone, op)
+	}
+	return undone, nil
+}
+
+func undoOp(dir string, op *JournalOp, force bool) error {
+	// The first entry per path holds the content to restore; the last holds
+	// what the operation left behind.
+	first := make(map[string]JournalEntry)
+	last := make(map[string]JournalEntry)
+	var paths []string
+	for _, e := range op.Entries {
+		if _, ok := first[e.Path]; !ok {
+			first[e.Path] = e
+			paths = append(paths, e.Path)
+		}
+		last[e.Path] = e
+	}
+
+	// pending reports whether rel still needs restoring. A file already
+	// holding its old content was restored by an undo that was interrupted.
+	pending := func(rel string) (bool, error) {
+		current, err := readCurrent(filepath.Join(dir, rel))
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case sameContent(last[rel].After, current):
+			return true, nil
+		case sameContent(first[rel].Before, current):
+			return false, nil
+		case force:
+			return true, nil
+		}
+		return false, fmt.Errorf("%s: %w", rel, ErrUndoConflict)
+	}
+
+	// Refuse before anything is written; each file is checked again under
+	// its lock as it is restored.
+	for _, rel := range paths {
+		if _, err := pending(rel); err != nil {
+			return err
+		}
+	}
+
+	undo := &Op{dir: dir, id: acore.NewID(), label: "undo " + op.Label, undoes: op.ID}
+
+	// Restore in reverse so files created by the operation are removed
+	// after anything written into them.
+	for i := len(paths) - 1; i >= 0; i-- {
+		if err := restore(undo, dir, paths[i], first[paths[i]].Before, pending); err != nil {
+			return err
+		}
+	}
+	// Until this is recorded the operation is not reported as undone, so an
+	// interrupted undo is finished by the next one.
+	return undo.complete()
+}
+
+// restore puts the content before back at rel, or removes rel if before is
+// nil, if pending says it still needs restoring. The check and the write
+// are made under the file's lock, so a concurrent write is not discarded.
+func restore(undo *Op, dir, rel string, before *string, pending func(string) (bool, error)) error {
+	path := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", rel, err)
+	}
+	unlock, err := LockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	needed, err := pending(rel)
+	if err != nil || !needed {
+		return err
+	}
+	if before == nil {
+		if err := RemoveFile(undo, path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", rel, err)
+		}
+		return nil
+	}
+	if err := WriteFileAtomic(undo, path, []byte(*before), 0644); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", rel, err)
+	}
+	return nil
+}
+
+// sameContent reports whether journaled content matches a file's current
+// content, where nil means the file does not exist.
+func sameContent(journaled *string, current []byte) bool {
+	if journaled == nil || current == nil {
+		return journaled == nil && current == nil
+	}
+	return *journaled == string(current)
+}
@@ -0,0 +1,316 @@
+package denote
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUndoRestoresJournaledWrites(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 2)
+
+	tasks, err := NewScanner(dir).FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	original, err := os.ReadFile(tasks[0].FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	op := NewOp(dir, "batch")
+	for _, task := range tasks {
+		task.Priority = PriorityP1
+		if err := SaveTask(op, task); err != nil {
+			t.Fatalf("SaveTask: %v", err)
+		}
+	}
+	moved := tasks[1].FilePath + ".moved"
+	if err := MoveFile(op, tasks[1].FilePath, moved); err != nil {
+		t.Fatalf("MoveFile: %v", err)
+	}
+
+	ops, err := History(dir)
+	if err != nil || len(ops) != 1 || len(ops[0].Files) != 3 {
+		t.Fatalf("History = %+v, %v; want one op touching 3 files", ops, err)
+	}
+
+	undone, err := Undo(dir, 1, false)
+	if err != nil || len(undone) != 1 {
+		t.Fatalf("Undo = %v, %v", undone, err)
+	}
+
+	got, err := os.ReadFile(tasks[0].FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(original) {
+		t.Errorf("undo did not restore %s:\n%s", tasks[0].FilePath, got)
+	}
+	if _, err := os.Stat(moved); !os.IsNotExist(err) {
+		t.Errorf("moved file still present after undo")
+	}
+	restored, err := ParseTaskFile(tasks[1].FilePath)
+	if err != nil {
+		t.Fatalf("moved file not restored: %v", err)
+	}
+	if restored.Priority == PriorityP1 {
+		t.Errorf("priority change not undone on %s", tasks[1].FilePath)
+	}
+
+	// The batch is marked undone and the undo itself is not undoable.
+	undone, err = Undo(dir, 1, false)
+	if err != nil || len(undone) != 0 {
+		t.Errorf("second Undo = %v, %v; want nothing to undo", undone, err)
+	}
+}
+
+func TestUndoRefusesLaterEdits(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 1)
+
+	tasks, err := NewScanner(dir).FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	task := tasks[0]
+
+	task.Priority = PriorityP3
+	if err := SaveTask(NewOp(dir, "first"), task); err != nil {
+		t.Fatalf("SaveTask: %v", err)
+	}
+
+	// An unjournaled edit made after the operation
+	task.Area = "home"
+	if err := SaveTask(nil, task); err != nil {
+		t.Fatalf("SaveTask: %v", err)
+	}
+
+	if _, err := Undo(dir, 1, false); !errors.Is(err, ErrUndoConflict) {
+		t.Fatalf("Undo error = %v, want ErrUndoConflict", err)
+	}
+	if _, err := Undo(dir, 1, true); err != nil {
+		t.Fatalf("forced Undo: %v", err)
+	}
+	got, err := ParseTaskFile(task.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Priority != PriorityP1 || got.Area == "home" {
+		t.Errorf("forced undo left priority=%q area=%q", got.Priority, got.Area)
+	}
+}
+
+func TestUndoChecksUnderTheFileLock(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 1)
+	tasks, err := NewScanner(dir).FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	task := tasks[0]
+	task.Priority = PriorityP3
+	if err := SaveTask(NewOp(dir, "edit"), task); err != nil {
+		t.Fatalf("SaveTask: %v", err)
+	}
+
+	// A writer holding the file passes the up-front check and then lands
+	// its edit before the restore gets the lock.
+	unlock, err := LockFile(task.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		content, _ := os.ReadFile(task.FilePath)
+		os.WriteFile(task.FilePath, append(content, "Later edit\n"...), 0644)
+		unlock()
+	}()
+
+	if _, err := Undo(dir, 1, false); !errors.Is(err, ErrUndoConflict) {
+		t.Fatalf("Undo error = %v, want ErrUndoConflict", err)
+	}
+	if content, _ := os.ReadFile(task.FilePath); !strings.Contains(string(content), "Later edit") {
+		t.Errorf("undo discarded the concurrent edit")
+	}
+}
+
+func TestInterruptedUndoIsResumed(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 2)
+	tasks, err := NewScanner(dir).FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	var originals []string
+	op := NewOp(dir, "batch")
+	for _, task := range tasks {
+		content, _ := os.ReadFile(task.FilePath)
+		originals = append(originals, string(content))
+		task.Priority = PriorityP1
+		if err := SaveTask(op, task); err != nil {
+			t.Fatalf("SaveTask: %v", err)
+		}
+	}
+
+	// An undo that restored the first file and stopped
+	ops, _ := History(dir)
+	partial := &Op{dir: dir, id: "partial", label: "undo batch", undoes: ops[0].ID}
+	if err := WriteFileAtomic(partial, tasks[0].FilePath, []byte(originals[0]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if ops, _ := History(dir); ops[0].Undone {
+		t.Fatalf("operation reported undone by an interrupted undo")
+	}
+
+	// The next undo finishes the job instead of reporting a conflict
+	if undone, err := Undo(dir, 1, false); err != nil || len(undone) != 1 {
+		t.Fatalf("Undo = %v, %v", undone, err)
+	}
+	for i, task := range tasks {
+		if content, _ := os.ReadFile(task.FilePath); string(content) != originals[i] {
+			t.Errorf("%s not restored", task.FilePath)
+		}
+	}
+	if ops, _ := History(dir); !ops[0].Undone {
+		t.Errorf("operation not reported undone after the undo completed")
+	}
+}
+
+func TestConcurrentOps(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 8)
+
+	tasks, err := NewScanner(dir).FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+
+	// Each goroutine writes its own tasks under its own operation, as the
+	// TUI does when a command finishes while another key is handled.
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		op := NewOp(dir, "writer")
+		wg.Add(1)
+		go func(i int, op *Op) {
+			defer wg.Done()
+			for _, task := range tasks[i*4 : i*4+4] {
+				task.Priority = PriorityP2
+				if err := SaveTask(op, task); err != nil {
+					t.Errorf("SaveTask: %v", err)
+				}
+			}
+		}(i, op)
+	}
+	wg.Wait()
+
+	ops, err := History(dir)
+	if err != nil || len(ops) != 2 {
+		t.Fatalf("History = %d ops, %v; want 2", len(ops), err)
+	}
+	for _, op := range ops {
+		if len(op.Files) != 4 {
+			t.Errorf("op %s journaled %d files, want 4", op.ID, len(op.Files))
+		}
+	}
+}
+
+func TestJournalChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 2)
+	tasks, err := NewScanner(dir).FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+
+	op := NewOp(dir, "edit")
+	for _, task := range append(tasks, tasks[0]) {
+		task.Priority = PriorityP1
+		if err := SaveTask(op, task); err != nil {
+			t.Fatalf("SaveTask: %v", err)
+		}
+	}
+	paths, pos, err := JournalChanges(dir, JournalPos{})
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("JournalChanges = %q, %v; want the 2 edited files once each", paths, err)
+	}
+
+	// A torn line is left for the next call
+	journal := filepath.Join(dir, JournalFileName)
+	f, err := os.OpenFile(journal, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"x","path":"half`)
+	f.Close()
+	if paths, next, err := JournalChanges(dir, pos); err != nil || len(paths) != 0 || next != pos {
+		t.Errorf("JournalChanges over a torn line = %q, %v, %v; want nothing past %v", paths, next, err, pos)
+	}
+	if end, err := JournalEnd(dir); err != nil || end != pos {
+		t.Errorf("JournalEnd = %v, %v; want %v, before the torn line", end, err, pos)
+	}
+
+	if err := os.Remove(journal); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := JournalChanges(dir, pos); !errors.Is(err, ErrJournalReplaced) {
+		t.Errorf("JournalChanges after the journal was removed = %v, want ErrJournalReplaced", err)
+	}
+}
+
+func TestJournalCompaction(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "note.md")
+	for i := 0; i < journalKeepOps+5; i++ {
+		if err := WriteFileAtomic(NewOp(dir, fmt.Sprintf("write %d", i)), path, []byte(fmt.Sprintf("version %d\n", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := JournalEnd(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	journal := filepath.Join(dir, JournalFileName)
+	if err := compactJournal(journal); err != nil {
+		t.Fatalf("compactJournal: %v", err)
+	}
+	ops, err := History(dir)
+	if err != nil || len(ops) != journalKeepOps {
+		t.Fatalf("History after compaction = %d ops, %v; want %d", len(ops), err, journalKeepOps)
+	}
+	if ops[0].Label != "write 5" || ops[len(ops)-1].Label != fmt.Sprintf("write %d", journalKeepOps+4) {
+		t.Errorf("kept %q to %q, want the most recent operations", ops[0].Label, ops[len(ops)-1].Label)
+	}
+	if _, _, err := JournalChanges(dir, before); !errors.Is(err, ErrJournalReplaced) {
+		t.Errorf("JournalChanges across a compaction = %v, want ErrJournalReplaced", err)
+	}
+	if undone, err := Undo(dir, 1, false); err != nil || len(undone) != 1 {
+		t.Errorf("Undo after compaction = %v, %v", undone, err)
+	}
+
+	// Large operations are dropped sooner, once the journal outgrows its
+	// size limit
+	big := strings.Repeat("x", journalMaxSize/5)
+	for i := 0; i < 3; i++ {
+		if err := WriteFileAtomic(NewOp(dir, "big"), path, []byte(fmt.Sprint(i, big)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := os.Stat(journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > journalMaxSize/2 {
+		t.Errorf("journal is %d bytes after compaction, want at most %d", info.Size(), journalMaxSize/2)
+	}
+	if ops, _ := History(dir); len(ops) != 1 || ops[0].Label != "big" {
+		t.Errorf("History = %d ops, want only the last", len(ops))
+	}
+}
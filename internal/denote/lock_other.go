@@ -0,0 +1,11 @@
+//go:build !unix
+
+package denote
+
+import "os"
+
+// Advisory locking is only implemented on Unix; elsewhere writes still go
+// through temp-and-rename but are not serialized between processes.
+func tryLock(f *os.File) (bool, error) { return true, nil }
+
+func releaseLock(f *os.File) {}
@@ -1,12 +1,16 @@
 package denote
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/mph-llm-experiments/acore"
+	"gopkg.in/yaml.v3"
 )
 
 // storeAndName creates a LocalStore from the directory of an absolute path
@@ -23,17 +27,33 @@ var (
 
 // ParseTaskFile reads and parses a task file using acore.
 func ParseTaskFile(path string) (*Task, error) {
+	return parseTask(path, true)
+}
+
+// ParseTaskFrontmatter parses only the frontmatter of a task file,
+// leaving Content empty.
+func ParseTaskFrontmatter(path string) (*Task, error) {
+	return parseTask(path, false)
+}
+
+func parseTask(path string, withContent bool) (*Task, error) {
 	var task Task
-	store, name := storeAndName(path)
-	content, err := acore.ReadFile(store, name, &task)
-	if err != nil {
+	// Stat before reading so a write that lands mid-parse shows up as a
+	// newer mtime rather than being silently missed.
+	info, statErr := os.Stat(path)
+	if withContent {
+		store, name := storeAndName(path)
+		content, err := acore.ReadFile(store, name, &task)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse task file: %w", err)
+		}
+		task.Content = content
+	} else if err := readFrontmatter(path, &task); err != nil {
 		return nil, fmt.Errorf("failed to parse task file: %w", err)
 	}
-	task.Content = content
 	task.FilePath = path
 
-	// Get file modification time
-	if info, err := os.Stat(path); err == nil {
+	if statErr == nil {
 		task.ModTime = info.ModTime()
 	}
 
@@ -61,17 +81,31 @@ func ParseTaskFile(path string) (*Task, error) {
 
 // ParseProjectFile reads and parses a project file using acore.
 func ParseProjectFile(path string) (*Project, error) {
+	return parseProject(path, true)
+}
+
+// ParseProjectFrontmatter parses only the frontmatter of a project file,
+// leaving Content empty.
+func ParseProjectFrontmatter(path string) (*Project, error) {
+	return parseProject(path, false)
+}
+
+func parseProject(path string, withContent bool) (*Project, error) {
 	var project Project
-	store, name := storeAndName(path)
-	content, err := acore.ReadFile(store, name, &project)
-	if err != nil {
+	info, statErr := os.Stat(path)
+	if withContent {
+		store, name := storeAndName(path)
+		content, err := acore.ReadFile(store, name, &project)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse project file: %w", err)
+		}
+		project.Content = content
+	} else if err := readFrontmatter(path, &project); err != nil {
 		return nil, fmt.Errorf("failed to parse project file: %w", err)
 	}
-	project.Content = content
 	project.FilePath = path
 
-	// Get file modification time
-	if info, err := os.Stat(path); err == nil {
+	if statErr == nil {
 		project.ModTime = info.ModTime()
 	}
 
@@ -99,16 +133,31 @@ func ParseProjectFile(path string) (*Project, error) {
 
 // ParseActionFile reads and parses an action file using acore.
 func ParseActionFile(path string) (*Action, error) {
+	return parseAction(path, true)
+}
+
+// ParseActionFrontmatter parses only the frontmatter of an action file,
+// leaving Content empty.
+func ParseActionFrontmatter(path string) (*Action, error) {
+	return parseAction(path, false)
+}
+
+func parseAction(path string, withContent bool) (*Action, error) {
 	var action Action
-	store, name := storeAndName(path)
-	content, err := acore.ReadFile(store, name, &action)
-	if err != nil {
+	info, statErr := os.Stat(path)
+	if withContent {
+		store, name := storeAndName(path)
+		content, err := acore.ReadFile(store, name, &action)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse action file: %w", err)
+		}
+		action.Content = content
+	} else if err := readFrontmatter(path, &action); err != nil {
 		return nil, fmt.Errorf("failed to parse action file: %w", err)
 	}
-	action.Content = content
 	action.FilePath = path
 
-	if info, err := os.Stat(path); err == nil {
+	if statErr == nil {
 		action.ModTime = info.ModTime()
 	}
 
@@ -134,6 +183,50 @@ func ParseActionFile(path string) (*Action, error) {
 	return &action, nil
 }
 
+// readFrontmatter decodes the YAML block at the top of path into v without
+// reading the rest of the file.
+func readFrontmatter(path string, v interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var block strings.Builder
+	for lineNum := 0; ; lineNum++ {
+		line, err := r.ReadString('\n')
+		trimmed := strings.TrimRight(line, "\r\n")
+		if lineNum == 0 {
+			if trimmed != "---" {
+				return fmt.Errorf("no frontmatter found")
+			}
+		} else if trimmed == "---" {
+			return yaml.Unmarshal([]byte(block.String()), v)
+		} else {
+			block.WriteString(line)
+		}
+		if err == io.EOF {
+			return fmt.Errorf("unterminated frontmatter")
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// body returns the part of a file's content after its frontmatter, as
+// acore.ReadFile returns it.
+func body(content string) string {
+	if !strings.HasPrefix(content, "---\n") {
+		return content
+	}
+	if _, rest, ok := strings.Cut(content[4:], "\n---\n"); ok {
+		return rest
+	}
+	return ""
+}
+
 // contains checks if a slice contains a string
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
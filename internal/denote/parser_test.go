@@ -0,0 +1,128 @@
+package denote
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// frontmatterFixtures are notes written the ways people and tools write
+// them, keyed by filename.
+var frontmatterFixtures = map[string]string{
+	"01--plain__task.md": "---\nid: \"01\"\ntitle: Plain\nindex_id: 1\ntype: task\nstatus: open\npriority: p2\n---\n\nBody\n",
+	"02--full__task.md": "---\n" +
+		"id: \"02\"\n" +
+		"title: \"Quoted: with colon\"\n" +
+		"index_id: 2\n" +
+		"type: task\n" +
+		"tags: [task, work, \"två\"]\n" +
+		"created: 2026-01-02T10:00:00Z\n" +
+		"modified: '2026-01-03T10:00:00Z'\n" +
+		"status: done\n" +
+		"priority: p1\n" +
+		"due_date: 2026-02-01\n" +
+		"start_date: \"2026-01-15\"\n" +
+		"estimate: 5\n" +
+		"project_id: \"12\"\n" +
+		"area: work\n" +
+		"recur: every 2 weeks\n" +
+		"completed_at: 2026-01-20T09:00:00Z\n" +
+		"status_history:\n" +
+		"  - status: open\n" +
+		"    at: 2026-01-02T10:00:00Z\n" +
+		"  - status: done\n" +
+		"    at: 2026-01-20T09:00:00Z\n" +
+		"related_people:\n  - ana\n" +
+		"---\n\n## Log\n\n- did it\n",
+	"03--rule-in-body__task.md": "---\nid: \"03\"\ntitle: Rule in body\nindex_id: 3\ntype: task\nstatus: open\n---\n\nAbove\n\n---\n\nstatus: done\n",
+	"04--no-body__task.md":      "---\nid: \"04\"\ntitle: No body\nindex_id: 4\ntype: task\nstatus: paused\n---\n",
+	"05--block-scalar__task.md": "---\nid: \"05\"\ntitle: >-\n  Folded\n  title\nindex_id: 5\ntype: task\n# a comment\nstatus: open\ntags:\n  - a\n  - b\n---\nBody\n",
+	"06--garden__project.md":    "---\nid: \"06\"\ntitle: Garden\nindex_id: 6\ntype: project\nstatus: active\npriority: p3\ndue_date: 2026-05-01\narea: home\ntags: [project]\n---\n\nPlan\n",
+	"07--bump__action.md": "---\nid: \"07\"\ntitle: Bump\nindex_id: 7\ntype: action\naction_type: task_update\nstatus: pending\n" +
+		"proposed_at: 2026-01-05T08:00:00Z\nproposed_by: agent-1\nfields:\n  target_id: \"2\"\n  priority: p1\n" +
+		"policy:\n  rule: bumps\n  decision: review\n  reason: over the rate limit\n---\n\nWhy\n",
+}
+
+// TestFrontmatterOnlyMatchesFullParse checks that the frontmatter-only
+// reader, which stops at the closing delimiter, decodes every fixture to
+// the same metadata as acore.ReadFile.
+func TestFrontmatterOnlyMatchesFullParse(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range frontmatterFixtures {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name := range frontmatterFixtures {
+		path := filepath.Join(dir, name)
+		var full, fm interface{}
+		var fullErr, fmErr error
+		switch {
+		case strings.HasSuffix(name, "__task.md"):
+			var a, b *Task
+			a, fullErr = ParseTaskFile(path)
+			b, fmErr = ParseTaskFrontmatter(path)
+			if a != nil {
+				a.Content = ""
+			}
+			full, fm = a, b
+		case strings.HasSuffix(name, "__project.md"):
+			var a, b *Project
+			a, fullErr = ParseProjectFile(path)
+			b, fmErr = ParseProjectFrontmatter(path)
+			if a != nil {
+				a.Content = ""
+			}
+			full, fm = a, b
+		case strings.HasSuffix(name, "__action.md"):
+			var a, b *Action
+			a, fullErr = ParseActionFile(path)
+			b, fmErr = ParseActionFrontmatter(path)
+			if a != nil {
+				a.Content = ""
+			}
+			full, fm = a, b
+		}
+
+		if fullErr != nil || fmErr != nil {
+			t.Errorf("%s: full parse error %v, frontmatter-only error %v", name, fullErr, fmErr)
+			continue
+		}
+		if !reflect.DeepEqual(full, fm) {
+			t.Errorf("%s: parses differ\n full: %+v\n   fm: %+v", name, full, fm)
+		}
+	}
+}
+
+// TestIndexedBodiesMatchFullParse checks that bodies read for files served
+// from the metadata index match what acore.ReadFile returns.
+func TestIndexedBodiesMatchFullParse(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range frontmatterFixtures {
+		if strings.HasSuffix(name, "__task.md") {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if _, err := NewScanner(dir).FindTasks(); err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	tasks, err := NewScanner(dir).FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	for _, task := range tasks {
+		want, err := ParseTaskFile(task.FilePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.Content != want.Content {
+			t.Errorf("%s: indexed body %q, want %q", filepath.Base(task.FilePath), task.Content, want.Content)
+		}
+	}
+}
@@ -1,19 +1,73 @@
 package denote
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mph-llm-experiments/acore"
 )
 
-// Scanner finds and loads task/project files
+// ArchiveDir is the subdirectory of the notes directory holding archived
+// tasks and projects, grouped into one subdirectory per year.
+const ArchiveDir = "archive"
+
+// Scanner finds and loads task/project files. Parsed files are cached in
+// the directory's metadata index so unchanged files are not re-read, and
+// changed files are parsed concurrently.
 type Scanner struct {
 	BaseDir string
+
+	// Workers bounds the number of files parsed concurrently.
+	// Zero means one worker per CPU.
+	Workers int
+
+	// FrontmatterOnly skips reading file bodies, leaving Content empty.
+	// Use it when only metadata is needed (listings, completions).
+	FrontmatterOnly bool
+
+	// IncludeArchived makes FindTasks and FindProjects also return files
+	// moved to the archive/YYYY/ subdirectories. Lookups always fall back
+	// to the archive so archived IDs stay resolvable.
+	IncludeArchived bool
+
+	idx  *Index
+	errs []ParseError
+}
+
+// ParseError records a file that was skipped because it could not be read
+// or parsed.
+type ParseError struct {
+	Path    string `json:"path"`
+	Message string `json:"error"`
+}
+
+func (e ParseError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ParseErrors returns the files that failed to load during this scanner's
+// scans, in the order they were encountered.
+func (s *Scanner) ParseErrors() []ParseError {
+	return s.errs
+}
+
+// recordError notes a failed file, replacing any earlier error for the same
+// path so repeated scans don't report it twice.
+func (s *Scanner) recordError(pe ParseError) {
+	for i := range s.errs {
+		if s.errs[i].Path == pe.Path {
+			s.errs[i] = pe
+			return
+		}
+	}
+	s.errs = append(s.errs, pe)
 }
 
 // NewScanner creates a new scanner for the given directory
@@ -21,37 +75,207 @@ func NewScanner(dir string) *Scanner {
 	return &Scanner{BaseDir: dir}
 }
 
-// FindAllTaskAndProjectFiles finds all task and project files and returns File views.
-func (s *Scanner) FindAllTaskAndProjectFiles() ([]File, error) {
-	var allFiles []File
-	sc := &acore.Scanner{Store: acore.NewLocalStore(s.BaseDir)}
+// index lazily loads the metadata index for the scanner's directory.
+func (s *Scanner) index() *Index {
+	if s.idx == nil {
+		s.idx = LoadIndex(s.BaseDir)
+	}
+	return s.idx
+}
 
-	// Find task files
-	taskNames, err := sc.FindByType("task")
+// withBody completes a cached entry for rel, which holds only metadata, by
+// reading the file's body unless the scanner skips bodies.
+func (s *Scanner) withBody(rel string, e *IndexEntry) (*IndexEntry, error) {
+	if s.FrontmatterOnly {
+		return e, nil
+	}
+	data, err := os.ReadFile(filepath.Join(s.BaseDir, rel))
+	if err != nil {
+		return nil, err
+	}
+	return e.withContent(body(string(data))), nil
+}
+
+// parse reads the file at rel (relative to BaseDir) as fileType.
+func (s *Scanner) parse(rel, fileType string) (*IndexEntry, error) {
+	path := filepath.Join(s.BaseDir, rel)
+	e := &IndexEntry{Type: fileType}
+	var err error
+	switch fileType {
+	case TypeTask:
+		e.Task, err = parseTask(path, !s.FrontmatterOnly)
+	case TypeProject:
+		e.Project, err = parseProject(path, !s.FrontmatterOnly)
+	case TypeAction:
+		e.Action, err = parseAction(path, !s.FrontmatterOnly)
+	default:
+		err = fmt.Errorf("unknown file type: %s", fileType)
+	}
 	if err != nil {
 		return nil, err
 	}
-	for _, name := range taskNames {
-		task, err := ParseTaskFile(filepath.Join(s.BaseDir, name))
+	return e, nil
+}
+
+// load returns the index entry for rel, parsing the file only if it changed
+// since it was last indexed.
+func (s *Scanner) load(rel, fileType string) (*IndexEntry, error) {
+	info, err := os.Stat(filepath.Join(s.BaseDir, rel))
+	if err != nil {
+		return nil, err
+	}
+	if e := s.index().lookup(rel, info); e != nil {
+		return s.withBody(rel, e)
+	}
+
+	e, err := s.parse(rel, fileType)
+	if err != nil {
+		return nil, err
+	}
+	s.index().store(rel, info, e)
+	return e, nil
+}
+
+// loadAll loads every file in names as fileType. Files not in the index are
+// parsed, and the bodies of indexed files read, on a bounded pool of
+// workers; results keep the order of names, with nil for files that could
+// not be loaded. Failures are recorded in the scanner's parse errors.
+func (s *Scanner) loadAll(names []string, fileType string) []*IndexEntry {
+	entries := make([]*IndexEntry, len(names))
+	cached := make([]*IndexEntry, len(names))
+	infos := make([]os.FileInfo, len(names))
+	errs := make([]error, len(names))
+
+	var work []int
+	for i, rel := range names {
+		info, err := os.Stat(filepath.Join(s.BaseDir, rel))
 		if err != nil {
+			s.recordError(ParseError{Path: filepath.Join(s.BaseDir, rel), Message: err.Error()})
+			continue
+		}
+		infos[i] = info
+		cached[i] = s.index().lookup(rel, info)
+		if cached[i] != nil && s.FrontmatterOnly {
+			entries[i] = cached[i]
 			continue
 		}
+		work = append(work, i)
+	}
+
+	workers := s.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(work) {
+		workers = len(work)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if cached[i] != nil {
+					entries[i], errs[i] = s.withBody(names[i], cached[i])
+				} else {
+					entries[i], errs[i] = s.parse(names[i], fileType)
+				}
+			}
+		}()
+	}
+	for _, i := range work {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// The index is not safe for concurrent use, so record results afterwards.
+	for _, i := range work {
+		if errs[i] != nil {
+			s.recordError(ParseError{Path: filepath.Join(s.BaseDir, names[i]), Message: errs[i].Error()})
+			continue
+		}
+		if cached[i] == nil {
+			s.index().store(names[i], infos[i], entries[i])
+		}
+	}
+
+	return entries
+}
+
+func (s *Scanner) taskFrom(rel string, e *IndexEntry) *Task {
+	task := *e.Task
+	task.FilePath = filepath.Join(s.BaseDir, rel)
+	if s.FrontmatterOnly {
+		task.Content = ""
+	}
+	return &task
+}
+
+func (s *Scanner) projectFrom(rel string, e *IndexEntry) *Project {
+	project := *e.Project
+	project.FilePath = filepath.Join(s.BaseDir, rel)
+	if s.FrontmatterOnly {
+		project.Content = ""
+	}
+	return &project
+}
+
+func (s *Scanner) actionFrom(rel string, e *IndexEntry) *Action {
+	action := *e.Action
+	action.FilePath = filepath.Join(s.BaseDir, rel)
+	if s.FrontmatterOnly {
+		action.Content = ""
+	}
+	return &action
+}
+
+// findNames lists files of fileType in the subdirectory rel of BaseDir
+// ("." for BaseDir itself), returning paths relative to BaseDir.
+func (s *Scanner) findNames(rel, fileType string) ([]string, error) {
+	sc := &acore.Scanner{Store: acore.NewLocalStore(filepath.Join(s.BaseDir, rel))}
+	names, err := sc.FindByType(fileType)
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range names {
+		names[i] = filepath.Join(rel, name)
+	}
+	return names, nil
+}
+
+// FindAllTaskAndProjectFiles finds all task and project files and returns File views.
+func (s *Scanner) FindAllTaskAndProjectFiles() ([]File, error) {
+	var allFiles []File
+
+	// File views carry no body, so there is no need to read one.
+	fm := *s
+	fm.idx = s.index()
+	fm.errs = nil
+	fm.FrontmatterOnly = true
+
+	tasks, err := fm.FindTasks()
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
 		allFiles = append(allFiles, FileFromTask(task))
 	}
 
-	// Find project files
-	projectNames, err := sc.FindByType("project")
+	projects, err := fm.FindProjects()
 	if err != nil {
 		return nil, err
 	}
-	for _, name := range projectNames {
-		project, err := ParseProjectFile(filepath.Join(s.BaseDir, name))
-		if err != nil {
-			continue
-		}
+	for _, project := range projects {
 		allFiles = append(allFiles, FileFromProject(project))
 	}
 
+	for _, pe := range fm.errs {
+		s.recordError(pe)
+	}
+
 	return allFiles, nil
 }
 
@@ -60,97 +284,221 @@ func (s *Scanner) FindAllNotes() ([]File, error) {
 	return s.FindAllTaskAndProjectFiles()
 }
 
+// archiveDirs returns the archive/YYYY subdirectories of BaseDir, relative
+// to BaseDir, oldest year first.
+func (s *Scanner) archiveDirs() []string {
+	entries, err := os.ReadDir(filepath.Join(s.BaseDir, ArchiveDir))
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() {
+			dirs = append(dirs, filepath.Join(ArchiveDir, e.Name()))
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// scanDirs returns the directories FindTasks and FindProjects read.
+func (s *Scanner) scanDirs() []string {
+	if s.IncludeArchived {
+		return append([]string{"."}, s.archiveDirs()...)
+	}
+	return []string{"."}
+}
+
 // FindTasks finds all task files in the directory
 func (s *Scanner) FindTasks() ([]*Task, error) {
-	sc := &acore.Scanner{Store: acore.NewLocalStore(s.BaseDir)}
-	names, err := sc.FindByType("task")
+	var tasks []*Task
+	for _, rel := range s.scanDirs() {
+		found, err := s.findTasksIn(rel)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, found...)
+	}
+	return tasks, nil
+}
+
+func (s *Scanner) findTasksIn(rel string) ([]*Task, error) {
+	names, err := s.findNames(rel, "task")
 	if err != nil {
 		return nil, err
 	}
 
 	var tasks []*Task
-	for _, name := range names {
-		task, err := ParseTaskFile(filepath.Join(s.BaseDir, name))
-		if err != nil {
+	seen := make(map[string]bool, len(names))
+	for i, e := range s.loadAll(names, TypeTask) {
+		seen[names[i]] = true
+		if e == nil {
 			continue
 		}
-		tasks = append(tasks, task)
+		tasks = append(tasks, s.taskFrom(names[i], e))
 	}
 
+	s.index().prune(TypeTask, rel, seen)
+	// The index is a cache; failing to persist it must not fail the scan.
+	_ = s.index().Save()
+
 	return tasks, nil
 }
 
 // FindProjects finds all project files in the directory
 func (s *Scanner) FindProjects() ([]*Project, error) {
-	sc := &acore.Scanner{Store: acore.NewLocalStore(s.BaseDir)}
-	names, err := sc.FindByType("project")
+	var projects []*Project
+	for _, rel := range s.scanDirs() {
+		found, err := s.findProjectsIn(rel)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, found...)
+	}
+	return projects, nil
+}
+
+func (s *Scanner) findProjectsIn(rel string) ([]*Project, error) {
+	names, err := s.findNames(rel, "project")
 	if err != nil {
 		return nil, err
 	}
 
 	var projects []*Project
-	for _, name := range names {
-		project, err := ParseProjectFile(filepath.Join(s.BaseDir, name))
-		if err != nil {
+	seen := make(map[string]bool, len(names))
+	for i, e := range s.loadAll(names, TypeProject) {
+		seen[names[i]] = true
+		if e == nil {
 			continue
 		}
-		projects = append(projects, project)
+		projects = append(projects, s.projectFrom(names[i], e))
 	}
 
+	s.index().prune(TypeProject, rel, seen)
+	_ = s.index().Save()
+
 	return projects, nil
 }
 
 // FindActions finds all action files in the queue/ subdirectory
 func (s *Scanner) FindActions() ([]*Action, error) {
-	queueDir := filepath.Join(s.BaseDir, "queue")
+	return s.findActionsIn("queue")
+}
 
-	// Ensure queue dir exists
-	if _, err := os.Stat(queueDir); os.IsNotExist(err) {
+// FindArchivedActions finds action files in the queue/archive/ subdirectory
+func (s *Scanner) FindArchivedActions() ([]*Action, error) {
+	return s.findActionsIn(filepath.Join("queue", "archive"))
+}
+
+func (s *Scanner) findActionsIn(rel string) ([]*Action, error) {
+	// Ensure the directory exists
+	if _, err := os.Stat(filepath.Join(s.BaseDir, rel)); os.IsNotExist(err) {
 		return nil, nil
 	}
 
-	sc := &acore.Scanner{Store: acore.NewLocalStore(queueDir)}
-	names, err := sc.FindByType("action")
+	names, err := s.findNames(rel, "action")
 	if err != nil {
 		return nil, err
 	}
 
 	var actions []*Action
-	for _, name := range names {
-		action, err := ParseActionFile(filepath.Join(queueDir, name))
-		if err != nil {
+	seen := make(map[string]bool, len(names))
+	for i, e := range s.loadAll(names, TypeAction) {
+		seen[names[i]] = true
+		if e == nil {
 			continue
 		}
-		actions = append(actions, action)
+		actions = append(actions, s.actionFrom(names[i], e))
 	}
 
+	s.index().prune(TypeAction, rel, seen)
+	_ = s.index().Save()
+
 	return actions, nil
 }
 
-// FindArchivedActions finds action files in the queue/archive/ subdirectory
-func (s *Scanner) FindArchivedActions() ([]*Action, error) {
-	archiveDir := filepath.Join(s.BaseDir, "queue", "archive")
+// LookupTask returns the first task for which match reports true, or nil if
+// there is none. Unchanged files are answered from the metadata index; a full
+// scan is only done when the index has no fresh match. The archive is
+// searched after the main directory.
+func (s *Scanner) LookupTask(match func(*Task) bool) (*Task, error) {
+	dirs := append([]string{"."}, s.archiveDirs()...)
+	for _, dir := range dirs {
+		for _, rel := range s.index().names(TypeTask, dir) {
+			if e := s.index().Entries[rel]; !match(e.Task) {
+				continue
+			}
+			if e, err := s.load(rel, TypeTask); err == nil && match(e.Task) {
+				return s.taskFrom(rel, e), nil
+			}
+		}
+	}
 
-	if _, err := os.Stat(archiveDir); os.IsNotExist(err) {
-		return nil, nil
+	for _, dir := range dirs {
+		tasks, err := s.findTasksIn(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			if match(task) {
+				return task, nil
+			}
+		}
 	}
+	return nil, nil
+}
 
-	sc := &acore.Scanner{Store: acore.NewLocalStore(archiveDir)}
-	names, err := sc.FindByType("action")
-	if err != nil {
-		return nil, err
+// LookupProject returns the first project for which match reports true, or
+// nil if there is none. See LookupTask.
+func (s *Scanner) LookupProject(match func(*Project) bool) (*Project, error) {
+	dirs := append([]string{"."}, s.archiveDirs()...)
+	for _, dir := range dirs {
+		for _, rel := range s.index().names(TypeProject, dir) {
+			if e := s.index().Entries[rel]; !match(e.Project) {
+				continue
+			}
+			if e, err := s.load(rel, TypeProject); err == nil && match(e.Project) {
+				return s.projectFrom(rel, e), nil
+			}
+		}
 	}
 
-	var actions []*Action
-	for _, name := range names {
-		action, err := ParseActionFile(filepath.Join(archiveDir, name))
+	for _, dir := range dirs {
+		projects, err := s.findProjectsIn(dir)
 		if err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			if match(project) {
+				return project, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// LookupAction returns the first queued action for which match reports true,
+// or nil if there is none. See LookupTask.
+func (s *Scanner) LookupAction(match func(*Action) bool) (*Action, error) {
+	for _, rel := range s.index().names(TypeAction, "queue") {
+		if e := s.index().Entries[rel]; !match(e.Action) {
 			continue
 		}
-		actions = append(actions, action)
+		if e, err := s.load(rel, TypeAction); err == nil && match(e.Action) {
+			return s.actionFrom(rel, e), nil
+		}
 	}
 
-	return actions, nil
+	actions, err := s.FindActions()
+	if err != nil {
+		return nil, err
+	}
+	for _, action := range actions {
+		if match(action) {
+			return action, nil
+		}
+	}
+	return nil, nil
 }
 
 // SortTasks sorts tasks by various criteria
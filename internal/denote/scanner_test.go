@@ -0,0 +1,250 @@
+package denote
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mph-llm-experiments/acore"
+)
+
+// writeCorpus generates n task files in dir with realistic frontmatter and
+// a few hundred bytes of body each.
+func writeCorpus(tb testing.TB, dir string, n int) {
+	tb.Helper()
+	store := acore.NewLocalStore(dir)
+	body := strings.Repeat("Some notes about the task.\n", 20)
+	priorities := []string{PriorityP1, PriorityP2, PriorityP3}
+
+	for i := 0; i < n; i++ {
+		task := &Task{}
+		task.ID = acore.NewID()
+		task.Title = fmt.Sprintf("Task number %d", i)
+		task.IndexID = i + 1
+		task.Type = TypeTask
+		task.Tags = []string{"task", "bench"}
+		task.Created = acore.Now()
+		task.Modified = task.Created
+		task.Status = TaskStatusOpen
+		task.Priority = priorities[i%len(priorities)]
+		task.Area = "work"
+		task.Estimate = 3
+
+		filename := acore.BuildFilename(task.ID, task.Title, "task")
+		if err := acore.WriteFile(store, filename, task, body); err != nil {
+			tb.Fatalf("write corpus: %v", err)
+		}
+	}
+}
+
+func TestFindTasksParallelMatchesSerial(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 200)
+
+	serial := &Scanner{BaseDir: dir, Workers: 1}
+	want, err := serial.FindTasks()
+	if err != nil {
+		t.Fatalf("serial FindTasks: %v", err)
+	}
+	if len(want) != 200 {
+		t.Fatalf("serial FindTasks returned %d tasks, want 200", len(want))
+	}
+
+	os.Remove(filepath.Join(dir, IndexFileName))
+	parallel := &Scanner{BaseDir: dir, Workers: 8}
+	got, err := parallel.FindTasks()
+	if err != nil {
+		t.Fatalf("parallel FindTasks: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parallel FindTasks returned %d tasks, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].FilePath != want[i].FilePath || got[i].IndexID != want[i].IndexID {
+			t.Errorf("task %d: got %s (#%d), want %s (#%d)", i, got[i].FilePath, got[i].IndexID, want[i].FilePath, want[i].IndexID)
+		}
+		if got[i].Content != want[i].Content {
+			t.Errorf("task %d: content mismatch", i)
+		}
+	}
+}
+
+func TestFindTasksFrontmatterOnly(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 20)
+
+	fm := &Scanner{BaseDir: dir, FrontmatterOnly: true}
+	tasks, err := fm.FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	for _, task := range tasks {
+		if task.Content != "" {
+			t.Errorf("%s: Content should be empty in frontmatter-only mode", task.FilePath)
+		}
+		if task.Title == "" || task.IndexID == 0 || task.Priority == "" {
+			t.Errorf("%s: frontmatter not parsed: %+v", task.FilePath, task.TaskMetadata)
+		}
+	}
+
+	// A full scan must not be served the body-less entries cached above.
+	full := NewScanner(dir)
+	tasks, err = full.FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	for _, task := range tasks {
+		if task.Content == "" {
+			t.Errorf("%s: Content missing after frontmatter-only scan", task.FilePath)
+		}
+	}
+}
+
+func TestArchivedTasksStayResolvable(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 5)
+
+	tasks, err := NewScanner(dir).FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	archived := tasks[0]
+	yearDir := filepath.Join(dir, ArchiveDir, "2025")
+	if err := os.MkdirAll(yearDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(archived.FilePath, filepath.Join(yearDir, filepath.Base(archived.FilePath))); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewScanner(dir)
+	tasks, err = s.FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	if len(tasks) != 4 {
+		t.Errorf("FindTasks returned %d tasks, want 4 without the archive", len(tasks))
+	}
+
+	s.IncludeArchived = true
+	tasks, err = s.FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	if len(tasks) != 5 {
+		t.Errorf("FindTasks returned %d tasks, want 5 with the archive", len(tasks))
+	}
+
+	found, err := NewScanner(dir).LookupTask(func(task *Task) bool { return task.IndexID == archived.IndexID })
+	if err != nil {
+		t.Fatalf("LookupTask: %v", err)
+	}
+	if found == nil || filepath.Dir(found.FilePath) != yearDir {
+		t.Fatalf("LookupTask(#%d) = %v, want the archived copy", archived.IndexID, found)
+	}
+}
+
+const benchCorpusSize = 10000
+
+func benchmarkColdScan(b *testing.B, workers int, frontmatterOnly bool) {
+	dir := b.TempDir()
+	writeCorpus(b, dir, benchCorpusSize)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		os.Remove(filepath.Join(dir, IndexFileName))
+		s := &Scanner{BaseDir: dir, Workers: workers, FrontmatterOnly: frontmatterOnly}
+		b.StartTimer()
+
+		tasks, err := s.FindTasks()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(tasks) != benchCorpusSize {
+			b.Fatalf("got %d tasks, want %d", len(tasks), benchCorpusSize)
+		}
+	}
+}
+
+func BenchmarkFindTasksSerial(b *testing.B)   { benchmarkColdScan(b, 1, false) }
+func BenchmarkFindTasksParallel(b *testing.B) { benchmarkColdScan(b, 0, false) }
+
+func BenchmarkFindTasksFrontmatterOnly(b *testing.B) { benchmarkColdScan(b, 0, true) }
+
+func BenchmarkFindTasksIndexed(b *testing.B) {
+	dir := b.TempDir()
+	writeCorpus(b, dir, benchCorpusSize)
+	if _, err := NewScanner(dir).FindTasks(); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tasks, err := NewScanner(dir).FindTasks()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(tasks) != benchCorpusSize {
+			b.Fatalf("got %d tasks, want %d", len(tasks), benchCorpusSize)
+		}
+	}
+}
+
+func TestBrokenFilesReportedNotLost(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 2)
+	if err := os.MkdirAll(filepath.Join(dir, "queue"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	broken := "---\ntitle: [unclosed\nstatus: open\n---\n\nBody\n"
+	paths := []string{
+		filepath.Join(dir, acore.BuildFilename(acore.NewID(), "Broken task", "task")),
+		filepath.Join(dir, acore.BuildFilename(acore.NewID(), "Broken project", "project")),
+		filepath.Join(dir, "queue", acore.BuildFilename(acore.NewID(), "Broken action", "action")),
+	}
+	for _, path := range paths {
+		if err := os.WriteFile(path, []byte(broken), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A second scanner sees the same errors, so broken files are never
+	// cached as if they had parsed.
+	for _, pass := range []string{"cold index", "warm index"} {
+		s := NewScanner(dir)
+		tasks, err := s.FindTasks()
+		if err != nil {
+			t.Fatalf("%s: FindTasks: %v", pass, err)
+		}
+		projects, err := s.FindProjects()
+		if err != nil {
+			t.Fatalf("%s: FindProjects: %v", pass, err)
+		}
+		actions, err := s.FindActions()
+		if err != nil {
+			t.Fatalf("%s: FindActions: %v", pass, err)
+		}
+		// Scanning again must not report the same file twice
+		if _, err := s.FindTasks(); err != nil {
+			t.Fatalf("%s: FindTasks: %v", pass, err)
+		}
+
+		if len(tasks) != 2 || len(projects) != 0 || len(actions) != 0 {
+			t.Errorf("%s: got %d tasks, %d projects, %d actions; want 2, 0, 0",
+				pass, len(tasks), len(projects), len(actions))
+		}
+		errs := s.ParseErrors()
+		if len(errs) != len(paths) {
+			t.Fatalf("%s: %d parse errors, want %d: %v", pass, len(errs), len(paths), errs)
+		}
+		for i, path := range paths {
+			if errs[i].Path != path || errs[i].Message == "" {
+				t.Errorf("%s: parse error %d = %+v, want one for %s", pass, i, errs[i], path)
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package denote
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
@@ -100,6 +101,26 @@ type TaskMetadata struct {
 	Area      string `yaml:"area,omitempty" json:"area,omitempty"`
 	Assignee  string `yaml:"assignee,omitempty" json:"assignee,omitempty"`
 	Recur     string `yaml:"recur,omitempty" json:"recur,omitempty"`
+
+	// SeriesID links the instances of a recurring task. It is the ID of
+	// the first instance, which itself may not carry it (see Series).
+	SeriesID string `yaml:"series_id,omitempty" json:"series_id,omitempty"`
+
+	// ScheduledDate is the date the recurrence picked when "shift to next
+	// workday" moved the due date off it. The next instance is computed
+	// from it (see RecurrenceDate).
+	ScheduledDate string `yaml:"scheduled_date,omitempty" json:"scheduled_date,omitempty"`
+
+	// CompletedAt is set when the task is marked done or dropped and
+	// cleared when it is reopened.
+	CompletedAt   string         `yaml:"completed_at,omitempty" json:"completed_at,omitempty"`
+	StatusHistory []StatusChange `yaml:"status_history,omitempty" json:"status_history,omitempty"`
+}
+
+// StatusChange records a task entering a status.
+type StatusChange struct {
+	Status string `yaml:"status" json:"status"`
+	At     string `yaml:"at" json:"at"`
 }
 
 // ProjectMetadata holds domain-specific project fields.
@@ -152,6 +173,69 @@ func FileFromProject(p *Project) File {
 	}
 }
 
+// SetStatus changes the task's status, appending the transition to its
+// status history and setting or clearing CompletedAt. Setting the current
+// status again is a no-op.
+func (t *Task) SetStatus(status string) {
+	if status == t.TaskMetadata.Status {
+		return
+	}
+	now := acore.Now()
+	t.TaskMetadata.Status = status
+	t.TaskMetadata.StatusHistory = append(t.TaskMetadata.StatusHistory, StatusChange{Status: status, At: now})
+	if status == TaskStatusDone || status == TaskStatusDropped {
+		t.TaskMetadata.CompletedAt = now
+	} else {
+		t.TaskMetadata.CompletedAt = ""
+	}
+}
+
+// Series returns the ID of the recurring series the task belongs to: its
+// series_id, or for the first instance its own ID.
+func (t *Task) Series() string {
+	if t.TaskMetadata.SeriesID != "" {
+		return t.TaskMetadata.SeriesID
+	}
+	return t.ID
+}
+
+// SetDueDate changes the task's due date. A date set by hand replaces
+// the recurrence's schedule, so the scheduled date is cleared.
+func (t *Task) SetDueDate(date string) {
+	t.TaskMetadata.DueDate = date
+	t.TaskMetadata.ScheduledDate = ""
+}
+
+// RecurrenceDate returns the date the next instance of a recurring task
+// is computed from: the scheduled date if the due date was shifted to a
+// workday, and otherwise the due date.
+func (t *Task) RecurrenceDate() string {
+	if t.TaskMetadata.ScheduledDate != "" {
+		return t.TaskMetadata.ScheduledDate
+	}
+	return t.TaskMetadata.DueDate
+}
+
+// CompletedTime returns when the task was marked done or dropped, or the
+// current time if it has no valid completed_at.
+func (t *Task) CompletedTime() time.Time {
+	if completed, err := ParseTimestamp(t.TaskMetadata.CompletedAt); err == nil {
+		return completed
+	}
+	return time.Now()
+}
+
+// ParseTimestamp parses the timestamps atask writes (created, modified,
+// completed_at) as well as plain YYYY-MM-DD dates, in local time.
+func ParseTimestamp(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp: %q", s)
+}
+
 // IsTaggedForToday checks if the task is tagged for today
 func (t *Task) IsTaggedForToday() bool {
 	if t.TaskMetadata.TodayDate == "" {
@@ -369,6 +453,17 @@ type ActionMetadata struct {
 	ProposedAt string            `yaml:"proposed_at" json:"proposed_at"`
 	ProposedBy string            `yaml:"proposed_by" json:"proposed_by"`
 	Fields     map[string]string `yaml:"fields" json:"fields"`
+
+	// Policy is the queue policy's decision on the action, made when it
+	// was created. Nil if the queue has no policy file.
+	Policy *ActionPolicy `yaml:"policy,omitempty" json:"policy,omitempty"`
+}
+
+// ActionPolicy records which queue policy rule decided an action.
+type ActionPolicy struct {
+	Rule     string `yaml:"rule,omitempty" json:"rule,omitempty"` // empty if no rule matched
+	Decision string `yaml:"decision" json:"decision"`             // approve, reject or review
+	Reason   string `yaml:"reason,omitempty" json:"reason,omitempty"`
 }
 
 // Action combines acore.Entity with action-specific metadata.
@@ -10,7 +10,7 @@ import (
 )
 
 // UpdateTaskStatus updates the status field in a task file.
-func UpdateTaskStatus(filepath string, newStatus string) error {
+func UpdateTaskStatus(op *Op, filepath string, newStatus string) error {
 	if !IsValidTaskStatus(newStatus) {
 		return fmt.Errorf("invalid status: %s", newStatus)
 	}
@@ -20,15 +20,14 @@ func UpdateTaskStatus(filepath string, newStatus string) error {
 		return fmt.Errorf("failed to parse task: %w", err)
 	}
 
-	task.Status = newStatus
+	task.SetStatus(newStatus)
 	task.Modified = acore.Now()
 
-	s, n := storeAndName(filepath)
-	return acore.UpdateFrontmatter(s, n, task)
+	return SaveTask(op, task)
 }
 
 // UpdateTaskPriority updates the priority field in a task file.
-func UpdateTaskPriority(filepath string, newPriority string) error {
+func UpdateTaskPriority(op *Op, filepath string, newPriority string) error {
 	if newPriority != "" && !IsValidPriority(newPriority) {
 		return fmt.Errorf("invalid priority: %s", newPriority)
 	}
@@ -41,12 +40,11 @@ func UpdateTaskPriority(filepath string, newPriority string) error {
 	task.Priority = newPriority
 	task.Modified = acore.Now()
 
-	s, n := storeAndName(filepath)
-	return acore.UpdateFrontmatter(s, n, task)
+	return SaveTask(op, task)
 }
 
 // UpdateTaskProjectID updates the project_id field in a task file.
-func UpdateTaskProjectID(filepath string, projectID string) error {
+func UpdateTaskProjectID(op *Op, filepath string, projectID string) error {
 	task, err := ParseTaskFile(filepath)
 	if err != nil {
 		return fmt.Errorf("failed to parse task: %w", err)
@@ -55,26 +53,24 @@ func UpdateTaskProjectID(filepath string, projectID string) error {
 	task.ProjectID = projectID
 	task.Modified = acore.Now()
 
-	s, n := storeAndName(filepath)
-	return acore.UpdateFrontmatter(s, n, task)
+	return SaveTask(op, task)
 }
 
 // UpdateTaskDueDate updates the due_date field in a task file.
-func UpdateTaskDueDate(filepath string, dueDate string) error {
+func UpdateTaskDueDate(op *Op, filepath string, dueDate string) error {
 	task, err := ParseTaskFile(filepath)
 	if err != nil {
 		return fmt.Errorf("failed to parse task: %w", err)
 	}
 
-	task.DueDate = dueDate
+	task.SetDueDate(dueDate)
 	task.Modified = acore.Now()
 
-	s, n := storeAndName(filepath)
-	return acore.UpdateFrontmatter(s, n, task)
+	return SaveTask(op, task)
 }
 
 // UpdateTaskStartDate updates the start_date field in a task file.
-func UpdateTaskStartDate(filepath string, startDate string) error {
+func UpdateTaskStartDate(op *Op, filepath string, startDate string) error {
 	task, err := ParseTaskFile(filepath)
 	if err != nil {
 		return fmt.Errorf("failed to parse task: %w", err)
@@ -83,12 +79,11 @@ func UpdateTaskStartDate(filepath string, startDate string) error {
 	task.StartDate = startDate
 	task.Modified = acore.Now()
 
-	s, n := storeAndName(filepath)
-	return acore.UpdateFrontmatter(s, n, task)
+	return SaveTask(op, task)
 }
 
 // UpdateTaskEstimate updates the estimate field in a task file.
-func UpdateTaskEstimate(filepath string, estimate int) error {
+func UpdateTaskEstimate(op *Op, filepath string, estimate int) error {
 	if estimate != 0 && !IsValidEstimate(estimate) {
 		return fmt.Errorf("invalid estimate: %d (must be 0, 1, 2, 3, 5, 8, or 13)", estimate)
 	}
@@ -101,12 +96,11 @@ func UpdateTaskEstimate(filepath string, estimate int) error {
 	task.Estimate = estimate
 	task.Modified = acore.Now()
 
-	s, n := storeAndName(filepath)
-	return acore.UpdateFrontmatter(s, n, task)
+	return SaveTask(op, task)
 }
 
 // UpdateTaskArea updates the area field in a task file.
-func UpdateTaskArea(filepath string, area string) error {
+func UpdateTaskArea(op *Op, filepath string, area string) error {
 	task, err := ParseTaskFile(filepath)
 	if err != nil {
 		return fmt.Errorf("failed to parse task: %w", err)
@@ -115,12 +109,11 @@ func UpdateTaskArea(filepath string, area string) error {
 	task.Area = area
 	task.Modified = acore.Now()
 
-	s, n := storeAndName(filepath)
-	return acore.UpdateFrontmatter(s, n, task)
+	return SaveTask(op, task)
 }
 
 // UpdateTaskTags updates the tags field in a task file.
-func UpdateTaskTags(filepath string, tags []string) error {
+func UpdateTaskTags(op *Op, filepath string, tags []string) error {
 	task, err := ParseTaskFile(filepath)
 	if err != nil {
 		return fmt.Errorf("failed to parse task: %w", err)
@@ -129,14 +122,13 @@ func UpdateTaskTags(filepath string, tags []string) error {
 	task.Tags = tags
 	task.Modified = acore.Now()
 
-	s, n := storeAndName(filepath)
-	return acore.UpdateFrontmatter(s, n, task)
+	return SaveTask(op, task)
 }
 
 // BulkUpdateTaskStatus updates status for multiple tasks.
-func BulkUpdateTaskStatus(filepaths []string, newStatus string) error {
+func BulkUpdateTaskStatus(op *Op, filepaths []string, newStatus string) error {
 	for _, filepath := range filepaths {
-		if err := UpdateTaskStatus(filepath, newStatus); err != nil {
+		if err := UpdateTaskStatus(op, filepath, newStatus); err != nil {
 			return fmt.Errorf("failed to update %s: %w", filepath, err)
 		}
 	}
@@ -144,14 +136,24 @@ func BulkUpdateTaskStatus(filepaths []string, newStatus string) error {
 }
 
 // UpdateProjectFile updates a project file with new metadata.
-func UpdateProjectFile(path string, project *Project) error {
+func UpdateProjectFile(op *Op, path string, project *Project) error {
 	project.Modified = acore.Now()
-	s, n := storeAndName(path)
-	return acore.UpdateFrontmatter(s, n, project)
+	modTime, err := WriteFrontmatter(op, path, project, project.ModTime)
+	if err != nil {
+		return err
+	}
+	project.ModTime = modTime
+	return nil
 }
 
 // AddLogEntry adds a timestamped log entry to a task file.
-func AddLogEntry(filepath string, message string) error {
+func AddLogEntry(op *Op, filepath string, message string) error {
+	unlock, err := LockFile(filepath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	content, err := os.ReadFile(filepath)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
@@ -200,7 +202,7 @@ func AddLogEntry(filepath string, message string) error {
 	}
 
 	newContent := strings.Join(newLines, "\n")
-	if err := os.WriteFile(filepath, []byte(newContent), 0644); err != nil {
+	if err := WriteFileAtomic(op, filepath, []byte(newContent), 0644); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
@@ -208,7 +210,13 @@ func AddLogEntry(filepath string, message string) error {
 }
 
 // DeleteLogEntry removes a log entry matching the given line from a task file.
-func DeleteLogEntry(filepath string, line string) error {
+func DeleteLogEntry(op *Op, filepath string, line string) error {
+	unlock, err := LockFile(filepath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	content, err := os.ReadFile(filepath)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
@@ -240,7 +248,7 @@ func DeleteLogEntry(filepath string, line string) error {
 	}
 
 	newContent := strings.Join(collapsed, "\n")
-	if err := os.WriteFile(filepath, []byte(newContent), 0644); err != nil {
+	if err := WriteFileAtomic(op, filepath, []byte(newContent), 0644); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
@@ -0,0 +1,210 @@
+package denote
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mph-llm-experiments/acore"
+)
+
+// ErrStaleRead is returned when a file changed on disk after it was parsed,
+// so writing the parsed copy back would discard someone else's edit.
+var ErrStaleRead = errors.New("file changed on disk since it was read; reload and try again")
+
+// lockDirName holds the advisory lock files for a directory. Lock files are
+// kept out of the notes directory itself so they are never mistaken for notes.
+const lockDirName = ".atask-locks"
+
+// lockTimeout bounds how long a writer waits for another process.
+const lockTimeout = 5 * time.Second
+
+// lockStripes is the number of lock files the notes in a directory share,
+// so the lock directory stays the same size however many notes are written.
+const lockStripes = 32
+
+// LockFile takes the advisory write lock for path. Writers in other atask
+// processes (CLI, TUI, agents) block until unlock is called. Notes share a
+// fixed set of lock files, so a caller must not hold two file locks at once.
+func LockFile(path string) (unlock func(), err error) {
+	h := fnv.New32a()
+	h.Write([]byte(filepath.Base(path)))
+	stripe := fmt.Sprintf("file-%02d.lock", h.Sum32()%lockStripes)
+	return lockPath(filepath.Join(filepath.Dir(path), lockDirName, stripe))
+}
+
+// LockDir takes the advisory lock for dir. It serializes operations that
+// create, rename or number files in the directory.
+func LockDir(dir string) (unlock func(), err error) {
+	return lockPath(filepath.Join(dir, lockDirName, "dir.lock"))
+}
+
+func lockPath(lockFile string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(lockFile), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", lockFile, err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("timed out waiting for lock on %s", filepath.Base(lockFile))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	return func() {
+		releaseLock(f)
+		f.Close()
+	}, nil
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers see either the old or the new content. The
+// change is recorded in the undo journal under op.
+func WriteFileAtomic(op *Op, path string, data []byte, perm os.FileMode) error {
+	before, err := readCurrent(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := op.record(path, before, data); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	return nil
+}
+
+// WriteFrontmatter replaces the frontmatter of the file at path with v,
+// keeping the body, under the file's lock. If readAt is non-zero and the
+// file's mtime no longer matches it, ErrStaleRead is returned and nothing is
+// written. The change is journaled under op and the file's new mtime is
+// returned.
+func WriteFrontmatter(op *Op, path string, v interface{}, readAt time.Time) (time.Time, error) {
+	unlock, err := LockFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	if !readAt.IsZero() && !info.ModTime().Equal(readAt) {
+		return time.Time{}, fmt.Errorf("%s: %w", filepath.Base(path), ErrStaleRead)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// Let acore render the frontmatter into a scratch copy in the same
+	// directory, then rename the copy over the original.
+	scratch, err := os.MkdirTemp(filepath.Dir(path), ".atask-write-")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+
+	name := filepath.Base(path)
+	if err := os.WriteFile(filepath.Join(scratch, name), content, info.Mode().Perm()); err != nil {
+		return time.Time{}, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := acore.UpdateFrontmatter(acore.NewLocalStore(scratch), name, v); err != nil {
+		return time.Time{}, err
+	}
+	updated, err := os.ReadFile(filepath.Join(scratch, name))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read temp file: %w", err)
+	}
+	if err := op.record(path, content, updated); err != nil {
+		return time.Time{}, err
+	}
+	if err := os.Rename(filepath.Join(scratch, name), path); err != nil {
+		return time.Time{}, fmt.Errorf("failed to replace file: %w", err)
+	}
+
+	info, err = os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// SaveTask writes the task's frontmatter back to its file, refusing if the
+// file changed since the task was parsed.
+func SaveTask(op *Op, task *Task) error {
+	modTime, err := WriteFrontmatter(op, task.FilePath, task, task.ModTime)
+	if err != nil {
+		return err
+	}
+	task.ModTime = modTime
+	return nil
+}
+
+// SaveProject writes the project's frontmatter back to its file, refusing if
+// the file changed since the project was parsed.
+func SaveProject(op *Op, project *Project) error {
+	modTime, err := WriteFrontmatter(op, project.FilePath, project, project.ModTime)
+	if err != nil {
+		return err
+	}
+	project.ModTime = modTime
+	return nil
+}
+
+// SaveAction writes the action's frontmatter back to its file, refusing if
+// the file changed since the action was parsed.
+func SaveAction(op *Op, action *Action) error {
+	modTime, err := WriteFrontmatter(op, action.FilePath, action, action.ModTime)
+	if err != nil {
+		return err
+	}
+	action.ModTime = modTime
+	return nil
+}
@@ -0,0 +1,90 @@
+package denote
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveTaskDetectsStaleRead(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 1)
+
+	tasks, err := NewScanner(dir).FindTasks()
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("FindTasks: %v (%d tasks)", err, len(tasks))
+	}
+	path := tasks[0].FilePath
+
+	first, err := ParseTaskFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ParseTaskFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.Status = TaskStatusDone
+	if err := SaveTask(nil, first); err != nil {
+		t.Fatalf("first save: %v", err)
+	}
+	// Saving the same copy again is fine: SaveTask tracks its own write.
+	first.Priority = PriorityP1
+	if err := SaveTask(nil, first); err != nil {
+		t.Fatalf("second save of same copy: %v", err)
+	}
+
+	// Force a distinct mtime in case both writes land in the same tick.
+	later := time.Now().Add(time.Second)
+	os.Chtimes(path, later, later)
+
+	second.Status = TaskStatusDropped
+	if err := SaveTask(nil, second); !errors.Is(err, ErrStaleRead) {
+		t.Fatalf("save of stale copy: got %v, want ErrStaleRead", err)
+	}
+
+	got, err := ParseTaskFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != TaskStatusDone || got.Priority != PriorityP1 {
+		t.Errorf("stale write clobbered file: status=%s priority=%s", got.Status, got.Priority)
+	}
+
+	// No temp files or scratch directories are left next to the task.
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if matched, _ := filepath.Match(".atask-write-*", e.Name()); matched {
+			t.Errorf("leftover scratch directory %s", e.Name())
+		}
+	}
+}
+
+func TestLockFilesStayBounded(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpus(t, dir, 3*lockStripes)
+
+	tasks, err := NewScanner(dir).FindTasks()
+	if err != nil {
+		t.Fatalf("FindTasks: %v", err)
+	}
+	op := NewOp(dir, "bulk")
+	for _, task := range tasks {
+		task.Priority = PriorityP2
+		if err := SaveTask(op, task); err != nil {
+			t.Fatalf("SaveTask: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, lockDirName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The file stripes plus the journal's own lock
+	if len(entries) > lockStripes+1 {
+		t.Errorf("%d lock files for %d notes, want at most %d", len(entries), len(tasks), lockStripes+1)
+	}
+}
@@ -0,0 +1,440 @@
+// Package doctor checks a notes directory for inconsistent task and project
+// metadata and applies the repairs that are safe to make automatically.
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mph-llm-experiments/acore"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+// Check names reported in Issue.Check
+const (
+	CheckParseError       = "parse_error"
+	CheckInvalidStatus    = "invalid_status"
+	CheckInvalidPriority  = "invalid_priority"
+	CheckMalformedDate    = "malformed_date"
+	CheckInvalidEstimate  = "invalid_estimate"
+	CheckDanglingProject  = "dangling_project"
+	CheckDuplicateIndexID = "duplicate_index_id"
+	CheckSlugMismatch     = "slug_mismatch"
+	CheckRecurWithoutDue  = "recur_without_due"
+)
+
+// ReportFileName is the file in the notes directory that `atask doctor
+// --fix` saves its report to.
+const ReportFileName = ".atask-doctor-report.json"
+
+// Issue is a single problem found in a file. Fix describes the repair for
+// the issue, or is empty when the issue needs a human. Lossy repairs throw
+// information away and are only applied when asked for explicitly.
+type Issue struct {
+	Check    string `json:"check"`
+	Path     string `json:"path"`
+	IndexID  int    `json:"index_id,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Message  string `json:"message"`
+	Fix      string `json:"fix,omitempty"`
+	Lossy    bool   `json:"lossy,omitempty"`
+	Fixed    bool   `json:"fixed"`
+	FixError string `json:"fix_error,omitempty"`
+
+	apply func(op *denote.Op) error
+}
+
+// Report is the result of checking a directory.
+type Report struct {
+	Directory string   `json:"directory"`
+	Checked   string   `json:"checked"`
+	Tasks     int      `json:"tasks"`
+	Projects  int      `json:"projects"`
+	Issues    []*Issue `json:"issues"`
+}
+
+// Fixable returns the number of issues that have a safe repair.
+func (r *Report) Fixable() int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.apply != nil && !issue.Lossy && !issue.Fixed {
+			n++
+		}
+	}
+	return n
+}
+
+// Fix applies every safe repair, plus the lossy ones when lossy is set,
+// journaled under op, and returns the number that succeeded. Renames run
+// last because earlier repairs write to the original path.
+func (r *Report) Fix(op *denote.Op, lossy bool) int {
+	fixed := 0
+	for _, renames := range []bool{false, true} {
+		for _, issue := range r.Issues {
+			if issue.apply == nil || issue.Fixed || (issue.Lossy && !lossy) || (issue.Check == CheckSlugMismatch) != renames {
+				continue
+			}
+			if err := issue.apply(op); err != nil {
+				issue.FixError = err.Error()
+				continue
+			}
+			issue.Fixed = true
+			fixed++
+		}
+	}
+	return fixed
+}
+
+// Write saves the report as JSON at path, so the repairs made by a --fix
+// run can be reviewed later.
+func (r *Report) Write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	return denote.WriteFileAtomic(nil, path, append(data, '\n'), 0644)
+}
+
+// Check scans dir and reports every issue found.
+func Check(dir string) (*Report, error) {
+	// Archived files still own their IDs and can be project targets, so
+	// they are checked too.
+	scanner := denote.NewScanner(dir)
+	scanner.IncludeArchived = true
+	tasks, err := scanner.FindTasks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan tasks: %w", err)
+	}
+	projects, err := scanner.FindProjects()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan projects: %w", err)
+	}
+
+	r := &Report{Directory: dir, Checked: time.Now().Format(time.RFC3339), Tasks: len(tasks), Projects: len(projects), Issues: []*Issue{}}
+
+	for _, pe := range scanner.ParseErrors() {
+		r.add(&Issue{Check: CheckParseError, Path: pe.Path, Message: pe.Message})
+	}
+
+	projectRefs := make(map[string]bool)
+	for _, p := range projects {
+		projectRefs[strconv.Itoa(p.IndexID)] = true
+		projectRefs[p.ID] = true
+		r.checkProject(p)
+	}
+	for _, t := range tasks {
+		r.checkTask(t, projectRefs)
+	}
+
+	r.checkDuplicateIDs(dir, tasks, projects)
+
+	return r, nil
+}
+
+func (r *Report) add(issue *Issue) {
+	r.Issues = append(r.Issues, issue)
+}
+
+func (r *Report) checkTask(t *denote.Task, projectRefs map[string]bool) {
+	save := func(op *denote.Op) error {
+		t.Modified = acore.Now()
+		return denote.SaveTask(op, t)
+	}
+
+	if !denote.IsValidTaskStatus(t.Status) {
+		issue := &Issue{Check: CheckInvalidStatus, Path: t.FilePath, IndexID: t.IndexID, Field: "status", Value: t.Status,
+			Message: fmt.Sprintf("%q is not a valid task status", t.Status)}
+		if fixed := normalize(t.Status); denote.IsValidTaskStatus(fixed) {
+			issue.Fix = "set status to " + fixed
+			issue.apply = func(op *denote.Op) error { t.Status = fixed; return save(op) }
+		}
+		r.add(issue)
+	}
+
+	if t.Priority != "" && !denote.IsValidPriority(t.Priority) {
+		issue := &Issue{Check: CheckInvalidPriority, Path: t.FilePath, IndexID: t.IndexID, Field: "priority", Value: t.Priority,
+			Message: fmt.Sprintf("%q is not a valid priority", t.Priority)}
+		if fixed := normalize(t.Priority); denote.IsValidPriority(fixed) {
+			issue.Fix = "set priority to " + fixed
+			issue.apply = func(op *denote.Op) error { t.Priority = fixed; return save(op) }
+		}
+		r.add(issue)
+	}
+
+	r.checkDate(t.FilePath, t.IndexID, "due_date", &t.DueDate, save)
+	r.checkDate(t.FilePath, t.IndexID, "start_date", &t.StartDate, save)
+	r.checkDate(t.FilePath, t.IndexID, "today_date", &t.TodayDate, save)
+
+	if t.Estimate != 0 && !denote.IsValidEstimate(t.Estimate) {
+		r.add(&Issue{Check: CheckInvalidEstimate, Path: t.FilePath, IndexID: t.IndexID, Field: "estimate", Value: strconv.Itoa(t.Estimate),
+			Message: fmt.Sprintf("estimate %d is not one of 1, 2, 3, 5, 8, 13", t.Estimate)})
+	}
+
+	// The project may only be missing from this copy of the directory (an
+	// unsynced device, a typo in the ID), so the link is not dropped unless
+	// asked.
+	if t.ProjectID != "" && !projectRefs[t.ProjectID] {
+		r.add(&Issue{Check: CheckDanglingProject, Path: t.FilePath, IndexID: t.IndexID, Field: "project_id", Value: t.ProjectID,
+			Message: fmt.Sprintf("project %s does not exist", t.ProjectID),
+			Fix:     "clear project_id",
+			Lossy:   true,
+			apply:   func(op *denote.Op) error { t.ProjectID = ""; return save(op) },
+		})
+	}
+
+	if t.Recur != "" && t.DueDate == "" {
+		r.add(&Issue{Check: CheckRecurWithoutDue, Path: t.FilePath, IndexID: t.IndexID, Field: "recur", Value: t.Recur,
+			Message: "recurring task has no due date, so no next instance can be created"})
+	}
+
+	r.checkSlug(t.FilePath, t.IndexID, t.Title)
+}
+
+func (r *Report) checkProject(p *denote.Project) {
+	save := func(op *denote.Op) error {
+		p.Modified = acore.Now()
+		return denote.UpdateProjectFile(op, p.FilePath, p)
+	}
+
+	if !denote.IsValidProjectStatus(p.Status) {
+		issue := &Issue{Check: CheckInvalidStatus, Path: p.FilePath, IndexID: p.IndexID, Field: "status", Value: p.Status,
+			Message: fmt.Sprintf("%q is not a valid project status", p.Status)}
+		if fixed := normalize(p.Status); denote.IsValidProjectStatus(fixed) {
+			issue.Fix = "set status to " + fixed
+			issue.apply = func(op *denote.Op) error { p.Status = fixed; return save(op) }
+		}
+		r.add(issue)
+	}
+
+	if p.Priority != "" && !denote.IsValidPriority(p.Priority) {
+		issue := &Issue{Check: CheckInvalidPriority, Path: p.FilePath, IndexID: p.IndexID, Field: "priority", Value: p.Priority,
+			Message: fmt.Sprintf("%q is not a valid priority", p.Priority)}
+		if fixed := normalize(p.Priority); denote.IsValidPriority(fixed) {
+			issue.Fix = "set priority to " + fixed
+			issue.apply = func(op *denote.Op) error { p.Priority = fixed; return save(op) }
+		}
+		r.add(issue)
+	}
+
+	r.checkDate(p.FilePath, p.IndexID, "due_date", &p.DueDate, save)
+	r.checkDate(p.FilePath, p.IndexID, "start_date", &p.StartDate, save)
+
+	r.checkSlug(p.FilePath, p.IndexID, p.Title)
+}
+
+// checkDate reports a date field that isn't YYYY-MM-DD. Values that are an
+// unambiguous spelling of a date (2026/03/01, 2026-3-1, an RFC 3339
+// timestamp) are rewritten in canonical form.
+func (r *Report) checkDate(path string, indexID int, field string, value *string, save func(*denote.Op) error) {
+	if *value == "" {
+		return
+	}
+	if _, err := time.Parse("2006-01-02", *value); err == nil {
+		return
+	}
+
+	issue := &Issue{Check: CheckMalformedDate, Path: path, IndexID: indexID, Field: field, Value: *value,
+		Message: fmt.Sprintf("%s %q is not a YYYY-MM-DD date", field, *value)}
+	if fixed, ok := normalizeDate(*value); ok {
+		issue.Fix = fmt.Sprintf("set %s to %s", field, fixed)
+		issue.apply = func(op *denote.Op) error { *value = fixed; return save(op) }
+	}
+	r.add(issue)
+}
+
+// checkSlug reports files whose filename slug no longer matches the title,
+// typically because the title was edited by hand.
+func (r *Report) checkSlug(path string, indexID int, title string) {
+	base := filepath.Base(path)
+	id, _, fileType, err := acore.ParseFilename(base)
+	if err != nil || title == "" {
+		// Legacy Denote filenames are left to `atask migrate`
+		return
+	}
+
+	expected := acore.BuildFilename(id, title, fileType)
+	if expected == base {
+		return
+	}
+
+	newPath := filepath.Join(filepath.Dir(path), expected)
+	r.add(&Issue{Check: CheckSlugMismatch, Path: path, IndexID: indexID, Field: "title", Value: title,
+		Message: "filename does not match title",
+		Fix:     "rename to " + expected,
+		apply: func(op *denote.Op) error {
+			// The directory lock keeps anyone from creating the target
+			// meanwhile; the file lock waits out writers of the old path.
+			unlockDir, err := denote.LockDir(filepath.Dir(path))
+			if err != nil {
+				return err
+			}
+			defer unlockDir()
+			unlock, err := denote.LockFile(path)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
+			if _, err := os.Stat(newPath); err == nil {
+				return fmt.Errorf("target file already exists: %s", expected)
+			}
+			return denote.MoveFile(op, path, newPath)
+		},
+	})
+}
+
+// checkDuplicateIDs reports index_ids shared by more than one task or
+// project (tasks and projects draw from the same counter). The oldest file
+// keeps its ID; the others are given fresh ones. Tasks refer to projects by
+// index_id, so a renumbered project takes its tasks along, unless two
+// projects shared the ID and the tasks can't be told apart.
+func (r *Report) checkDuplicateIDs(dir string, tasks []*denote.Task, projects []*denote.Project) {
+	type holder struct {
+		entity  *acore.Entity
+		project bool
+		save    func(*denote.Op) error
+	}
+
+	byID := make(map[int][]holder)
+	used := make(map[int]bool)
+	refs := make(map[string][]*denote.Task) // project_id -> tasks
+	for _, t := range tasks {
+		t := t
+		used[t.IndexID] = true
+		byID[t.IndexID] = append(byID[t.IndexID], holder{&t.Entity, false, func(op *denote.Op) error {
+			return denote.SaveTask(op, t)
+		}})
+		if t.ProjectID != "" {
+			refs[t.ProjectID] = append(refs[t.ProjectID], t)
+		}
+	}
+	for _, p := range projects {
+		p := p
+		used[p.IndexID] = true
+		byID[p.IndexID] = append(byID[p.IndexID], holder{&p.Entity, true, func(op *denote.Op) error {
+			return denote.UpdateProjectFile(op, p.FilePath, p)
+		}})
+	}
+
+	var ids []int
+	for id, holders := range byID {
+		if id > 0 && len(holders) > 1 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	nextID := func() (int, error) {
+		unlock, err := denote.LockDir(dir)
+		if err != nil {
+			return 0, err
+		}
+		defer unlock()
+
+		counter, err := acore.NewIndexCounter(acore.NewLocalStore(dir), "atask")
+		if err != nil {
+			return 0, err
+		}
+		for {
+			id, err := counter.Next()
+			if err != nil {
+				return 0, err
+			}
+			if !used[id] {
+				used[id] = true
+				return id, nil
+			}
+		}
+	}
+
+	for _, id := range ids {
+		holders := byID[id]
+		sort.SliceStable(holders, func(i, j int) bool {
+			if holders[i].entity.Created != holders[j].entity.Created {
+				return holders[i].entity.Created < holders[j].entity.Created
+			}
+			return holders[i].entity.FilePath < holders[j].entity.FilePath
+		})
+		sharedByProjects := 0
+		for _, h := range holders {
+			if h.project {
+				sharedByProjects++
+			}
+		}
+		members := refs[strconv.Itoa(id)]
+
+		keeper := filepath.Base(holders[0].entity.FilePath)
+		for _, h := range holders[1:] {
+			h := h
+			issue := &Issue{Check: CheckDuplicateIndexID, Path: h.entity.FilePath, IndexID: id, Field: "index_id", Value: strconv.Itoa(id),
+				Message: fmt.Sprintf("index_id %d is also used by %s", id, keeper),
+				Fix:     "assign a new index_id"}
+			var moved []*denote.Task
+			switch {
+			case h.project && sharedByProjects > 1 && len(members) > 0:
+				// Which project each task meant is unknown, so they stay
+				// with whichever file keeps the ID.
+				issue.Fix = fmt.Sprintf("assign a new index_id; %d tasks with project_id %d are left as they are", len(members), id)
+				issue.Lossy = true
+			case h.project && len(members) > 0:
+				moved = members
+				issue.Fix = fmt.Sprintf("assign a new index_id and update project_id on %d tasks", len(members))
+			}
+			issue.apply = func(op *denote.Op) error {
+				newID, err := nextID()
+				if err != nil {
+					return fmt.Errorf("failed to get next index ID: %w", err)
+				}
+				h.entity.IndexID = newID
+				h.entity.Modified = acore.Now()
+				if err := h.save(op); err != nil {
+					return err
+				}
+				for _, t := range moved {
+					t.ProjectID = strconv.Itoa(newID)
+					t.Modified = acore.Now()
+					if err := denote.SaveTask(op, t); err != nil {
+						return fmt.Errorf("failed to update project_id in %s: %w", filepath.Base(t.FilePath), err)
+					}
+				}
+				return nil
+			}
+			r.add(issue)
+		}
+	}
+}
+
+// normalize lowercases and trims a status or priority value.
+func normalize(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// dateLayouts are alternative spellings of a calendar date that can be
+// rewritten as YYYY-MM-DD without guessing.
+var dateLayouts = []string{
+	"2006-1-2",
+	"2006/01/02",
+	"2006/1/2",
+	"2006.01.02",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+}
+
+func normalizeDate(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format("2006-01-02"), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,276 @@
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mph-llm-experiments/acore"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+// writeNote writes a note whose frontmatter is exactly fields, so fixtures
+// can hold values the typed structs would never produce.
+func writeNote(t *testing.T, dir, slugTitle, fileType, fields string) string {
+	t.Helper()
+	id := acore.NewID()
+	path := filepath.Join(dir, acore.BuildFilename(id, slugTitle, fileType))
+	content := fmt.Sprintf("---\nid: %q\ntype: %s\n%s---\n\nNotes\n", id, fileType, fields)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// issueKeys summarizes a report as sorted "check file field" strings.
+func issueKeys(r *Report, names map[string]string) []string {
+	var keys []string
+	for _, issue := range r.Issues {
+		keys = append(keys, fmt.Sprintf("%s %s %s", issue.Check, names[issue.Path], issue.Field))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sameKeys(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	sort.Strings(want)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s:\n got %q\nwant %q", what, got, want)
+	}
+}
+
+func TestCheckAndFix(t *testing.T) {
+	dir := t.TempDir()
+
+	names := map[string]string{
+		writeNote(t, dir, "Status", "task", "title: Status\nindex_id: 1\nstatus: \" Open\"\n"):                                  "status",
+		writeNote(t, dir, "Someday", "task", "title: Someday\nindex_id: 2\nstatus: someday\n"):                                  "someday",
+		writeNote(t, dir, "Priority", "task", "title: Priority\nindex_id: 3\nstatus: open\npriority: P1\n"):                     "priority",
+		writeNote(t, dir, "Dates", "task", "title: Dates\nindex_id: 4\nstatus: open\ndue_date: 2026/03/01\nstart_date: soon\n"): "dates",
+		writeNote(t, dir, "Estimate", "task", "title: Estimate\nindex_id: 5\nstatus: open\nestimate: 4\n"):                      "estimate",
+		writeNote(t, dir, "Orphan", "task", "title: Orphan\nindex_id: 6\nstatus: open\nproject_id: \"999\"\n"):                  "orphan",
+		writeNote(t, dir, "First", "task", "title: First\nindex_id: 7\nstatus: open\ncreated: \"2026-01-01T10:00:00Z\"\n"):      "first",
+		writeNote(t, dir, "Second", "task", "title: Second\nindex_id: 7\nstatus: open\ncreated: \"2026-02-01T10:00:00Z\"\n"):    "second",
+		writeNote(t, dir, "Old title", "task", "title: New title\nindex_id: 8\nstatus: open\n"):                                 "renamed",
+		writeNote(t, dir, "Repeats", "task", "title: Repeats\nindex_id: 9\nstatus: open\nrecur: daily\n"):                       "repeats",
+		writeNote(t, dir, "Broken", "task", "title: [unclosed\n"):                                                               "broken",
+		writeNote(t, dir, "Garden", "project", "title: Garden\nindex_id: 10\nstatus: ACTIVE\npriority: high\n"):                 "garden",
+		writeNote(t, dir, "Healthy", "task", "title: Healthy\nindex_id: 11\nstatus: open\nproject_id: \"10\"\n"):                "healthy",
+		// A project sharing a task's ID, with a task under it
+		writeNote(t, dir, "Older", "task", "title: Older\nindex_id: 12\nstatus: open\ncreated: \"2026-01-01T10:00:00Z\"\n"):        "older",
+		writeNote(t, dir, "Shared", "project", "title: Shared\nindex_id: 12\nstatus: active\ncreated: \"2026-02-01T10:00:00Z\"\n"): "shared",
+		writeNote(t, dir, "Member", "task", "title: Member\nindex_id: 14\nstatus: open\nproject_id: \"12\"\n"):                     "member",
+		// Two projects sharing an ID: their tasks can't be told apart
+		writeNote(t, dir, "Alpha", "project", "title: Alpha\nindex_id: 13\nstatus: active\ncreated: \"2026-01-01T10:00:00Z\"\n"): "alpha",
+		writeNote(t, dir, "Beta", "project", "title: Beta\nindex_id: 13\nstatus: active\ncreated: \"2026-02-01T10:00:00Z\"\n"):   "beta",
+		writeNote(t, dir, "Either", "task", "title: Either\nindex_id: 15\nstatus: open\nproject_id: \"13\"\n"):                   "either",
+	}
+
+	report, err := Check(dir)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if report.Tasks != 14 || report.Projects != 4 {
+		t.Errorf("checked %d tasks and %d projects, want 14 and 4", report.Tasks, report.Projects)
+	}
+	sameKeys(t, "issues", issueKeys(report, names), []string{
+		"parse_error broken ",
+		"invalid_status status status",
+		"invalid_status someday status",
+		"invalid_priority priority priority",
+		"malformed_date dates due_date",
+		"malformed_date dates start_date",
+		"invalid_estimate estimate estimate",
+		"dangling_project orphan project_id",
+		"duplicate_index_id second index_id",
+		"slug_mismatch renamed title",
+		"recur_without_due repeats recur",
+		"invalid_status garden status",
+		"invalid_priority garden priority",
+		"duplicate_index_id shared index_id",
+		"duplicate_index_id beta index_id",
+	})
+
+	// Safe repairs: status, priority, due_date, the duplicate IDs of a
+	// task and of a project with its task, the project status and the
+	// rename. The dangling project_id and the ambiguous project ID are
+	// lossy.
+	if n := report.Fixable(); n != 7 {
+		t.Errorf("Fixable() = %d, want 7", n)
+	}
+	if n := report.Fix(nil, false); n != 7 {
+		t.Errorf("Fix() = %d, want 7", n)
+	}
+	for _, issue := range report.Issues {
+		if issue.FixError != "" {
+			t.Errorf("%s %s: %s", issue.Check, issue.Path, issue.FixError)
+		}
+	}
+
+	// Renamed files get new names in the re-check
+	for path, name := range names {
+		if name == "renamed" {
+			delete(names, path)
+			names[filepath.Join(filepath.Dir(path), acore.BuildFilename(filepath.Base(path)[:26], "New title", "task"))] = name
+		}
+	}
+
+	after, err := Check(dir)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	sameKeys(t, "issues after --fix", issueKeys(after, names), []string{
+		"parse_error broken ",
+		"invalid_status someday status",
+		"malformed_date dates start_date",
+		"invalid_estimate estimate estimate",
+		"dangling_project orphan project_id",
+		"recur_without_due repeats recur",
+		"invalid_priority garden priority",
+		"duplicate_index_id beta index_id",
+	})
+
+	scanner := denote.NewScanner(dir)
+	tasks, _ := scanner.FindTasks()
+	projects, _ := scanner.FindProjects()
+	byName := make(map[string]*denote.Task)
+	for _, task := range tasks {
+		byName[names[task.FilePath]] = task
+	}
+	projectByName := make(map[string]*denote.Project)
+	for _, p := range projects {
+		projectByName[names[p.FilePath]] = p
+	}
+	if got := byName["status"].Status; got != denote.TaskStatusOpen {
+		t.Errorf("status = %q, want open", got)
+	}
+	if got := byName["priority"].Priority; got != denote.PriorityP1 {
+		t.Errorf("priority = %q, want p1", got)
+	}
+	if got := byName["dates"].DueDate; got != "2026-03-01" {
+		t.Errorf("due_date = %q, want 2026-03-01", got)
+	}
+	if byName["first"].IndexID != 7 || byName["second"].IndexID == 7 || byName["second"].IndexID == 0 {
+		t.Errorf("index_ids = %d and %d, want the older file to keep 7", byName["first"].IndexID, byName["second"].IndexID)
+	}
+	if byName["renamed"] == nil {
+		t.Errorf("renamed file not found under its new name")
+	}
+	if got := projectByName["garden"].Status; got != denote.ProjectStatusActive {
+		t.Errorf("project status = %q, want active", got)
+	}
+	if got := byName["orphan"].ProjectID; got != "999" {
+		t.Errorf("project_id = %q, want it kept without --clear-dangling", got)
+	}
+	shared := projectByName["shared"]
+	if byName["older"].IndexID != 12 || shared.IndexID == 12 || byName["member"].ProjectID != strconv.Itoa(shared.IndexID) {
+		t.Errorf("renumbered project %d, its task's project_id %q; want the task to follow the project",
+			shared.IndexID, byName["member"].ProjectID)
+	}
+	if projectByName["beta"].IndexID != 13 || byName["either"].ProjectID != "13" {
+		t.Errorf("ambiguous project ID renumbered without asking")
+	}
+
+	// Lossy repairs are applied only when asked for
+	if n := after.Fix(nil, true); n != 2 {
+		t.Errorf("Fix(lossy) = %d, want 2", n)
+	}
+	orphan, err := denote.ParseTaskFile(byName["orphan"].FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orphan.ProjectID != "" {
+		t.Errorf("project_id = %q after --clear-dangling, want it cleared", orphan.ProjectID)
+	}
+}
+
+func TestRenameRefusesToOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	path := writeNote(t, dir, "Old title", "task", "title: New title\nindex_id: 1\nstatus: open\n")
+	id := filepath.Base(path)[:26]
+	target := filepath.Join(dir, acore.BuildFilename(id, "New title", "task"))
+	if err := os.WriteFile(target, []byte("in the way"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Check(dir)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	report.Fix(nil, false)
+
+	var rename *Issue
+	for _, issue := range report.Issues {
+		if issue.Check == CheckSlugMismatch && issue.Path == path {
+			rename = issue
+		}
+	}
+	if rename == nil || rename.Fixed || rename.FixError == "" {
+		t.Fatalf("rename onto an existing file = %+v, want a fix error", rename)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "in the way" {
+		t.Errorf("existing file was overwritten")
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	dir := t.TempDir()
+	writeNote(t, dir, "Status", "task", "title: Status\nindex_id: 1\nstatus: OPEN\n")
+
+	report, err := Check(dir)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	report.Fix(nil, false)
+
+	path := filepath.Join(dir, ReportFileName)
+	if err := report.Write(path); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved Report
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("report is not JSON: %v", err)
+	}
+	if saved.Checked == "" || len(saved.Issues) != 1 || !saved.Issues[0].Fixed || saved.Issues[0].Fix != "set status to open" {
+		t.Errorf("saved report = %s", data)
+	}
+}
+
+func TestRenameWaitsForWriters(t *testing.T) {
+	dir := t.TempDir()
+	path := writeNote(t, dir, "Old title", "task", "title: New title\nindex_id: 1\nstatus: open\n")
+
+	report, err := Check(dir)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	// A writer in another process holds the file while doctor runs
+	unlock, err := denote.LockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	held := 200 * time.Millisecond
+	go func() {
+		time.Sleep(held)
+		unlock()
+	}()
+
+	start := time.Now()
+	if n := report.Fix(nil, false); n != 1 {
+		t.Fatalf("Fix() = %d, want 1", n)
+	}
+	if waited := time.Since(start); waited < held {
+		t.Errorf("rename finished after %v, before the writer released the file", waited)
+	}
+}
@@ -0,0 +1,255 @@
+// Package policy decides which queued actions are approved or rejected
+// without a human, from the rules in queue/policy.toml:
+//
+//	default = "review"
+//
+//	[[rule]]
+//	name = "priority-bumps"
+//	action_type = "task_update"
+//	proposed_by = "agent-*"
+//	fields = { priority = "p*" }
+//	allowed_fields = ["target_id", "priority"]
+//	decision = "approve"
+//	rate_limit = "10/day"
+//
+// Rules are tried in order and the first one that matches decides. An
+// action no rule matches gets the default decision, review unless set.
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+// Decisions a rule can make.
+const (
+	Approve = "approve" // execute the action right away
+	Reject  = "reject"  // reject and archive the action
+	Review  = "review"  // leave the action pending for a human
+)
+
+// FileName is the name of the policy file in the queue directory.
+const FileName = "policy.toml"
+
+// Path returns the policy file for a notes directory.
+func Path(dir string) string {
+	return filepath.Join(dir, "queue", FileName)
+}
+
+// Policy is a parsed policy file.
+type Policy struct {
+	Default string `toml:"default"` // decision when no rule matches
+	Rules   []Rule `toml:"rule"`
+}
+
+// Rule matches actions and decides them. Patterns are globs, so
+// "agent-*" matches any proposer starting with "agent-".
+type Rule struct {
+	Name          string            `toml:"name"`
+	ActionType    string            `toml:"action_type"`    // any type if empty
+	ProposedBy    string            `toml:"proposed_by"`    // any proposer if empty
+	Fields        map[string]string `toml:"fields"`         // each field must be set and match
+	AllowedFields []string          `toml:"allowed_fields"` // if set, the action may have no other fields
+	Decision      string            `toml:"decision"`
+	RateLimit     string            `toml:"rate_limit"` // N/hour, N/day, N/week or N/<duration>, per proposer
+
+	limit  int
+	window time.Duration
+}
+
+// Load reads and validates a policy file. It returns nil without an error
+// if the file does not exist, in which case every action needs review.
+func Load(file string) (*Policy, error) {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return nil, nil
+	}
+	var p Policy
+	if _, err := toml.DecodeFile(file, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse queue policy: %w", err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid queue policy %s: %w", file, err)
+	}
+	return &p, nil
+}
+
+// validate checks the decisions, patterns and rate limits, and fills in
+// the default decision.
+func (p *Policy) validate() error {
+	if p.Default == "" {
+		p.Default = Review
+	}
+	if !validDecision(p.Default) {
+		return fmt.Errorf("invalid default decision %q (use approve, reject or review)", p.Default)
+	}
+
+	names := make(map[string]bool)
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.Name == "" {
+			return fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("duplicate rule name %q", r.Name)
+		}
+		names[r.Name] = true
+
+		if !validDecision(r.Decision) {
+			return fmt.Errorf("rule %s: invalid decision %q (use approve, reject or review)", r.Name, r.Decision)
+		}
+		patterns := []string{r.ActionType, r.ProposedBy}
+		for _, v := range r.Fields {
+			patterns = append(patterns, v)
+		}
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rule %s: invalid pattern %q", r.Name, pattern)
+			}
+		}
+		if r.RateLimit != "" {
+			limit, window, err := parseRateLimit(r.RateLimit)
+			if err != nil {
+				return fmt.Errorf("rule %s: %w", r.Name, err)
+			}
+			r.limit, r.window = limit, window
+		}
+	}
+	return nil
+}
+
+func validDecision(d string) bool {
+	return d == Approve || d == Reject || d == Review
+}
+
+// parseRateLimit reads a limit such as "10/day" or "3/30m".
+func parseRateLimit(s string) (int, time.Duration, error) {
+	count, period, ok := strings.Cut(s, "/")
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if !ok || err != nil || n <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate_limit %q (use N/hour, N/day, N/week or N/<duration>)", s)
+	}
+	switch period = strings.TrimSpace(period); period {
+	case "hour":
+		return n, time.Hour, nil
+	case "day":
+		return n, 24 * time.Hour, nil
+	case "week":
+		return n, 7 * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate_limit %q (use N/hour, N/day, N/week or N/<duration>)", s)
+	}
+	return n, d, nil
+}
+
+// NeedsHistory reports whether any rule has a rate limit, for which
+// Evaluate needs the actions already in the queue.
+func (p *Policy) NeedsHistory() bool {
+	for _, r := range p.Rules {
+		if r.limit > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate decides an action. history holds the queued and archived
+// actions, used to count what a rate-limited rule has already decided for
+// the same proposer. A rule over its limit leaves the action for review.
+func (p *Policy) Evaluate(action *denote.Action, history []*denote.Action, now time.Time) denote.ActionPolicy {
+	for _, r := range p.Rules {
+		if !r.matches(action) {
+			continue
+		}
+		if r.limit > 0 && r.Decision != Review && r.recent(action.ProposedBy, history, now) >= r.limit {
+			return denote.ActionPolicy{Rule: r.Name, Decision: Review,
+				Reason: fmt.Sprintf("rate limit %s reached for %s", r.RateLimit, action.ProposedBy)}
+		}
+		return denote.ActionPolicy{Rule: r.Name, Decision: r.Decision}
+	}
+	return denote.ActionPolicy{Decision: p.Default, Reason: "no rule matched"}
+}
+
+// matches reports whether the rule applies to the action.
+func (r *Rule) matches(action *denote.Action) bool {
+	if !glob(r.ActionType, action.ActionType) || !glob(r.ProposedBy, action.ProposedBy) {
+		return false
+	}
+	for key, pattern := range r.Fields {
+		value, ok := action.Fields[key]
+		if !ok || !glob(pattern, value) {
+			return false
+		}
+	}
+	if r.AllowedFields != nil {
+		for key := range action.Fields {
+			if !contains(r.AllowedFields, key) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// recent counts the actions from proposer that the rule decided within
+// its rate limit window.
+func (r *Rule) recent(proposer string, history []*denote.Action, now time.Time) int {
+	n := 0
+	for _, a := range history {
+		if a.ProposedBy != proposer || a.Policy == nil || a.Policy.Rule != r.Name || a.Policy.Decision != r.Decision {
+			continue
+		}
+		if at, err := denote.ParseTimestamp(a.ProposedAt); err == nil && now.Sub(at) < r.window {
+			n++
+		}
+	}
+	return n
+}
+
+// Describe returns a sentence saying what the policy decided, for the
+// action's body and the command output.
+func Describe(d denote.ActionPolicy) string {
+	var s string
+	switch d.Decision {
+	case Approve:
+		s = "Auto-approved"
+	case Reject:
+		s = "Auto-rejected"
+	default:
+		s = "Needs review"
+	}
+	if d.Rule != "" {
+		s += fmt.Sprintf(" by policy rule %q", d.Rule)
+	}
+	if d.Reason != "" {
+		s += " (" + d.Reason + ")"
+	}
+	return s
+}
+
+// glob matches value against pattern; an empty pattern matches anything.
+func glob(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+func load(t *testing.T, data string) (*Policy, error) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(file)
+}
+
+func newAction(actionType, proposedBy string, fields map[string]string) *denote.Action {
+	a := &denote.Action{}
+	a.ActionType = actionType
+	a.ProposedBy = proposedBy
+	a.Fields = fields
+	return a
+}
+
+func TestEvaluate(t *testing.T) {
+	p, err := load(t, `
+[[rule]]
+name = "priority-bumps"
+action_type = "task_update"
+proposed_by = "agent-*"
+fields = { priority = "p*" }
+allowed_fields = ["target_id", "priority"]
+decision = "approve"
+
+[[rule]]
+name = "no-people"
+action_type = "people_*"
+decision = "reject"
+`)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		action *denote.Action
+		rule   string
+		want   string
+	}{
+		{"priority bump", newAction("task_update", "agent-1", map[string]string{"target_id": "4", "priority": "p1"}), "priority-bumps", Approve},
+		{"other field too", newAction("task_update", "agent-1", map[string]string{"target_id": "4", "priority": "p1", "status": "done"}), "", Review},
+		{"missing field", newAction("task_update", "agent-1", map[string]string{"target_id": "4"}), "", Review},
+		{"human proposer", newAction("task_update", "cli", map[string]string{"target_id": "4", "priority": "p1"}), "", Review},
+		{"glob type", newAction("people_log", "cli", map[string]string{"target_id": "2"}), "no-people", Reject},
+	}
+	for _, tt := range tests {
+		got := p.Evaluate(tt.action, nil, time.Now())
+		if got.Rule != tt.rule || got.Decision != tt.want {
+			t.Errorf("%s: got %+v, want rule %q decision %s", tt.name, got, tt.rule, tt.want)
+		}
+	}
+}
+
+func TestEvaluateRateLimit(t *testing.T) {
+	p, err := load(t, `
+default = "reject"
+
+[[rule]]
+name = "bumps"
+action_type = "task_update"
+decision = "approve"
+rate_limit = "2/day"
+`)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !p.NeedsHistory() {
+		t.Error("NeedsHistory = false with a rate limit")
+	}
+
+	now := time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC)
+	decided := func(proposer, at string) *denote.Action {
+		a := newAction("task_update", proposer, nil)
+		a.ProposedAt = at
+		a.Policy = &denote.ActionPolicy{Rule: "bumps", Decision: Approve}
+		return a
+	}
+	history := []*denote.Action{
+		decided("agent-1", "2026-03-11T09:00:00Z"),
+		decided("agent-1", "2026-03-10T08:00:00Z"), // outside the window
+		decided("agent-2", "2026-03-11T10:00:00Z"),
+	}
+
+	action := newAction("task_update", "agent-1", nil)
+	if got := p.Evaluate(action, history, now); got.Decision != Approve {
+		t.Errorf("first of two: %+v, want approve", got)
+	}
+	history = append(history, decided("agent-1", "2026-03-11T11:00:00Z"))
+	got := p.Evaluate(action, history, now)
+	if got.Decision != Review || got.Rule != "bumps" || !strings.Contains(got.Reason, "rate limit") {
+		t.Errorf("over the limit: %+v, want review", got)
+	}
+	if got := p.Evaluate(newAction("idea_create", "agent-1", nil), history, now); got.Decision != Reject || got.Rule != "" {
+		t.Errorf("default: %+v, want reject", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	if p, err := Load(filepath.Join(t.TempDir(), FileName)); p != nil || err != nil {
+		t.Errorf("missing file: %v, %v; want nil, nil", p, err)
+	}
+
+	for data, want := range map[string]string{
+		"[[rule]]\ndecision = \"approve\"\n":                                                              "has no name",
+		"[[rule]]\nname = \"a\"\ndecision = \"yes\"\n":                                                    "invalid decision",
+		"[[rule]]\nname = \"a\"\ndecision = \"approve\"\n[[rule]]\nname = \"a\"\ndecision = \"reject\"\n": "duplicate rule name",
+		"[[rule]]\nname = \"a\"\ndecision = \"approve\"\nrate_limit = \"often\"\n":                        "invalid rate_limit",
+		"[[rule]]\nname = \"a\"\naction_type = \"[\"\ndecision = \"approve\"\n":                           "invalid pattern",
+		"default = \"maybe\"\n": "invalid default decision",
+	} {
+		if _, err := load(t, data); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Load(%q) error = %v, want %q", data, err, want)
+		}
+	}
+}
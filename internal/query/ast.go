@@ -2,46 +2,106 @@ package query
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mph-llm-experiments/atask/internal/config"
 	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/search"
 )
 
 // Node represents a node in the abstract syntax tree
 type Node interface {
-	Evaluate(task *denote.Task, cfg *config.Config) bool
+	Evaluate(r Record, cfg *config.Config) bool
 	String() string
 }
 
-// ComparisonNode represents a field comparison (e.g., status:open, estimate>5)
+// ComparisonNode represents a field comparison (e.g., status:open, estimate>5,
+// title~^review, priority:(p1,p2))
 type ComparisonNode struct {
 	Field    string
-	Operator string // ":", ">", "<", "=", "!="
+	Operator string // ":", ">", "<", ">=", "<=", "=", "!=", "~", "!~"
 	Value    string
+	Values   []string // set for lists such as (p1,p2)
+	Workdays bool     // due:workdays<3: Value counts workdays from today
+
+	items    []*ComparisonNode // one ":" comparison per list value
+	join     *ComparisonNode   // the comparison after the dot in project.status
+	pattern  *regexp.Regexp    // compiled regex or glob, if any
+	compiled bool
+	fieldPos int // positions in the query, for errors
+	valuePos int
 }
 
 func (n *ComparisonNode) String() string {
+	if n.Values != nil {
+		return fmt.Sprintf("%s%s(%s)", n.Field, n.Operator, strings.Join(n.Values, ","))
+	}
+	if n.Workdays {
+		return fmt.Sprintf("%s:workdays%s%s", n.Field, n.Operator, n.Value)
+	}
 	return fmt.Sprintf("%s%s%s", n.Field, n.Operator, n.Value)
 }
 
-func (n *ComparisonNode) Evaluate(task *denote.Task, cfg *config.Config) bool {
+func (n *ComparisonNode) Evaluate(r Record, cfg *config.Config) bool {
+	if n.Values != nil {
+		// A list matches if any of its values does
+		matched := false
+		for _, item := range n.listItems() {
+			if item.Evaluate(r, cfg) {
+				matched = true
+				break
+			}
+		}
+		if n.Operator == "!=" {
+			return !matched
+		}
+		return matched
+	}
+
+	switch rec := r.(type) {
+	case *denote.Task:
+		return n.evaluateTask(rec, nil, cfg)
+	case *TaskRecord:
+		return n.evaluateTask(rec.Task, rec.Project, cfg)
+	case *denote.Project:
+		return n.evaluateProject(rec, cfg)
+	case *denote.Action:
+		return n.evaluateAction(rec)
+	default:
+		return false
+	}
+}
+
+// evaluateTask compares a task field. project is the task's project, used
+// for dotted fields like project.status; nil means it has none.
+func (n *ComparisonNode) evaluateTask(task *denote.Task, project *denote.Project, cfg *config.Config) bool {
 	field := strings.ToLower(n.Field)
 	value := strings.ToLower(n.Value)
 
+	if strings.HasPrefix(field, "project.") {
+		if project == nil {
+			// Compare against an empty project so project.status:empty and
+			// negations behave as for any missing value
+			project = &denote.Project{}
+		}
+		return n.joinNode().evaluateProject(project, cfg)
+	}
+
 	switch field {
 	case "status":
-		return compareString(strings.ToLower(task.TaskMetadata.Status), n.Operator, value)
+		return n.matchString(strings.ToLower(task.TaskMetadata.Status), value)
 
 	case "priority":
-		return compareString(strings.ToLower(task.TaskMetadata.Priority), n.Operator, value)
+		return n.matchString(strings.ToLower(task.TaskMetadata.Priority), value)
 
 	case "area":
-		return compareString(strings.ToLower(task.TaskMetadata.Area), n.Operator, value)
+		return n.matchString(strings.ToLower(task.TaskMetadata.Area), value)
 
 	case "assignee":
-		return compareString(strings.ToLower(task.TaskMetadata.Assignee), n.Operator, value)
+		return n.matchString(strings.ToLower(task.TaskMetadata.Assignee), value)
 
 	case "project_id":
 		// Special values
@@ -53,7 +113,7 @@ func (n *ComparisonNode) Evaluate(task *denote.Task, cfg *config.Config) bool {
 			isSet := task.TaskMetadata.ProjectID != ""
 			return n.Operator == ":" && isSet
 		}
-		return compareString(task.TaskMetadata.ProjectID, n.Operator, value)
+		return n.matchString(task.TaskMetadata.ProjectID, value)
 
 	case "estimate":
 		return compareInt(task.TaskMetadata.Estimate, n.Operator, value)
@@ -62,62 +122,41 @@ func (n *ComparisonNode) Evaluate(task *denote.Task, cfg *config.Config) bool {
 		return compareInt(task.IndexID, n.Operator, value)
 
 	case "due", "due_date":
-		// Special values
-		switch value {
-		case "empty":
-			isEmpty := task.TaskMetadata.DueDate == ""
-			return n.Operator == ":" && isEmpty
-		case "set":
-			isSet := task.TaskMetadata.DueDate != ""
-			return n.Operator == ":" && isSet
-		case "overdue":
-			isOverdue := denote.IsOverdue(task.TaskMetadata.DueDate)
-			return n.Operator == ":" && isOverdue
-		case "today":
-			daysUntil := denote.DaysUntilDue(task.TaskMetadata.DueDate)
-			isToday := daysUntil == 0
-			return n.Operator == ":" && isToday
-		case "week":
-			isThisWeek := denote.IsDueThisWeek(task.TaskMetadata.DueDate)
-			return n.Operator == ":" && isThisWeek
-		case "soon":
-			isSoon := denote.IsDueSoon(task.TaskMetadata.DueDate, cfg.SoonHorizon)
-			return n.Operator == ":" && isSoon
-		default:
-			// Compare as date string (YYYY-MM-DD)
-			return compareString(task.TaskMetadata.DueDate, n.Operator, value)
-		}
+		return n.compareDue(task.TaskMetadata.DueDate, value, cfg)
 
 	case "start", "start_date":
-		if value == "empty" {
-			isEmpty := task.TaskMetadata.StartDate == ""
-			return n.Operator == ":" && isEmpty
-		}
-		if value == "set" {
-			isSet := task.TaskMetadata.StartDate != ""
-			return n.Operator == ":" && isSet
-		}
-		return compareString(task.TaskMetadata.StartDate, n.Operator, value)
+		return compareDate(task.TaskMetadata.StartDate, n.Operator, value)
 
 	case "today", "today_date":
 		// Special value: "tagged" means tagged for today
 		if value == "tagged" || value == "true" {
 			return n.Operator == ":" && task.IsTaggedForToday()
 		}
-		// Otherwise compare as date string
-		return compareString(task.TaskMetadata.TodayDate, n.Operator, value)
+		return compareDate(task.TaskMetadata.TodayDate, n.Operator, value)
 
-	case "title":
-		return compareString(strings.ToLower(task.Title), n.Operator, value)
+	case "completed", "completed_at":
+		return compareDate(task.TaskMetadata.CompletedAt, n.Operator, value)
 
-	case "tag", "tags":
-		// Check if any tag matches
-		for _, tag := range task.Tags {
-			if compareString(strings.ToLower(tag), n.Operator, value) {
-				return true
+	case "created":
+		return compareDate(task.Created, n.Operator, value)
+
+	case "modified":
+		return compareDate(modifiedOf(task.Modified, task.ModTime), n.Operator, value)
+
+	case "status_history", "history":
+		// Matches if the task ever entered the given status
+		for _, change := range task.TaskMetadata.StatusHistory {
+			if n.matches(strings.ToLower(change.Status), value) {
+				return !n.negated()
 			}
 		}
-		return false
+		return n.negated()
+
+	case "title":
+		return n.matchString(strings.ToLower(task.Title), value)
+
+	case "tag", "tags":
+		return n.matchAny(task.Tags, value)
 
 	case "recur":
 		if value == "empty" {
@@ -128,16 +167,10 @@ func (n *ComparisonNode) Evaluate(task *denote.Task, cfg *config.Config) bool {
 			isSet := task.TaskMetadata.Recur != ""
 			return n.Operator == ":" && isSet
 		}
-		return compareString(strings.ToLower(task.TaskMetadata.Recur), n.Operator, value)
+		return n.matchString(strings.ToLower(task.TaskMetadata.Recur), value)
 
 	case "content", "body", "text":
-		// Search in file content (case-insensitive substring match)
-		if n.Operator == ":" || n.Operator == "=" {
-			return strings.Contains(strings.ToLower(task.Content), value)
-		} else if n.Operator == "!=" {
-			return !strings.Contains(strings.ToLower(task.Content), value)
-		}
-		return false
+		return n.matchContent(task.Content, value)
 
 	default:
 		// Unknown field always returns false
@@ -159,34 +192,207 @@ func (n *BooleanNode) String() string {
 	return fmt.Sprintf("(%s %s %s)", n.Left, n.Op, n.Right)
 }
 
-func (n *BooleanNode) Evaluate(task *denote.Task, cfg *config.Config) bool {
+func (n *BooleanNode) Evaluate(r Record, cfg *config.Config) bool {
 	switch n.Op {
 	case "AND":
-		return n.Left.Evaluate(task, cfg) && n.Right.Evaluate(task, cfg)
+		return n.Left.Evaluate(r, cfg) && n.Right.Evaluate(r, cfg)
 	case "OR":
-		return n.Left.Evaluate(task, cfg) || n.Right.Evaluate(task, cfg)
+		return n.Left.Evaluate(r, cfg) || n.Right.Evaluate(r, cfg)
 	case "NOT":
-		return !n.Left.Evaluate(task, cfg)
+		return !n.Left.Evaluate(r, cfg)
 	default:
 		return false
 	}
 }
 
+// RefNode is a reference to a saved query, written @name
+type RefNode struct {
+	Name   string
+	Filter Node // the saved query's filter; nil matches everything
+}
+
+func (n *RefNode) String() string {
+	return "@" + n.Name
+}
+
+func (n *RefNode) Evaluate(r Record, cfg *config.Config) bool {
+	return n.Filter == nil || n.Filter.Evaluate(r, cfg)
+}
+
 // Helper functions for comparison
 
-func compareString(actual, operator, expected string) bool {
-	switch operator {
-	case ":":
-		return actual == expected
-	case "=":
-		return actual == expected
-	case "!=":
-		return actual != expected
+// matchString compares a string field: by regex for ~ and !~, by glob if the
+// value contains * or ?, and otherwise by equality.
+func (n *ComparisonNode) matchString(actual, expected string) bool {
+	switch n.Operator {
+	case ":", "=", "~":
+		return n.matches(actual, expected)
+	case "!=", "!~":
+		return !n.matches(actual, expected)
 	default:
 		return false
 	}
 }
 
+// compareDue compares a due date, including the special values overdue,
+// week and soon, and the number of workdays until it.
+func (n *ComparisonNode) compareDue(due, value string, cfg *config.Config) bool {
+	if n.Workdays {
+		dueDate, err := time.ParseInLocation("2006-01-02", due, time.Local)
+		if err != nil {
+			// No due date is never a number of workdays away
+			return n.Operator == "!="
+		}
+		return compareInt(cfg.WorkCalendar().WorkdaysUntil(time.Now(), dueDate), n.Operator, value)
+	}
+	switch value {
+	case "overdue":
+		isOverdue := denote.IsOverdue(due)
+		return n.Operator == ":" && isOverdue
+	case "week":
+		isThisWeek := denote.IsDueThisWeek(due)
+		return n.Operator == ":" && isThisWeek
+	case "soon":
+		isSoon := denote.IsDueSoon(due, cfg.SoonHorizon)
+		return n.Operator == ":" && isSoon
+	}
+	return compareDate(due, n.Operator, value)
+}
+
+// matchAny matches a list field such as tags: true if any element matches,
+// or for negated operators, if none does.
+func (n *ComparisonNode) matchAny(list []string, expected string) bool {
+	for _, v := range list {
+		if n.matches(strings.ToLower(v), expected) {
+			return !n.negated()
+		}
+	}
+	return n.negated()
+}
+
+// matchContent searches file content: for every word of the value, compared
+// as search terms so that content:"token refresh" matches "refreshing the
+// tokens", or for a regex or glob matching anywhere.
+func (n *ComparisonNode) matchContent(content, expected string) bool {
+	var found bool
+	if re := n.compiledPattern(); re != nil {
+		found = re.MatchString(strings.ToLower(content))
+	} else {
+		found = search.Contains(content, expected)
+	}
+	switch n.Operator {
+	case ":", "=", "~":
+		return found
+	case "!=", "!~":
+		return !found
+	}
+	return false
+}
+
+// matches reports whether actual matches the node's value, ignoring
+// negation.
+func (n *ComparisonNode) matches(actual, expected string) bool {
+	if re := n.compiledPattern(); re != nil {
+		return re.MatchString(actual)
+	}
+	return actual == expected
+}
+
+func (n *ComparisonNode) negated() bool {
+	return n.Operator == "!=" || n.Operator == "!~"
+}
+
+// compiledPattern returns the node's regex or glob, compiling it on first
+// use. It returns nil for plain values and invalid patterns, which the
+// parser rejects.
+func (n *ComparisonNode) compiledPattern() *regexp.Regexp {
+	if !n.compiled {
+		n.pattern, _ = n.compilePattern()
+		n.compiled = true
+	}
+	return n.pattern
+}
+
+func (n *ComparisonNode) compilePattern() (*regexp.Regexp, error) {
+	if n.Operator == "~" || n.Operator == "!~" {
+		return regexp.Compile("(?i)" + n.Value)
+	}
+	if !isGlob(n.Value) {
+		return nil, nil
+	}
+	// Content is searched, so its globs match anywhere like plain values do
+	field := strings.ToLower(n.Field)
+	anchored := field != "content" && field != "body" && field != "text"
+	return regexp.Compile(globToRegexp(strings.ToLower(n.Value), anchored))
+}
+
+// joinNode returns the comparison after the first dot of a dotted field,
+// such as status:active for project.status:active.
+func (n *ComparisonNode) joinNode() *ComparisonNode {
+	if n.join == nil {
+		_, rest, _ := strings.Cut(n.Field, ".")
+		n.join = &ComparisonNode{Field: rest, Operator: n.Operator, Value: n.Value, Workdays: n.Workdays,
+			fieldPos: n.fieldPos + len(n.Field) - len(rest), valuePos: n.valuePos}
+	}
+	return n.join
+}
+
+// listItems returns one ":" comparison per list value.
+func (n *ComparisonNode) listItems() []*ComparisonNode {
+	if n.items == nil {
+		for _, v := range n.Values {
+			n.items = append(n.items, &ComparisonNode{Field: n.Field, Operator: ":", Value: v,
+				fieldPos: n.fieldPos, valuePos: n.valuePos})
+		}
+	}
+	return n.items
+}
+
+// isGlob reports whether value contains an unescaped * or ?.
+func isGlob(value string) bool {
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '*', '?':
+			return true
+		}
+	}
+	return false
+}
+
+// globToRegexp translates a glob where * matches any run of characters and
+// ? matches one character into a case-insensitive regular expression. A
+// backslash makes the next character literal.
+func globToRegexp(glob string, anchored bool) string {
+	var b strings.Builder
+	b.WriteString("(?is)")
+	if anchored {
+		b.WriteString("^")
+	}
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			} else {
+				b.WriteString(`\\`)
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if anchored {
+		b.WriteString("$")
+	}
+	return b.String()
+}
+
 func compareInt(actual int, operator, expectedStr string) bool {
 	expected, err := strconv.Atoi(expectedStr)
 	if err != nil {
@@ -200,6 +406,10 @@ func compareInt(actual int, operator, expectedStr string) bool {
 		return actual > expected
 	case "<":
 		return actual < expected
+	case ">=":
+		return actual >= expected
+	case "<=":
+		return actual <= expected
 	case "!=":
 		return actual != expected
 	default:
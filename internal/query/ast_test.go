@@ -0,0 +1,317 @@
+package query
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+func TestCompareTimestampPeriods(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 3, 11, 15, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		actual   string
+		operator string
+		value    string
+		want     bool
+	}{
+		{"2026-03-09T08:00:00", ":", "this-week", true},
+		{"2026-03-08T23:00:00", ":", "this-week", false},
+		{"2026-03-08T23:00:00", ":", "last-week", true},
+		{"2026-03-02T00:00:00", ":", "last-week", true},
+		{"2026-03-01T12:00:00", ":", "last-week", false},
+		{"2026-03-11T09:00:00", ":", "today", true},
+		{"2026-02-15T09:00:00", ":", "last-month", true},
+		{"2026-01-02T10:00:00", ">", "2026-01-01", true},
+		{"2026-01-01T10:00:00", ">", "2026-01-01", false},
+		{"2026-01-01T10:00:00", ":", "2026-01-01", true},
+		{"2025-12-31T10:00:00", "<", "2026-01-01", true},
+		{"2026-01-01T10:00:00", "!=", "2026-01-01", false},
+		{"", ":", "this-week", false},
+		{"2026-03-11T09:00:00", ":", "someday", false},
+	}
+
+	for _, tt := range tests {
+		if got := compareTimestamp(tt.actual, tt.operator, tt.value, now); got != tt.want {
+			t.Errorf("compareTimestamp(%q, %q, %q) = %v, want %v", tt.actual, tt.operator, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCompletedQuery(t *testing.T) {
+	task := &denote.Task{}
+	task.SetStatus(denote.TaskStatusDone)
+
+	cfg := &config.Config{}
+	for query, want := range map[string]bool{
+		"completed:today":                 true,
+		"completed:set":                   true,
+		"completed>2000-01-01":            true,
+		"completed<2000-01-01":            false,
+		"history:done":                    true,
+		"history:paused":                  false,
+		"NOT completed:empty":             true,
+		"status:done AND completed:today": true,
+	} {
+		node, err := Parse(query)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", query, err)
+		}
+		if got := node.Evaluate(task, cfg); got != want {
+			t.Errorf("%q = %v, want %v", query, got, want)
+		}
+	}
+
+	task.SetStatus(denote.TaskStatusOpen)
+	if task.CompletedAt != "" {
+		t.Errorf("reopening kept completed_at = %q", task.CompletedAt)
+	}
+	if len(task.StatusHistory) != 2 {
+		t.Errorf("status_history has %d entries, want 2", len(task.StatusHistory))
+	}
+}
+
+func TestContentQuery(t *testing.T) {
+	task := &denote.Task{}
+	task.Content = "Refreshing the OAuth tokens fails after an hour."
+
+	cfg := &config.Config{}
+	for query, want := range map[string]bool{
+		`content:oauth`:              true,
+		`content:"token refresh"`:    true,
+		`content:"refresh hourly"`:   false,
+		`content:auth`:               false,
+		`content:*auth*`:             true,
+		`content~"fails? after"`:     true,
+		`content!="token refresh"`:   false,
+		`NOT content:"signing keys"`: true,
+	} {
+		node, err := Parse(query)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", query, err)
+		}
+		if got := node.Evaluate(task, cfg); got != want {
+			t.Errorf("%q = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestCompareTimestampRelative(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 1, 14, 15, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		actual   string
+		operator string
+		value    string
+		want     bool
+	}{
+		{"2026-01-20", "<", "+7d", true},
+		{"2026-01-21", "<", "+7d", false},
+		{"2026-01-21", "<=", "+7d", true},
+		{"2026-01-22", "<=", "+7d", false},
+		{"2026-01-14", ">", "today", false},
+		{"2026-01-14", ">=", "today", true},
+		{"2026-01-15", ">", "today", true},
+		{"2025-12-16T09:00:00", ">", "-30d", true},
+		{"2025-12-15T09:00:00", ">", "-30d", false},
+		{"2025-12-15T09:00:00", ">=", "-30d", true},
+		{"2026-01-31", "<=", "eom", true},
+		{"2026-02-01", "<=", "eom", false},
+		{"2026-01-31", ":", "eom", true},
+		{"2026-01-18", ":", "eow", true},
+		{"2026-02-14", ":", "+1m", true},
+		{"2026-01-15", ":", "tomorrow", true},
+		{"2026-01-19", ":", "next-week", true},
+		{"", "<", "+7d", false},
+		{"", "!=", "today", true},
+	}
+
+	for _, tt := range tests {
+		if got := compareTimestamp(tt.actual, tt.operator, tt.value, now); got != tt.want {
+			t.Errorf("compareTimestamp(%q, %q, %q) = %v, want %v", tt.actual, tt.operator, tt.value, got, tt.want)
+		}
+	}
+
+	// Month offsets clamp to the end of shorter months
+	jan31 := time.Date(2026, 1, 31, 12, 0, 0, 0, time.Local)
+	if start, _, _ := periodRange("+1m", jan31); start.Format("2006-01-02") != "2026-02-28" {
+		t.Errorf("+1m from Jan 31 = %s, want 2026-02-28", start.Format("2006-01-02"))
+	}
+}
+
+func TestParseDateOperators(t *testing.T) {
+	now := time.Now()
+	task := &denote.Task{}
+	task.DueDate = now.AddDate(0, 0, 3).Format("2006-01-02")
+	task.StartDate = now.AddDate(0, 0, -1).Format("2006-01-02")
+	task.Created = now.AddDate(0, 0, -10).Format(time.RFC3339)
+	task.Modified = now.Format(time.RFC3339)
+
+	cfg := &config.Config{}
+	for query, want := range map[string]bool{
+		"due<+7d":                 true,
+		"due<=+3d":                true,
+		"due<+3d":                 false,
+		"due>=today":              true,
+		"start<today":             true,
+		"start>today":             false,
+		"created>-30d":            true,
+		"created>-7d":             false,
+		"modified:today":          true,
+		"due<+7d AND estimate<=0": true,
+		"today:empty":             true,
+	} {
+		node, err := Parse(query)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", query, err)
+		}
+		if got := node.Evaluate(task, cfg); got != want {
+			t.Errorf("%q = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestDueWorkdaysQuery(t *testing.T) {
+	// Every day is a workday except tomorrow, so results do not depend on
+	// the weekday the test runs
+	today := time.Now()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	data := "notes_directory = \"" + dir + "\"\n\n[calendar]\n" +
+		"workdays = [\"mon\", \"tue\", \"wed\", \"thu\", \"fri\", \"sat\", \"sun\"]\n" +
+		"holidays = [\"" + today.AddDate(0, 0, 1).Format("2006-01-02") + "\"]\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	dueIn := func(days int) *denote.Task {
+		task := &denote.Task{}
+		task.DueDate = today.AddDate(0, 0, days).Format("2006-01-02")
+		return task
+	}
+	tests := []struct {
+		query string
+		task  *denote.Task
+		want  bool
+	}{
+		{"due:workdays<3", dueIn(3), true}, // 2 workdays away, skipping tomorrow
+		{"due:workdays=2", dueIn(3), true},
+		{"due:workdays>2", dueIn(3), false},
+		{"due:workdays<3", dueIn(4), false},
+		{"due:workdays<3", dueIn(-5), true}, // overdue counts as negative
+		{"due:workdays<=0", dueIn(0), true},
+		{"due:workdays<3", &denote.Task{}, false},
+		{"due:workdays!=1", &denote.Task{}, true},
+	}
+	for _, tt := range tests {
+		node, err := Parse(tt.query)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.query, err)
+		}
+		if got := node.Evaluate(tt.task, cfg); got != tt.want {
+			t.Errorf("%q on due %q = %v, want %v", tt.query, tt.task.DueDate, got, tt.want)
+		}
+	}
+
+	node, err := Parse("due:workdays<3 AND status:open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := node.String(), "(due:workdays<3 AND status:open)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	for _, query := range []string{"area:workdays<3", "due:workdays<soon", "due:workdays<"} {
+		if _, err := Parse(query); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", query)
+		}
+	}
+}
+
+func TestParseRejectsUnsupportedComparisons(t *testing.T) {
+	for _, query := range []string{
+		"status>open",
+		"area<=work",
+		"title>=foo",
+		"estimate>big",
+		"due>overdue",
+		"due<empty",
+		"due<someday",
+		"created>=+7x",
+		"project_id!=empty",
+	} {
+		if _, err := Parse(query); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", query)
+		}
+	}
+}
+
+func TestEvaluateProjectsActionsAndJoins(t *testing.T) {
+	cfg := &config.Config{}
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+
+	project := &denote.Project{}
+	project.IndexID = 3
+	project.ID = "01PROJECT"
+	project.Title = "Launch"
+	project.ProjectMetadata.Status = denote.ProjectStatusActive
+	project.ProjectMetadata.Area = "work"
+	project.ProjectMetadata.DueDate = yesterday
+
+	action := &denote.Action{}
+	action.Title = "Create follow-up"
+	action.ActionType = denote.ActionTypeTaskCreate
+	action.ActionMetadata.Status = denote.ActionPending
+	action.ProposedBy = "agent-mail"
+	action.Fields = map[string]string{"priority": "p1"}
+
+	task := &denote.Task{}
+	task.ProjectID = "01PROJECT"
+	byID := ProjectsByID([]*denote.Project{project})
+	withProject := NewTaskRecord(task, byID)
+	orphan := NewTaskRecord(&denote.Task{}, byID)
+
+	tests := []struct {
+		query  string
+		record Record
+		want   bool
+	}{
+		{"status:active AND due:overdue", project, true},
+		{"area:home", project, false},
+		{"title~^laun", project, true},
+		{"status:pending AND action_type:task_create", action, true},
+		{"proposed_by:agent-*", action, true},
+		{"fields.priority:p1", action, true},
+		{"fields.due:empty OR fields.due:x", action, false},
+		{"project.status:active", withProject, true},
+		{"project.area:(home,work)", withProject, true},
+		{"project.due<today", withProject, true},
+		{"project.status:active", orphan, false},
+		{"project.status!=active", orphan, true},
+		{"project.title:launch", task, false}, // no join without a TaskRecord
+	}
+
+	for _, tt := range tests {
+		node, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		if got := node.Evaluate(tt.record, cfg); got != tt.want {
+			t.Errorf("%q on %T = %v, want %v", tt.query, tt.record, got, tt.want)
+		}
+	}
+
+	if _, err := Parse("project.status>active"); err == nil {
+		t.Errorf("Parse accepted project.status>active")
+	}
+}
@@ -0,0 +1,461 @@
+package query
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+// Query is a parsed query expression with optional result clauses:
+//
+//	status:open AND area:work ORDER BY due ASC, priority LIMIT 10 FIELDS index_id,title,due
+type Query struct {
+	Filter  Node      // nil matches every task
+	OrderBy []SortKey // empty keeps the caller's default order
+	Limit   int       // 0 means no limit
+	Fields  []string  // JSON keys to project; empty means whole objects
+	Saved   string    // set when the expression is a single @name reference
+}
+
+// SortKey is one ORDER BY key.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// sortFields maps the fields accepted by ORDER BY to their canonical names.
+var sortFields = map[string]string{
+	"priority":     "priority",
+	"due":          "due",
+	"due_date":     "due",
+	"start":        "start",
+	"start_date":   "start",
+	"today":        "today",
+	"today_date":   "today",
+	"completed":    "completed",
+	"completed_at": "completed",
+	"created":      "created",
+	"modified":     "modified",
+	"title":        "title",
+	"status":       "status",
+	"area":         "area",
+	"assignee":     "assignee",
+	"project_id":   "project_id",
+	"recur":        "recur",
+	"estimate":     "estimate",
+	"index_id":     "index_id",
+	"action_type":  "action_type",
+	"proposed_by":  "proposed_by",
+	"proposed_at":  "proposed_at",
+}
+
+// projectionFields maps the names accepted by FIELDS to the JSON keys of
+// tasks, projects and actions.
+var projectionFields = map[string]string{
+	"id":             "id",
+	"index_id":       "index_id",
+	"title":          "title",
+	"type":           "type",
+	"tags":           "tags",
+	"tag":            "tags",
+	"created":        "created",
+	"modified":       "modified",
+	"status":         "status",
+	"priority":       "priority",
+	"due":            "due_date",
+	"due_date":       "due_date",
+	"start":          "start_date",
+	"start_date":     "start_date",
+	"today":          "today_date",
+	"today_date":     "today_date",
+	"estimate":       "estimate",
+	"project_id":     "project_id",
+	"project_name":   "project_name",
+	"area":           "area",
+	"assignee":       "assignee",
+	"recur":          "recur",
+	"completed":      "completed_at",
+	"completed_at":   "completed_at",
+	"history":        "status_history",
+	"status_history": "status_history",
+	"related_people": "related_people",
+	"related_tasks":  "related_tasks",
+	"related_ideas":  "related_ideas",
+	"planned_for":    "planned_for",
+	"file_path":      "file_path",
+	"task_count":     "task_count",
+	"action_type":    "action_type",
+	"proposed_at":    "proposed_at",
+	"proposed_by":    "proposed_by",
+	"fields":         "fields",
+}
+
+// ParseQuery parses a filter expression followed by optional ORDER BY,
+// LIMIT and FIELDS clauses, in any order. The filter may be omitted.
+func ParseQuery(query string) (*Query, error) {
+	return parseQuery(query, "", nil)
+}
+
+func parseQuery(query string, records RecordType, resolve func(name string) (*Query, error)) (*Query, error) {
+	tokens, err := Tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &Parser{tokens: tokens, pos: 0, resolve: resolve, records: records}
+	q := &Query{}
+
+	if !parser.isAtEnd() && clauseKeyword(parser.current()) == "" {
+		q.Filter, err = parser.parseExpression()
+		if err != nil {
+			return nil, withQuery(err, query)
+		}
+	}
+
+	if err := parser.parseClauses(q); err != nil {
+		return nil, withQuery(err, query)
+	}
+	return q, nil
+}
+
+// clauseKeyword returns ORDER, LIMIT or FIELDS if tok starts a clause. The
+// keywords are only recognized where a field name could appear, so values
+// like title:limit are unaffected.
+func clauseKeyword(tok Token) string {
+	if tok.Type != TokenField {
+		return ""
+	}
+	switch kw := strings.ToUpper(tok.Value); kw {
+	case "ORDER", "LIMIT", "FIELDS":
+		return kw
+	}
+	return ""
+}
+
+// parseClauses reads ORDER BY, LIMIT and FIELDS clauses until EOF.
+func (p *Parser) parseClauses(q *Query) error {
+	seen := make(map[string]bool)
+	for !p.isAtEnd() {
+		tok := p.current()
+		kw := clauseKeyword(tok)
+		if kw == "" {
+			return errorAt(tok.Pos, "expected AND, OR, ORDER BY, LIMIT or FIELDS, got %s", describe(tok))
+		}
+		if seen[kw] {
+			return errorAt(tok.Pos, "duplicate %s", kw)
+		}
+		seen[kw] = true
+		p.advance()
+
+		switch kw {
+		case "ORDER":
+			if !p.check(TokenField) || !strings.EqualFold(p.current().Value, "BY") {
+				return errorAt(p.current().Pos, "expected BY after ORDER, got %s", describe(p.current()))
+			}
+			p.advance()
+			keys, err := parseSortKeys(p.clauseText())
+			if err != nil {
+				err.Pos = tok.Pos
+				return err
+			}
+			q.OrderBy = keys
+
+		case "LIMIT":
+			text := p.clauseText()
+			n, err := strconv.Atoi(text)
+			if err != nil || n < 1 {
+				return errorAt(tok.Pos, "LIMIT expects a positive number, got %q", text)
+			}
+			q.Limit = n
+
+		case "FIELDS":
+			fields, err := parseFields(p.clauseText())
+			if err != nil {
+				err.Pos = tok.Pos
+				return err
+			}
+			q.Fields = fields
+		}
+	}
+	return nil
+}
+
+// clauseText consumes the words of a clause up to the next clause keyword
+// and returns them joined by spaces.
+func (p *Parser) clauseText() string {
+	var words []string
+	for !p.isAtEnd() && clauseKeyword(p.current()) == "" {
+		words = append(words, p.advance().Value)
+	}
+	return strings.Join(words, " ")
+}
+
+func parseSortKeys(text string) ([]SortKey, *ParseError) {
+	var keys []SortKey
+	for _, item := range strings.Split(text, ",") {
+		words := strings.Fields(item)
+		if len(words) == 0 || len(words) > 2 {
+			return nil, errorAt(-1, "invalid ORDER BY key %q", strings.TrimSpace(item))
+		}
+		field, ok := sortFields[strings.ToLower(words[0])]
+		if !ok {
+			err := errorAt(-1, "cannot ORDER BY %s", words[0])
+			err.Suggestions = suggest(words[0], mapKeys(sortFields))
+			return nil, err
+		}
+		key := SortKey{Field: field}
+		if len(words) == 2 {
+			switch strings.ToUpper(words[1]) {
+			case "ASC":
+			case "DESC":
+				key.Desc = true
+			default:
+				return nil, errorAt(-1, "expected ASC or DESC after %s, got %s", words[0], words[1])
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func parseFields(text string) ([]string, *ParseError) {
+	var fields []string
+	for _, item := range strings.Split(text, ",") {
+		name := strings.ToLower(strings.TrimSpace(item))
+		key, ok := projectionFields[name]
+		if !ok {
+			err := errorAt(-1, "unknown field %q in FIELDS", strings.TrimSpace(item))
+			err.Suggestions = suggest(name, mapKeys(projectionFields))
+			return nil, err
+		}
+		fields = append(fields, key)
+	}
+	return fields, nil
+}
+
+// Match reports whether r passes the query's filter.
+func (q *Query) Match(r Record, cfg *config.Config) bool {
+	return q.Filter == nil || q.Filter.Evaluate(r, cfg)
+}
+
+// Sort orders tasks by the ORDER BY keys. Tasks missing a key's value sort
+// after those that have one, whichever the direction.
+func (q *Query) Sort(tasks []denote.Task) {
+	q.SortSlice(tasks, func(i int) Record { return &tasks[i] })
+}
+
+// SortSlice orders any slice of tasks, projects or actions by the ORDER BY
+// keys; record returns the element at index i.
+func (q *Query) SortSlice(slice interface{}, record func(i int) Record) {
+	if len(q.OrderBy) == 0 {
+		return
+	}
+	sort.SliceStable(slice, func(i, j int) bool {
+		a, b := record(i), record(j)
+		for _, key := range q.OrderBy {
+			c := compareForSort(a, b, key)
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+// Apply sorts and truncates tasks according to the query.
+func (q *Query) Apply(tasks []denote.Task) []denote.Task {
+	q.Sort(tasks)
+	if q.Limit > 0 && len(tasks) > q.Limit {
+		tasks = tasks[:q.Limit]
+	}
+	return tasks
+}
+
+// compareForSort returns -1, 0 or 1 comparing a and b on key.
+func compareForSort(a, b Record, key SortKey) int {
+	va, oka := sortValue(a, key.Field)
+	vb, okb := sortValue(b, key.Field)
+	switch {
+	case !oka && !okb:
+		return 0
+	case !oka:
+		return 1
+	case !okb:
+		return -1
+	}
+
+	var c int
+	switch x := va.(type) {
+	case int:
+		y := vb.(int)
+		if x < y {
+			c = -1
+		} else if x > y {
+			c = 1
+		}
+	case time.Time:
+		c = x.Compare(vb.(time.Time))
+	case string:
+		c = strings.Compare(x, vb.(string))
+	}
+	if key.Desc {
+		c = -c
+	}
+	return c
+}
+
+func sortString(s string) (interface{}, bool) {
+	return strings.ToLower(s), s != ""
+}
+
+func sortDate(s string) (interface{}, bool) {
+	d, err := denote.ParseTimestamp(s)
+	return d, err == nil
+}
+
+func sortPriority(p string) (interface{}, bool) {
+	switch p {
+	case denote.PriorityP1:
+		return 1, true
+	case denote.PriorityP2:
+		return 2, true
+	case denote.PriorityP3:
+		return 3, true
+	}
+	return 0, false
+}
+
+// sortValue returns the value of field used for sorting and whether the
+// record has one.
+func sortValue(r Record, field string) (interface{}, bool) {
+	switch rec := r.(type) {
+	case *TaskRecord:
+		return sortValue(rec.Task, field)
+
+	case *denote.Task:
+		switch field {
+		case "priority":
+			return sortPriority(rec.Priority)
+		case "due":
+			return sortDate(rec.DueDate)
+		case "start":
+			return sortDate(rec.StartDate)
+		case "today":
+			return sortDate(rec.TodayDate)
+		case "completed":
+			return sortDate(rec.CompletedAt)
+		case "status":
+			return sortString(rec.Status)
+		case "area":
+			return sortString(rec.Area)
+		case "assignee":
+			return sortString(rec.Assignee)
+		case "project_id":
+			return sortString(rec.ProjectID)
+		case "recur":
+			return sortString(rec.Recur)
+		case "estimate":
+			return rec.Estimate, rec.Estimate != 0
+		}
+		return entitySortValue(rec.Title, rec.IndexID, rec.Created, rec.Modified, rec.ModTime, field)
+
+	case *denote.Project:
+		switch field {
+		case "priority":
+			return sortPriority(rec.ProjectMetadata.Priority)
+		case "due":
+			return sortDate(rec.ProjectMetadata.DueDate)
+		case "start":
+			return sortDate(rec.ProjectMetadata.StartDate)
+		case "status":
+			return sortString(rec.ProjectMetadata.Status)
+		case "area":
+			return sortString(rec.ProjectMetadata.Area)
+		}
+		return entitySortValue(rec.Title, rec.IndexID, rec.Created, rec.Modified, rec.ModTime, field)
+
+	case *denote.Action:
+		switch field {
+		case "status":
+			return sortString(rec.ActionMetadata.Status)
+		case "action_type":
+			return sortString(rec.ActionType)
+		case "proposed_by":
+			return sortString(rec.ProposedBy)
+		case "proposed_at":
+			return sortDate(rec.ProposedAt)
+		}
+		return entitySortValue(rec.Title, rec.IndexID, rec.Created, rec.Modified, rec.ModTime, field)
+	}
+	return nil, false
+}
+
+// entitySortValue handles the fields tasks, projects and actions share.
+func entitySortValue(title string, indexID int, created, modified string, modTime time.Time, field string) (interface{}, bool) {
+	switch field {
+	case "title":
+		return sortString(title)
+	case "index_id":
+		return indexID, true
+	case "created":
+		return sortDate(created)
+	case "modified":
+		return sortDate(modifiedOf(modified, modTime))
+	}
+	return nil, false
+}
+
+// Row is a projected object whose keys marshal in FIELDS order.
+type Row struct {
+	Keys   []string
+	Values map[string]interface{}
+}
+
+// MarshalJSON encodes the row as an object with keys in order.
+func (r Row) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range r.Keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		value, err := json.Marshal(r.Values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
+
+// Project returns the FIELDS keys of v, which must marshal to a JSON
+// object. Keys missing from v (omitted because empty) are null.
+func (q *Query) Project(v interface{}) (Row, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return Row{}, err
+	}
+	var all map[string]interface{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return Row{}, err
+	}
+
+	row := Row{Values: make(map[string]interface{}, len(q.Fields))}
+	for _, f := range q.Fields {
+		if _, dup := row.Values[f]; dup {
+			continue
+		}
+		row.Keys = append(row.Keys, f)
+		row.Values[f] = all[f]
+	}
+	return row, nil
+}
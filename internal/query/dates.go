@@ -0,0 +1,166 @@
+package query
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+// compareDate compares a date or timestamp field. "empty" and "set" test
+// for presence; anything else is resolved with periodRange.
+func compareDate(actual, operator, expected string) bool {
+	switch expected {
+	case "empty":
+		return operator == ":" && actual == ""
+	case "set":
+		return operator == ":" && actual != ""
+	}
+	return compareTimestamp(actual, operator, expected, time.Now())
+}
+
+// compareTimestamp compares a date or timestamp by calendar day against a
+// value accepted by periodRange. ":" and "=" mean within the period, "<"
+// and ">" mean before its start or after its end, and "<=" and ">=" include
+// the period itself. A missing date only matches "!=".
+func compareTimestamp(actual, operator, expected string, now time.Time) bool {
+	if actual == "" {
+		return operator == "!="
+	}
+	t, err := denote.ParseTimestamp(actual)
+	if err != nil {
+		return false
+	}
+
+	start, end, ok := periodRange(expected, now)
+	if !ok {
+		return false
+	}
+
+	switch operator {
+	case ":", "=":
+		return !t.Before(start) && t.Before(end)
+	case "!=":
+		return t.Before(start) || !t.Before(end)
+	case ">":
+		return !t.Before(end)
+	case ">=":
+		return !t.Before(start)
+	case "<":
+		return t.Before(start)
+	case "<=":
+		return t.Before(end)
+	default:
+		return false
+	}
+}
+
+// periodNames are the named periods periodRange accepts.
+var periodNames = []string{
+	"today", "yesterday", "tomorrow",
+	"this-week", "last-week", "next-week",
+	"this-month", "last-month", "next-month",
+	"sow", "eow", "som", "eom", "soy", "eoy",
+}
+
+// periodRange returns the half-open interval [start, end) covered by a date
+// or named period. Accepted values are YYYY-MM-DD; today, yesterday and
+// tomorrow; last-, this- and next-week and -month; sow/eow, som/eom and
+// soy/eoy for the first or last day of the current week, month or year; and
+// offsets from today such as +7d, -30d, +2w, +1m or -1y. Weeks start on
+// Monday.
+func periodRange(value string, now time.Time) (start, end time.Time, ok bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	// Days since Monday
+	weekday := (int(today.Weekday()) + 6) % 7
+	thisWeek := today.AddDate(0, 0, -weekday)
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	thisYear := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+
+	day := func(d time.Time) (time.Time, time.Time, bool) {
+		return d, d.AddDate(0, 0, 1), true
+	}
+
+	switch value {
+	case "today":
+		return day(today)
+	case "yesterday":
+		return day(today.AddDate(0, 0, -1))
+	case "tomorrow":
+		return day(today.AddDate(0, 0, 1))
+	case "this-week":
+		return thisWeek, thisWeek.AddDate(0, 0, 7), true
+	case "last-week":
+		return thisWeek.AddDate(0, 0, -7), thisWeek, true
+	case "next-week":
+		return thisWeek.AddDate(0, 0, 7), thisWeek.AddDate(0, 0, 14), true
+	case "this-month":
+		return thisMonth, thisMonth.AddDate(0, 1, 0), true
+	case "last-month":
+		return thisMonth.AddDate(0, -1, 0), thisMonth, true
+	case "next-month":
+		return thisMonth.AddDate(0, 1, 0), thisMonth.AddDate(0, 2, 0), true
+	case "sow":
+		return day(thisWeek)
+	case "eow":
+		return day(thisWeek.AddDate(0, 0, 6))
+	case "som":
+		return day(thisMonth)
+	case "eom":
+		return day(thisMonth.AddDate(0, 1, -1))
+	case "soy":
+		return day(thisYear)
+	case "eoy":
+		return day(thisYear.AddDate(1, 0, -1))
+	}
+
+	if offset, ok := parseOffset(value, today); ok {
+		return day(offset)
+	}
+
+	d, err := time.ParseInLocation("2006-01-02", value, now.Location())
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	return day(d)
+}
+
+// parseOffset resolves a signed offset such as +7d, -2w, +1m or -1y
+// relative to today.
+func parseOffset(value string, today time.Time) (time.Time, bool) {
+	if len(value) < 3 || (value[0] != '+' && value[0] != '-') {
+		return time.Time{}, false
+	}
+	n, err := strconv.Atoi(value[1 : len(value)-1])
+	if err != nil || n < 0 {
+		return time.Time{}, false
+	}
+	if value[0] == '-' {
+		n = -n
+	}
+
+	switch value[len(value)-1] {
+	case 'd':
+		return today.AddDate(0, 0, n), true
+	case 'w':
+		return today.AddDate(0, 0, 7*n), true
+	case 'm':
+		return addMonths(today, n), true
+	case 'y':
+		return addMonths(today, 12*n), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// addMonths moves t by n months, clamping to the last day of the target
+// month so that Jan 31 + 1 month is Feb 28 rather than early March.
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	d := t.Day()
+	if d > last {
+		d = last
+	}
+	return time.Date(first.Year(), first.Month(), d, 0, 0, 0, 0, t.Location())
+}
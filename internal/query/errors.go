@@ -0,0 +1,184 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ParseError is a syntax or validation error at a position in a query. It
+// carries "did you mean" suggestions for misspelled names and values, and
+// marshals to JSON with a rendered caret line so agents can correct the
+// query.
+type ParseError struct {
+	Query       string   `json:"query"`
+	Pos         int      `json:"position"` // byte offset into Query
+	Message     string   `json:"message"`
+	Suggestions []string `json:"suggestions,omitempty"`
+	SavedQuery  string   `json:"saved_query,omitempty"` // set when Query is the text of a saved query
+}
+
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("%s at position %d", e.Message, e.Pos)
+	if e.SavedQuery != "" {
+		msg = fmt.Sprintf("in @%s: %s", e.SavedQuery, msg)
+	}
+	if len(e.Suggestions) > 0 {
+		msg += fmt.Sprintf(" (did you mean %s?)", quotedList(e.Suggestions, "or"))
+	}
+	return msg
+}
+
+// Caret returns the query with a second line pointing at the error:
+//
+//	stauts:open AND area:work
+//	^
+func (e *ParseError) Caret() string {
+	pos := e.Pos
+	if pos < 0 {
+		pos = 0
+	}
+	if pos > len(e.Query) {
+		pos = len(e.Query)
+	}
+	var pad strings.Builder
+	for _, r := range e.Query[:pos] {
+		if r == '\t' {
+			pad.WriteByte('\t')
+		} else {
+			pad.WriteByte(' ')
+		}
+	}
+	return e.Query + "\n" + pad.String() + "^"
+}
+
+// MarshalJSON adds the caret line and the full error text.
+func (e *ParseError) MarshalJSON() ([]byte, error) {
+	type plain ParseError
+	return json.Marshal(struct {
+		*plain
+		Caret string `json:"caret"`
+		Error string `json:"error"`
+	}{(*plain)(e), e.Caret(), e.Error()})
+}
+
+// errorAt returns a ParseError at pos. A negative pos is filled in by the
+// caller that knows where the offending token is.
+func errorAt(pos int, format string, args ...interface{}) *ParseError {
+	return &ParseError{Pos: pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// withQuery records the query text on a ParseError that doesn't have one
+// yet, and turns any other error into a ParseError at position 0.
+func withQuery(err error, query string) error {
+	pe, ok := err.(*ParseError)
+	if !ok {
+		return &ParseError{Query: query, Message: err.Error()}
+	}
+	if pe.Query == "" && pe.SavedQuery == "" {
+		pe.Query = query
+	}
+	return pe
+}
+
+// suggest returns up to three candidates close to word: the nearest ones
+// within a small edit distance, and those word is a prefix of.
+func suggest(word string, candidates []string) []string {
+	word = strings.ToLower(word)
+	if word == "" {
+		return nil
+	}
+	maxDist := 3
+	switch {
+	case len(word) <= 2:
+		maxDist = 1
+	case len(word) <= 5:
+		maxDist = 2
+	}
+
+	type match struct {
+		name string
+		dist int
+	}
+	var matches []match
+	seen := make(map[string]bool)
+	best := maxDist
+	for _, c := range candidates {
+		if seen[c] || c == "" {
+			continue
+		}
+		seen[c] = true
+		d := editDistance(word, c)
+		if len(word) >= 3 && strings.HasPrefix(c, word) {
+			// Completions rank with the closest misspellings
+			d = 1
+		}
+		if d <= maxDist {
+			matches = append(matches, match{c, d})
+			if d < best {
+				best = d
+			}
+		}
+	}
+
+	// Only keep the nearest candidates
+	kept := matches[:0]
+	for _, m := range matches {
+		if m.dist == best {
+			kept = append(kept, m)
+		}
+	}
+	matches = kept
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].dist != matches[j].dist {
+			return matches[i].dist < matches[j].dist
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	var out []string
+	for i := 0; i < len(matches) && i < 3; i++ {
+		out = append(out, matches[i].name)
+	}
+	return out
+}
+
+// editDistance is the Levenshtein distance between a and b, counting an
+// adjacent transposition as one edit so that "stauts" is close to "status".
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// quotedList formats items as "a", "b" or "c".
+func quotedList(items []string, conj string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = fmt.Sprintf("%q", item)
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " " + conj + " " + quoted[len(quoted)-1]
+}
@@ -0,0 +1,275 @@
+package query
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+// fieldKind determines which operators and values a field accepts.
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindNumber
+	kindDate
+)
+
+// RecordType names the kind of record a query is run against, which decides
+// the fields it may compare. The zero value accepts the fields of every
+// record type, for queries that aren't tied to one, such as saved queries.
+type RecordType string
+
+// Record types for ParseFor
+const (
+	TaskRecords    RecordType = "task"
+	ProjectRecords RecordType = "project"
+	ActionRecords  RecordType = "action"
+)
+
+// fieldKinds lists the task, project and action fields the parser can
+// check, and recordFields which of them each record type has.
+var fieldKinds = map[string]fieldKind{
+	"status":         kindString,
+	"priority":       kindString,
+	"area":           kindString,
+	"assignee":       kindString,
+	"project_id":     kindString,
+	"title":          kindString,
+	"tag":            kindString,
+	"tags":           kindString,
+	"recur":          kindString,
+	"status_history": kindString,
+	"history":        kindString,
+	"content":        kindString,
+	"body":           kindString,
+	"text":           kindString,
+	"action_type":    kindString,
+	"proposed_by":    kindString,
+	"estimate":       kindNumber,
+	"index_id":       kindNumber,
+	"due":            kindDate,
+	"due_date":       kindDate,
+	"start":          kindDate,
+	"start_date":     kindDate,
+	"today":          kindDate,
+	"today_date":     kindDate,
+	"completed":      kindDate,
+	"completed_at":   kindDate,
+	"created":        kindDate,
+	"modified":       kindDate,
+	"proposed_at":    kindDate,
+}
+
+var recordFields = map[RecordType][]string{
+	TaskRecords: {
+		"status", "priority", "area", "assignee", "project_id", "title", "tag", "tags",
+		"recur", "status_history", "history", "content", "body", "text", "estimate", "index_id",
+		"due", "due_date", "start", "start_date", "today", "today_date",
+		"completed", "completed_at", "created", "modified",
+	},
+	ProjectRecords: {
+		"status", "priority", "area", "title", "tag", "tags", "content", "body", "text", "index_id",
+		"due", "due_date", "start", "start_date", "created", "modified",
+	},
+	ActionRecords: {
+		"status", "action_type", "proposed_by", "title", "tag", "tags", "content", "body", "text", "index_id",
+		"proposed_at", "created", "modified",
+	},
+}
+
+// dateKeywords are the special values each date field accepts besides dates.
+// They only make sense with ":".
+var dateKeywords = map[string][]string{
+	"due":        {"overdue", "week", "soon"},
+	"due_date":   {"overdue", "week", "soon"},
+	"today":      {"tagged", "true"},
+	"today_date": {"tagged", "true"},
+}
+
+// statusValues and priorityValues are the values status and priority
+// comparisons accept for each record type. An empty value matches a
+// missing field.
+var (
+	statusValues = map[RecordType][]string{
+		TaskRecords: {
+			denote.TaskStatusOpen, denote.TaskStatusDone, denote.TaskStatusPaused,
+			denote.TaskStatusDelegated, denote.TaskStatusDropped,
+		},
+		ProjectRecords: {
+			denote.ProjectStatusActive, denote.ProjectStatusCompleted,
+			denote.ProjectStatusPaused, denote.ProjectStatusCancelled,
+		},
+		ActionRecords: {
+			denote.ActionPending, denote.ActionApproved, denote.ActionExecuted,
+			denote.ActionFailed, denote.ActionRejected,
+		},
+	}
+	priorityValues = []string{denote.PriorityP1, denote.PriorityP2, denote.PriorityP3}
+)
+
+// validate reports fields that records of type rt don't have, and operators
+// and values that the field cannot compare, so that a query like
+// status>open, stauts:open or, for projects, estimate>3 fails to parse
+// instead of matching nothing.
+func (n *ComparisonNode) validate(rt RecordType) error {
+	field := strings.ToLower(n.Field)
+	value := strings.ToLower(n.Value)
+
+	if n.Values != nil {
+		if n.Operator != ":" && n.Operator != "=" && n.Operator != "!=" {
+			return errorAt(n.fieldPos, "operator %s does not take a list (use :, = or !=, and quote patterns containing parentheses)", n.Operator)
+		}
+		for _, item := range n.listItems() {
+			if err := item.validate(rt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// project.status is checked as a project's status; fields.<key> is any
+	// action field
+	names := fieldNames(rt)
+	if strings.HasPrefix(field, "project.") && (rt == "" || rt == TaskRecords) {
+		return n.joinNode().validate(ProjectRecords)
+	}
+	if strings.HasPrefix(field, "fields.") && len(field) > len("fields.") && (rt == "" || rt == ActionRecords) {
+		names = append(names, field)
+	}
+	if !contains(names, field) {
+		err := errorAt(n.fieldPos, "unknown field %q", n.Field)
+		if rt != "" {
+			err = errorAt(n.fieldPos, "unknown %s field %q", rt, n.Field)
+		}
+		err.Suggestions = suggest(field, names)
+		return err
+	}
+	if n.Workdays {
+		if field != "due" && field != "due_date" {
+			return errorAt(n.fieldPos, "workdays only applies to due dates (use due:workdays<N)")
+		}
+		if _, err := strconv.Atoi(n.Value); err != nil {
+			return errorAt(n.valuePos, "%s:workdays expects a number, got %q", n.Field, n.Value)
+		}
+		return nil
+	}
+	kind := fieldKinds[field] // fields.<key> is a string
+
+	if kind != kindString && (n.Operator == "~" || n.Operator == "!~") {
+		return errorAt(n.fieldPos, "operator %s is not supported for %s (regex works on text fields)", n.Operator, n.Field)
+	}
+
+	switch kind {
+	case kindString:
+		if n.Operator != ":" && n.Operator != "=" && n.Operator != "!=" && n.Operator != "~" && n.Operator != "!~" {
+			return errorAt(n.fieldPos, "operator %s is not supported for %s (use :, =, !=, ~ or !~)", n.Operator, n.Field)
+		}
+		re, err := n.compilePattern()
+		if err != nil {
+			return errorAt(n.valuePos, "invalid pattern %q for %s: %v", n.Value, n.Field, err)
+		}
+		n.pattern, n.compiled = re, true
+		if (field == "project_id" || field == "recur") && (value == "empty" || value == "set") && n.Operator != ":" {
+			return errorAt(n.fieldPos, "operator %s is not supported for %s:%s (use :)", n.Operator, n.Field, value)
+		}
+		if re == nil && value != "" {
+			switch field {
+			case "status":
+				return n.checkValue(statusValuesFor(rt))
+			case "priority":
+				return n.checkValue(priorityValues)
+			}
+		}
+
+	case kindNumber:
+		if _, err := strconv.Atoi(n.Value); err != nil {
+			return errorAt(n.valuePos, "%s expects a number, got %q", n.Field, n.Value)
+		}
+
+	case kindDate:
+		keywords := append([]string{"empty", "set"}, dateKeywords[field]...)
+		for _, k := range keywords {
+			if value == k {
+				if n.Operator != ":" {
+					return errorAt(n.fieldPos, "operator %s is not supported for %s:%s (use :)", n.Operator, n.Field, value)
+				}
+				return nil
+			}
+		}
+		if _, _, ok := periodRange(value, time.Now()); !ok {
+			err := errorAt(n.valuePos, "invalid date %q for %s (use YYYY-MM-DD, today, +7d, -30d, eom, ...)", n.Value, n.Field)
+			err.Suggestions = suggest(value, append(keywords, periodNames...))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkValue rejects a value outside allowed, suggesting the closest ones.
+func (n *ComparisonNode) checkValue(allowed []string) error {
+	value := strings.ToLower(n.Value)
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	err := errorAt(n.valuePos, "unknown %s %q", n.Field, n.Value)
+	err.Suggestions = suggest(value, allowed)
+	return err
+}
+
+// fieldNames lists the fields a comparison on records of type rt accepts,
+// sorted.
+func fieldNames(rt RecordType) []string {
+	if fields, ok := recordFields[rt]; ok {
+		names := append([]string{}, fields...)
+		sort.Strings(names)
+		return names
+	}
+	names := make([]string, 0, len(fieldKinds))
+	for name := range fieldKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// statusValuesFor returns the statuses records of type rt can have.
+func statusValuesFor(rt RecordType) []string {
+	if values, ok := statusValues[rt]; ok {
+		return values
+	}
+	var all []string
+	for _, t := range []RecordType{TaskRecords, ProjectRecords, ActionRecords} {
+		for _, v := range statusValues[t] {
+			if !contains(all, v) {
+				all = append(all, v)
+			}
+		}
+	}
+	return all
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// mapKeys returns the sorted keys of m.
+func mapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -2,33 +2,32 @@ package query
 
 import (
 	"fmt"
+	"strings"
 )
 
 // Parser implements a recursive descent parser for query expressions
 type Parser struct {
-	tokens []Token
-	pos    int
+	tokens  []Token
+	pos     int
+	resolve func(name string) (*Query, error) // looks up @name; nil rejects references
+	records RecordType                        // decides which fields comparisons may use
 }
 
-// Parse converts a query string into an AST
+// Parse converts a query string into an AST. Result clauses (ORDER BY,
+// LIMIT, FIELDS) are rejected; use ParseQuery where they apply. Syntax
+// errors are returned as *ParseError.
 func Parse(query string) (Node, error) {
-	tokens, err := Tokenize(query)
+	q, err := ParseQuery(query)
 	if err != nil {
 		return nil, err
 	}
-
-	parser := &Parser{tokens: tokens, pos: 0}
-	node, err := parser.parseExpression()
-	if err != nil {
-		return nil, err
+	if len(q.OrderBy) > 0 || q.Limit > 0 || len(q.Fields) > 0 {
+		return nil, fmt.Errorf("ORDER BY, LIMIT and FIELDS are not supported here")
 	}
-
-	// Ensure we consumed all tokens (except EOF)
-	if !parser.isAtEnd() {
-		return nil, fmt.Errorf("unexpected token at position %d: %s", parser.current().Pos, parser.current())
+	if q.Filter == nil {
+		return nil, fmt.Errorf("empty query")
 	}
-
-	return node, nil
+	return q.Filter, nil
 }
 
 // parseExpression handles OR at the top level
@@ -69,8 +68,8 @@ func (p *Parser) parseTerm() (Node, error) {
 	return node, nil
 }
 
-// parseFactor handles NOT and parentheses
-// factor := NOT factor | ( expression ) | comparison
+// parseFactor handles NOT, parentheses and saved query references
+// factor := NOT factor | ( expression ) | @name | comparison
 func (p *Parser) parseFactor() (Node, error) {
 	// Handle NOT
 	if p.match(TokenNOT) {
@@ -82,50 +81,97 @@ func (p *Parser) parseFactor() (Node, error) {
 	}
 
 	// Handle parentheses
-	if p.match(TokenLeftParen) {
+	if p.check(TokenLeftParen) {
+		open := p.advance()
 		node, err := p.parseExpression()
 		if err != nil {
 			return nil, err
 		}
 		if !p.match(TokenRightParen) {
-			return nil, fmt.Errorf("expected ) at position %d", p.current().Pos)
+			return nil, errorAt(p.current().Pos, "expected ) to close ( at position %d, got %s", open.Pos, describe(p.current()))
 		}
 		return node, nil
 	}
 
+	// Handle @name
+	if p.check(TokenRef) {
+		ref := p.advance()
+		if p.resolve == nil {
+			return nil, errorAt(ref.Pos, "saved query @%s cannot be used here", ref.Value)
+		}
+		saved, err := p.resolve(ref.Value)
+		if err != nil {
+			if pe, ok := err.(*ParseError); ok && pe.Pos < 0 {
+				pe.Pos = ref.Pos
+			}
+			return nil, err
+		}
+		return &RefNode{Name: ref.Value, Filter: saved.Filter}, nil
+	}
+
 	// Handle comparison
 	return p.parseComparison()
 }
 
 // parseComparison handles field:value, field>value, etc.
-// comparison := FIELD (: | > | < | = | !=) VALUE
+// comparison := FIELD (: | > | < | >= | <= | = | != | ~ | !~) (VALUE | LIST)
 func (p *Parser) parseComparison() (Node, error) {
 	if !p.check(TokenField) {
-		return nil, fmt.Errorf("expected field name at position %d, got %s", p.current().Pos, p.current())
+		return nil, errorAt(p.current().Pos, "expected field name, got %s", describe(p.current()))
 	}
 
 	field := p.advance()
 
 	// Expect an operator
 	if !p.check(TokenColon) && !p.check(TokenGT) && !p.check(TokenLT) &&
-		!p.check(TokenEQ) && !p.check(TokenNE) {
-		return nil, fmt.Errorf("expected operator (:, >, <, =, !=) at position %d, got %s", p.current().Pos, p.current())
+		!p.check(TokenGE) && !p.check(TokenLE) && !p.check(TokenEQ) && !p.check(TokenNE) &&
+		!p.check(TokenMatch) && !p.check(TokenNotMatch) {
+		return nil, errorAt(p.current().Pos, "expected operator (:, >, <, >=, <=, =, !=, ~, !~) after %s, got %s", field.Value, describe(p.current()))
 	}
 
 	operator := p.advance()
 
-	// Expect a value
-	if !p.check(TokenValue) {
-		return nil, fmt.Errorf("expected value at position %d, got %s", p.current().Pos, p.current())
+	// Expect a value or a list of values
+	if !p.check(TokenValue) && !p.check(TokenList) {
+		return nil, errorAt(p.current().Pos, "expected value after %s%s, got %s", field.Value, operator.Value, describe(p.current()))
 	}
 
 	value := p.advance()
 
-	return &ComparisonNode{
+	// due:workdays<3 compares the workdays left until the due date
+	if operator.Type == TokenColon && strings.EqualFold(value.Value, "workdays") && value.Items == nil &&
+		(p.check(TokenGT) || p.check(TokenLT) || p.check(TokenGE) || p.check(TokenLE) || p.check(TokenEQ) || p.check(TokenNE)) {
+		operator = p.advance()
+		if !p.check(TokenValue) {
+			return nil, errorAt(p.current().Pos, "expected a number of workdays after %s:workdays%s, got %s", field.Value, operator.Value, describe(p.current()))
+		}
+		count := p.advance()
+		node := &ComparisonNode{
+			Field:    field.Value,
+			Operator: operator.Value,
+			Value:    count.Value,
+			Workdays: true,
+			fieldPos: field.Pos,
+			valuePos: count.Pos,
+		}
+		if err := node.validate(p.records); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+
+	node := &ComparisonNode{
 		Field:    field.Value,
 		Operator: operator.Value,
 		Value:    value.Value,
-	}, nil
+		Values:   value.Items,
+		fieldPos: field.Pos,
+		valuePos: value.Pos,
+	}
+	if err := node.validate(p.records); err != nil {
+		return nil, err
+	}
+	return node, nil
 }
 
 // Helper methods
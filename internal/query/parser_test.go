@@ -0,0 +1,319 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+func TestStringOperators(t *testing.T) {
+	task := &denote.Task{}
+	task.Title = "Review Q3 budget (draft)"
+	task.Area = "Work"
+	task.Assignee = "alice"
+	task.Priority = denote.PriorityP2
+	task.Recur = "every monday"
+	task.Tags = []string{"task", "client-acme"}
+	task.Content = "Waiting on numbers from finance."
+
+	cfg := &config.Config{}
+	for query, want := range map[string]bool{
+		`title~"^review"`:                  true,
+		`title~^budget`:                    false,
+		`title!~"^review"`:                 false,
+		`title~"q\d"`:                      true,
+		`title~"\(draft\)$"`:               true,
+		`title:"review q3 budget (draft)"`: true,
+		`title:review*`:                    true,
+		`title:*budget*`:                   true,
+		`title:review`:                     false,
+		`title:"review q? budget*"`:        true,
+		`priority:(p1,p2)`:                 true,
+		`priority:(p1,p3)`:                 false,
+		`priority!=(p1,p3)`:                true,
+		`area:(home, work)`:                true,
+		`assignee:al*`:                     true,
+		`assignee:(bob,al*)`:               true,
+		`tag:client-*`:                     true,
+		`tag!=client-*`:                    false,
+		`tag!=urgent`:                      true,
+		`tag~acme$`:                        true,
+		`tag:(urgent,client-acme)`:         true,
+		`recur:every*`:                     true,
+		`recur~"mon|tue"`:                  true,
+		`content:finance`:                  true,
+		`content:wait*numbers`:             true,
+		`content~"numbers\s+from"`:         true,
+		`content!~payroll`:                 true,
+		`status:(open,"")`:                 true,
+		`due:(empty,today)`:                true,
+		`estimate:(0,1)`:                   true,
+		`estimate!=(0,1)`:                  false,
+	} {
+		node, err := Parse(query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", query, err)
+			continue
+		}
+		if got := node.Evaluate(task, cfg); got != want {
+			t.Errorf("%q = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestParseRejectsBadPatterns(t *testing.T) {
+	for _, query := range []string{
+		`title~"(unclosed"`,
+		`title~(a|b)`,
+		`estimate~5`,
+		`due~today`,
+		`priority>(p1,p2)`,
+		`estimate:(1,big)`,
+	} {
+		if _, err := Parse(query); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", query)
+		}
+	}
+}
+
+func TestComparisonString(t *testing.T) {
+	node, err := Parse(`priority:(p1,p2) AND title~"^a b"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := node.String(), "(priority:(p1,p2) AND title~^a b)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestParseQueryClauses(t *testing.T) {
+	q, err := ParseQuery("status:open AND area:work ORDER BY due ASC, priority DESC LIMIT 10 FIELDS index_id, title,due")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Filter == nil || q.Filter.String() != "(status:open AND area:work)" {
+		t.Errorf("Filter = %v", q.Filter)
+	}
+	wantKeys := []SortKey{{Field: "due"}, {Field: "priority", Desc: true}}
+	if !reflect.DeepEqual(q.OrderBy, wantKeys) {
+		t.Errorf("OrderBy = %v, want %v", q.OrderBy, wantKeys)
+	}
+	if q.Limit != 10 {
+		t.Errorf("Limit = %d, want 10", q.Limit)
+	}
+	if want := []string{"index_id", "title", "due_date"}; !reflect.DeepEqual(q.Fields, want) {
+		t.Errorf("Fields = %v, want %v", q.Fields, want)
+	}
+
+	// Clauses alone, in any order, and keywords used as values
+	q, err = ParseQuery("limit 3 order by title")
+	if err != nil || q.Filter != nil || q.Limit != 3 || len(q.OrderBy) != 1 {
+		t.Errorf("clauses only = %+v, %v", q, err)
+	}
+	if _, err := ParseQuery("title:limit ORDER BY title"); err != nil {
+		t.Errorf("keyword as value: %v", err)
+	}
+
+	for _, query := range []string{
+		"status:open ORDER due",
+		"status:open ORDER BY",
+		"status:open ORDER BY content",
+		"status:open ORDER BY due SIDEWAYS",
+		"status:open LIMIT 0",
+		"status:open LIMIT ten",
+		"status:open LIMIT 1 LIMIT 2",
+		"status:open FIELDS title,bogus",
+		"status:open bogus",
+	} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%q) succeeded, want error", query)
+		}
+	}
+
+	if _, err := Parse("status:open LIMIT 5"); err == nil {
+		t.Errorf("Parse accepted a LIMIT clause")
+	}
+}
+
+func TestQuerySortAndLimit(t *testing.T) {
+	mk := func(id int, priority, due string) denote.Task {
+		var task denote.Task
+		task.IndexID = id
+		task.Priority = priority
+		task.DueDate = due
+		return task
+	}
+	tasks := []denote.Task{
+		mk(1, "p2", ""),
+		mk(2, "p1", "2026-05-01"),
+		mk(3, "", "2026-04-01"),
+		mk(4, "p1", "2026-04-01"),
+	}
+
+	q, err := ParseQuery("ORDER BY due DESC, priority LIMIT 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := q.Apply(tasks)
+	var ids []int
+	for _, task := range got {
+		ids = append(ids, task.IndexID)
+	}
+	// Missing dues sort last even when descending
+	if want := []int{2, 4, 3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("order = %v, want %v", ids, want)
+	}
+}
+
+func TestQueryProject(t *testing.T) {
+	q, err := ParseQuery("FIELDS title,index_id,due")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var task denote.Task
+	task.IndexID = 7
+	task.Title = "Write report"
+
+	row, err := q.Project(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"title":"Write report","index_id":7,"due_date":null}`; got != want {
+		t.Errorf("projected = %s, want %s", got, want)
+	}
+}
+
+func TestParseErrorDetails(t *testing.T) {
+	for _, tc := range []struct {
+		query       string
+		pos         int
+		suggestions []string
+	}{
+		{"stauts:open", 0, []string{"status"}},
+		{"status:opne AND area:work", 7, []string{"open"}},
+		{"area:work AND priority:p4", 23, []string{"p1", "p2", "p3"}},
+		{"project.stats:active", 8, []string{"status"}},
+		{"due:overdeu", 4, []string{"overdue"}},
+		{"status:open area:work", 12, nil},
+		{"(status:open", 12, nil},
+		{`title:"abc`, 6, nil},
+		{"status:open FIELDS titel", 12, []string{"title"}},
+		{"  bogus:x", 2, nil},
+	} {
+		_, err := ParseQuery(tc.query)
+		pe, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("ParseQuery(%q) error = %v, want *ParseError", tc.query, err)
+			continue
+		}
+		if pe.Pos != tc.pos || pe.Query != tc.query {
+			t.Errorf("ParseQuery(%q): position %d in %q, want %d", tc.query, pe.Pos, pe.Query, tc.pos)
+		}
+		if tc.suggestions != nil && !reflect.DeepEqual(pe.Suggestions, tc.suggestions) {
+			t.Errorf("ParseQuery(%q): suggestions %v, want %v", tc.query, pe.Suggestions, tc.suggestions)
+		}
+	}
+
+	_, err := ParseQuery("area:work AND stauts:open")
+	pe := err.(*ParseError)
+	if want := "area:work AND stauts:open\n              ^"; pe.Caret() != want {
+		t.Errorf("Caret() = %q, want %q", pe.Caret(), want)
+	}
+	if want := `unknown field "stauts" at position 14 (did you mean "status"?)`; pe.Error() != want {
+		t.Errorf("Error() = %q, want %q", pe.Error(), want)
+	}
+
+	data, err := json.Marshal(pe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"query", "position", "message", "suggestions", "caret", "error"} {
+		if _, ok := obj[key]; !ok {
+			t.Errorf("JSON error %s lacks %q", data, key)
+		}
+	}
+
+	// Values that are patterns, and empty values, aren't checked
+	for _, query := range []string{"status:d*", "status~^op", `priority:""`, "fields.anything:x"} {
+		if _, err := ParseQuery(query); err != nil {
+			t.Errorf("ParseQuery(%q): %v", query, err)
+		}
+	}
+}
+
+func TestParseErrorInSavedQuery(t *testing.T) {
+	cfg := &config.Config{Queries: map[string]config.SavedQuery{
+		"next": {Query: "status:open AND priorty:p1"},
+	}}
+	_, err := ParseWithConfig("area:work AND @next", cfg)
+	pe, ok := err.(*ParseError)
+	if !ok || pe.SavedQuery != "next" || pe.Query != "status:open AND priorty:p1" || pe.Pos != 16 {
+		t.Fatalf("error = %#v, want one at priorty in @next", err)
+	}
+
+	_, err = ParseWithConfig("@nxt", cfg)
+	if pe, ok := err.(*ParseError); !ok || pe.Pos != 0 || !reflect.DeepEqual(pe.Suggestions, []string{"@next"}) {
+		t.Errorf("error = %#v, want suggestion @next", err)
+	}
+}
+
+func TestParseForRecordType(t *testing.T) {
+	cfg := &config.Config{Queries: map[string]config.SavedQuery{
+		"big": {Query: "estimate>3"},
+	}}
+
+	for _, tc := range []struct {
+		query       string
+		rt          RecordType
+		ok          bool
+		suggestions []string
+	}{
+		{"estimate>3 AND recur:set", TaskRecords, true, nil},
+		{"project.status:active", TaskRecords, true, nil},
+		{"project.estimate>3", TaskRecords, false, nil},
+		{"action_type:task_create", TaskRecords, false, nil},
+		{"status:pending", TaskRecords, false, nil},
+		{"status:active AND due:soon", ProjectRecords, true, nil},
+		{"due:workdays<3", ProjectRecords, true, nil},
+		{"estimate>3", ProjectRecords, false, nil},
+		{"status:open", ProjectRecords, false, nil},
+		{"project.status:active", ProjectRecords, false, nil},
+		{"@big", ProjectRecords, false, nil},
+		{"projcet_id:1", ProjectRecords, false, []string{}},
+		{"are:work", ProjectRecords, false, []string{"area"}},
+		{"action_type:task_create AND fields.priority:p1", ActionRecords, true, nil},
+		{"proposd_by:agent", ActionRecords, false, []string{"proposed_by"}},
+		{"due:today", ActionRecords, false, nil},
+		{"due:workdays<3", ActionRecords, false, nil},
+		{"fields.priority:p1", TaskRecords, false, nil},
+		// Without a record type every field is accepted
+		{"estimate>3 OR action_type:x OR fields.x:y", "", true, nil},
+	} {
+		_, err := ParseFor(tc.query, cfg, tc.rt)
+		if (err == nil) != tc.ok {
+			t.Errorf("ParseFor(%q, %s) error = %v, want ok=%v", tc.query, tc.rt, err, tc.ok)
+			continue
+		}
+		if tc.suggestions == nil {
+			continue
+		}
+		pe, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("ParseFor(%q, %s) error = %v, want *ParseError", tc.query, tc.rt, err)
+		} else if fmt.Sprint(pe.Suggestions) != fmt.Sprint(tc.suggestions) {
+			t.Errorf("ParseFor(%q, %s): suggestions %v, want %v", tc.query, tc.rt, pe.Suggestions, tc.suggestions)
+		}
+	}
+}
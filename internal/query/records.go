@@ -0,0 +1,122 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+// Record is what a query is evaluated against: a *denote.Task,
+// *denote.Project or *denote.Action, or a *TaskRecord when the query may
+// join on the task's project. Other values never match.
+type Record interface{}
+
+// TaskRecord is a task together with its project, so that dotted fields
+// such as project.status:active or project.area:work can be resolved.
+type TaskRecord struct {
+	Task    *denote.Task
+	Project *denote.Project // nil if the task has no project or it wasn't found
+}
+
+// ProjectsByID indexes projects by both index_id and ULID, the two forms a
+// task's project_id may take.
+func ProjectsByID(projects []*denote.Project) map[string]*denote.Project {
+	byID := make(map[string]*denote.Project, 2*len(projects))
+	for _, p := range projects {
+		byID[strconv.Itoa(p.IndexID)] = p
+		if p.ID != "" {
+			byID[p.ID] = p
+		}
+	}
+	return byID
+}
+
+// NewTaskRecord pairs a task with its project from byID.
+func NewTaskRecord(t *denote.Task, byID map[string]*denote.Project) *TaskRecord {
+	rec := &TaskRecord{Task: t}
+	if t.ProjectID != "" {
+		rec.Project = byID[t.ProjectID]
+	}
+	return rec
+}
+
+// evaluateProject compares a project field.
+func (n *ComparisonNode) evaluateProject(p *denote.Project, cfg *config.Config) bool {
+	field := strings.ToLower(n.Field)
+	value := strings.ToLower(n.Value)
+
+	switch field {
+	case "status":
+		return n.matchString(strings.ToLower(p.ProjectMetadata.Status), value)
+	case "priority":
+		return n.matchString(strings.ToLower(p.ProjectMetadata.Priority), value)
+	case "area":
+		return n.matchString(strings.ToLower(p.ProjectMetadata.Area), value)
+	case "title":
+		return n.matchString(strings.ToLower(p.Title), value)
+	case "tag", "tags":
+		return n.matchAny(p.Tags, value)
+	case "due", "due_date":
+		return n.compareDue(p.ProjectMetadata.DueDate, value, cfg)
+	case "start", "start_date":
+		return compareDate(p.ProjectMetadata.StartDate, n.Operator, value)
+	case "created":
+		return compareDate(p.Created, n.Operator, value)
+	case "modified":
+		return compareDate(modifiedOf(p.Modified, p.ModTime), n.Operator, value)
+	case "index_id":
+		return compareInt(p.IndexID, n.Operator, value)
+	case "content", "body", "text":
+		return n.matchContent(p.Content, value)
+	default:
+		return false
+	}
+}
+
+// evaluateAction compares an action field. fields.<key> compares one of
+// the action's proposed field values.
+func (n *ComparisonNode) evaluateAction(a *denote.Action) bool {
+	field := strings.ToLower(n.Field)
+	value := strings.ToLower(n.Value)
+
+	if key, ok := strings.CutPrefix(field, "fields."); ok {
+		return n.matchString(strings.ToLower(a.Fields[key]), value)
+	}
+
+	switch field {
+	case "action_type":
+		return n.matchString(strings.ToLower(a.ActionType), value)
+	case "status":
+		return n.matchString(strings.ToLower(a.ActionMetadata.Status), value)
+	case "proposed_by":
+		return n.matchString(strings.ToLower(a.ProposedBy), value)
+	case "proposed_at":
+		return compareDate(a.ProposedAt, n.Operator, value)
+	case "title":
+		return n.matchString(strings.ToLower(a.Title), value)
+	case "tag", "tags":
+		return n.matchAny(a.Tags, value)
+	case "created":
+		return compareDate(a.Created, n.Operator, value)
+	case "modified":
+		return compareDate(modifiedOf(a.Modified, a.ModTime), n.Operator, value)
+	case "index_id":
+		return compareInt(a.IndexID, n.Operator, value)
+	case "content", "body", "text":
+		return n.matchContent(a.Content, value)
+	default:
+		return false
+	}
+}
+
+// modifiedOf returns the modified timestamp, falling back to the file's
+// modification time.
+func modifiedOf(modified string, modTime time.Time) string {
+	if modified == "" && !modTime.IsZero() {
+		return modTime.Format(time.RFC3339)
+	}
+	return modified
+}
@@ -0,0 +1,116 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mph-llm-experiments/atask/internal/config"
+)
+
+// ValidateSaved parses every saved query in cfg's [queries] table,
+// including the saved queries each one references. config.Load only checks
+// the names and sort keys, since parsing queries belongs to this package.
+func ValidateSaved(cfg *config.Config) error {
+	for _, name := range cfg.QueryNames() {
+		r := newResolver(cfg, "")
+		r.stack = []string{name}
+		if _, err := parseQuery(cfg.Queries[name].Query, "", r.resolve); err != nil {
+			return fmt.Errorf("invalid saved query %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ParseWithConfig parses like ParseQuery and also resolves @name references
+// to the saved queries in cfg's [queries] table. Saved queries may refer to
+// each other. When the whole filter is a single reference, the saved query's
+// ORDER BY, LIMIT and FIELDS clauses apply unless the expression has its own,
+// and Saved is set to the name.
+func ParseWithConfig(query string, cfg *config.Config) (*Query, error) {
+	return ParseFor(query, cfg, "")
+}
+
+// ParseFor parses like ParseWithConfig, and rejects comparisons on fields
+// that records of type rt don't have, including those in referenced saved
+// queries.
+func ParseFor(query string, cfg *config.Config, rt RecordType) (*Query, error) {
+	r := newResolver(cfg, rt)
+	q, err := parseQuery(query, rt, r.resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, ok := q.Filter.(*RefNode)
+	if !ok {
+		return q, nil
+	}
+	saved := r.parsed[ref.Name]
+	merged := &Query{
+		Filter:  ref,
+		OrderBy: saved.OrderBy,
+		Limit:   saved.Limit,
+		Fields:  saved.Fields,
+		Saved:   ref.Name,
+	}
+	if len(q.OrderBy) > 0 {
+		merged.OrderBy = q.OrderBy
+	}
+	if q.Limit > 0 {
+		merged.Limit = q.Limit
+	}
+	if len(q.Fields) > 0 {
+		merged.Fields = q.Fields
+	}
+	return merged, nil
+}
+
+// resolver parses saved queries on demand, detecting unknown names and
+// reference cycles.
+type resolver struct {
+	cfg     *config.Config
+	records RecordType
+	parsed  map[string]*Query
+	stack   []string // names being parsed, outermost first
+}
+
+func newResolver(cfg *config.Config, records RecordType) *resolver {
+	return &resolver{cfg: cfg, records: records, parsed: make(map[string]*Query)}
+}
+
+func (r *resolver) resolve(name string) (*Query, error) {
+	if q, ok := r.parsed[name]; ok {
+		return q, nil
+	}
+	for i, n := range r.stack {
+		if n == name {
+			cycle := append(append([]string{}, r.stack[i:]...), name)
+			return nil, errorAt(-1, "saved query cycle @%s", strings.Join(cycle, " -> @"))
+		}
+	}
+	var saved config.SavedQuery
+	ok := false
+	if r.cfg != nil {
+		saved, ok = r.cfg.Queries[name]
+	}
+	if !ok {
+		err := errorAt(-1, "unknown saved query @%s", name)
+		if r.cfg != nil {
+			for _, s := range suggest(name, r.cfg.QueryNames()) {
+				err.Suggestions = append(err.Suggestions, "@"+s)
+			}
+		}
+		return nil, err
+	}
+
+	r.stack = append(r.stack, name)
+	q, err := parseQuery(saved.Query, r.records, r.resolve)
+	r.stack = r.stack[:len(r.stack)-1]
+	if err != nil {
+		if pe, ok := err.(*ParseError); ok && pe.SavedQuery == "" {
+			pe.SavedQuery = name
+		}
+		return nil, err
+	}
+	r.parsed[name] = q
+	return q, nil
+}
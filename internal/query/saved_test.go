@@ -0,0 +1,118 @@
+package query
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+func TestParseWithConfigReferences(t *testing.T) {
+	cfg := &config.Config{Queries: map[string]config.SavedQuery{
+		"open":  {Query: "status:open"},
+		"work":  {Query: "@open AND area:work ORDER BY due LIMIT 5"},
+		"first": {Query: "ORDER BY priority LIMIT 1"},
+	}}
+
+	task := &denote.Task{}
+	task.Status = denote.TaskStatusOpen
+	task.Area = "work"
+
+	q, err := ParseWithConfig("@work", cfg)
+	if err != nil {
+		t.Fatalf("ParseWithConfig(@work): %v", err)
+	}
+	if q.Saved != "work" || q.Limit != 5 || !reflect.DeepEqual(q.OrderBy, []SortKey{{Field: "due"}}) {
+		t.Errorf("@work = %+v, want the saved clauses", q)
+	}
+	if !q.Match(task, cfg) {
+		t.Errorf("@work should match an open work task")
+	}
+
+	// The expression's own clauses override the saved ones
+	q, err = ParseWithConfig("@work LIMIT 2", cfg)
+	if err != nil || q.Limit != 2 || len(q.OrderBy) != 1 {
+		t.Errorf("@work LIMIT 2 = %+v, %v", q, err)
+	}
+
+	// Inside a larger expression only the filter is used
+	q, err = ParseWithConfig("NOT @work OR area:home", cfg)
+	if err != nil {
+		t.Fatalf("ParseWithConfig: %v", err)
+	}
+	if q.Saved != "" || q.Limit != 0 || q.Match(task, cfg) {
+		t.Errorf("NOT @work OR area:home = %+v", q)
+	}
+
+	// A saved query without a filter matches everything
+	q, err = ParseWithConfig("@first AND area:work", cfg)
+	if err != nil || !q.Match(task, cfg) {
+		t.Errorf("@first AND area:work = %+v, %v", q, err)
+	}
+
+	if _, err := ParseQuery("@work"); err == nil {
+		t.Errorf("ParseQuery(@work) should reject references")
+	}
+}
+
+func TestParseWithConfigErrors(t *testing.T) {
+	cfg := &config.Config{Queries: map[string]config.SavedQuery{
+		"a":   {Query: "status:open AND @b"},
+		"b":   {Query: "@a"},
+		"bad": {Query: "due>soon"},
+	}}
+
+	for query, want := range map[string]string{
+		"@missing":     "unknown saved query @missing",
+		"@a":           "cycle @a -> @b -> @a",
+		"area:x OR @b": "cycle @b -> @a -> @b",
+		"@bad":         "in @bad:",
+		"@":            "expected saved query name",
+	} {
+		_, err := ParseWithConfig(query, cfg)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseWithConfig(%q) error = %v, want %q", query, err, want)
+		}
+	}
+}
+
+func TestValidateSaved(t *testing.T) {
+	dir := t.TempDir()
+	load := func(queries string) (*config.Config, error) {
+		path := filepath.Join(dir, "config.toml")
+		data := "notes_directory = " + `"` + dir + `"` + "\n\n[queries]\n" + queries
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := config.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		return cfg, ValidateSaved(cfg)
+	}
+
+	cfg, err := load("waiting = \"status:delegated\"\nnext = { query = \"@waiting OR priority:p1\", sort = \"due\", reverse = true }\n")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := config.SavedQuery{Query: "@waiting OR priority:p1", Sort: "due", Reverse: true}
+	if cfg.Queries["next"] != want || cfg.Queries["waiting"].Query != "status:delegated" {
+		t.Errorf("Queries = %+v", cfg.Queries)
+	}
+
+	for queries, want := range map[string]string{
+		"next = \"@later\"\n":                                   "invalid saved query next: unknown saved query @later",
+		"next = \"status>open\"\n":                              "invalid saved query next",
+		"loop = \"@loop\"\n":                                    "cycle @loop -> @loop",
+		"next = { query = \"status:open\", sort = \"size\" }\n": "invalid sort for saved query next",
+		"\"my query\" = \"status:open\"\n":                      "invalid saved query name",
+	} {
+		if _, err := load(queries); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Load(%q) error = %v, want %q", queries, err, want)
+		}
+	}
+}
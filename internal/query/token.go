@@ -16,12 +16,18 @@ const (
 	TokenLT
 	TokenEQ
 	TokenNE
+	TokenGE
+	TokenLE
+	TokenMatch
+	TokenNotMatch
 	TokenValue
+	TokenList
 	TokenAND
 	TokenOR
 	TokenNOT
 	TokenLeftParen
 	TokenRightParen
+	TokenRef
 	TokenEOF
 )
 
@@ -30,6 +36,7 @@ type Token struct {
 	Type  TokenType
 	Value string
 	Pos   int
+	Items []string // for TokenList
 }
 
 func (t Token) String() string {
@@ -46,8 +53,18 @@ func (t Token) String() string {
 		return "="
 	case TokenNE:
 		return "!="
+	case TokenGE:
+		return ">="
+	case TokenLE:
+		return "<="
+	case TokenMatch:
+		return "~"
+	case TokenNotMatch:
+		return "!~"
 	case TokenValue:
 		return fmt.Sprintf("VALUE(%s)", t.Value)
+	case TokenList:
+		return fmt.Sprintf("LIST(%s)", strings.Join(t.Items, ","))
 	case TokenAND:
 		return "AND"
 	case TokenOR:
@@ -58,6 +75,8 @@ func (t Token) String() string {
 		return "("
 	case TokenRightParen:
 		return ")"
+	case TokenRef:
+		return "@" + t.Value
 	case TokenEOF:
 		return "EOF"
 	default:
@@ -65,11 +84,32 @@ func (t Token) String() string {
 	}
 }
 
-// Tokenize converts a query string into tokens
+// describe names a token for error messages.
+func describe(t Token) string {
+	switch t.Type {
+	case TokenEOF:
+		return "end of query"
+	case TokenList:
+		return t.Value
+	case TokenRef:
+		return "@" + t.Value
+	default:
+		return fmt.Sprintf("%q", t.Value)
+	}
+}
+
+// Tokenize converts a query string into tokens. Errors are *ParseError.
 func Tokenize(query string) ([]Token, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, withQuery(err, query)
+	}
+	return tokens, nil
+}
+
+func tokenize(query string) ([]Token, error) {
 	var tokens []Token
 	pos := 0
-	query = strings.TrimSpace(query)
 
 	for pos < len(query) {
 		// Skip whitespace
@@ -78,6 +118,28 @@ func Tokenize(query string) ([]Token, error) {
 			continue
 		}
 
+		// A quoted string or a parenthesized list after an operator is a value
+		if isOperator(tokens) {
+			switch query[pos] {
+			case '"', '\'':
+				value, end, err := readQuoted(query, pos)
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, Token{Type: TokenValue, Value: value, Pos: pos})
+				pos = end
+				continue
+			case '(':
+				items, end, err := readList(query, pos)
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, Token{Type: TokenList, Value: query[pos:end], Pos: pos, Items: items})
+				pos = end
+				continue
+			}
+		}
+
 		// Check for operators
 		if pos < len(query) {
 			switch query[pos] {
@@ -94,10 +156,20 @@ func Tokenize(query string) ([]Token, error) {
 				pos++
 				continue
 			case '>':
+				if pos+1 < len(query) && query[pos+1] == '=' {
+					tokens = append(tokens, Token{Type: TokenGE, Value: ">=", Pos: pos})
+					pos += 2
+					continue
+				}
 				tokens = append(tokens, Token{Type: TokenGT, Value: ">", Pos: pos})
 				pos++
 				continue
 			case '<':
+				if pos+1 < len(query) && query[pos+1] == '=' {
+					tokens = append(tokens, Token{Type: TokenLE, Value: "<=", Pos: pos})
+					pos += 2
+					continue
+				}
 				tokens = append(tokens, Token{Type: TokenLT, Value: "<", Pos: pos})
 				pos++
 				continue
@@ -105,12 +177,21 @@ func Tokenize(query string) ([]Token, error) {
 				tokens = append(tokens, Token{Type: TokenEQ, Value: "=", Pos: pos})
 				pos++
 				continue
+			case '~':
+				tokens = append(tokens, Token{Type: TokenMatch, Value: "~", Pos: pos})
+				pos++
+				continue
 			case '!':
 				if pos+1 < len(query) && query[pos+1] == '=' {
 					tokens = append(tokens, Token{Type: TokenNE, Value: "!=", Pos: pos})
 					pos += 2
 					continue
 				}
+				if pos+1 < len(query) && query[pos+1] == '~' {
+					tokens = append(tokens, Token{Type: TokenNotMatch, Value: "!~", Pos: pos})
+					pos += 2
+					continue
+				}
 			}
 		}
 
@@ -119,12 +200,13 @@ func Tokenize(query string) ([]Token, error) {
 		for pos < len(query) && !unicode.IsSpace(rune(query[pos])) &&
 			query[pos] != '(' && query[pos] != ')' &&
 			query[pos] != ':' && query[pos] != '>' &&
-			query[pos] != '<' && query[pos] != '=' && query[pos] != '!' {
+			query[pos] != '<' && query[pos] != '=' && query[pos] != '!' &&
+			query[pos] != '~' {
 			pos++
 		}
 
 		if pos == start {
-			return nil, fmt.Errorf("unexpected character at position %d: %c", pos, query[pos])
+			return nil, errorAt(pos, "unexpected character %q", query[pos])
 		}
 
 		word := query[start:pos]
@@ -140,16 +222,15 @@ func Tokenize(query string) ([]Token, error) {
 			tokens = append(tokens, Token{Type: TokenNOT, Value: word, Pos: start})
 		default:
 			// Determine if this is a field or value based on context
-			// If the last token was an operator (:, >, <, =, !=), it's a value
+			// If the last token was an operator, it's a value
 			// Otherwise, it's a field
-			if len(tokens) > 0 {
-				lastType := tokens[len(tokens)-1].Type
-				if lastType == TokenColon || lastType == TokenGT ||
-					lastType == TokenLT || lastType == TokenEQ || lastType == TokenNE {
-					tokens = append(tokens, Token{Type: TokenValue, Value: word, Pos: start})
-				} else {
-					tokens = append(tokens, Token{Type: TokenField, Value: word, Pos: start})
+			if isOperator(tokens) {
+				tokens = append(tokens, Token{Type: TokenValue, Value: word, Pos: start})
+			} else if strings.HasPrefix(word, "@") {
+				if len(word) == 1 {
+					return nil, errorAt(start, "expected saved query name after @")
 				}
+				tokens = append(tokens, Token{Type: TokenRef, Value: word[1:], Pos: start})
 			} else {
 				tokens = append(tokens, Token{Type: TokenField, Value: word, Pos: start})
 			}
@@ -159,3 +240,89 @@ func Tokenize(query string) ([]Token, error) {
 	tokens = append(tokens, Token{Type: TokenEOF, Pos: pos})
 	return tokens, nil
 }
+
+// isOperator reports whether the last token is a comparison operator, so
+// the next token is a value.
+func isOperator(tokens []Token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	switch tokens[len(tokens)-1].Type {
+	case TokenColon, TokenGT, TokenLT, TokenEQ, TokenNE, TokenGE, TokenLE, TokenMatch, TokenNotMatch:
+		return true
+	}
+	return false
+}
+
+// readQuoted reads a string quoted with " or ' starting at pos and returns
+// its content and the position after the closing quote. A backslash escapes
+// the quote character or another backslash; other backslash sequences are
+// kept as written so regular expressions like "\d+" survive.
+func readQuoted(query string, pos int) (string, int, error) {
+	quote := query[pos]
+	var b strings.Builder
+	for i := pos + 1; i < len(query); i++ {
+		c := query[i]
+		if c == '\\' && i+1 < len(query) && (query[i+1] == quote || query[i+1] == '\\') {
+			b.WriteByte(query[i+1])
+			i++
+			continue
+		}
+		if c == quote {
+			return b.String(), i + 1, nil
+		}
+		b.WriteByte(c)
+	}
+	return "", 0, errorAt(pos, "unterminated quoted string")
+}
+
+// readList reads a parenthesized, comma-separated list such as (p1,p2) or
+// ("a b", c) starting at pos and returns its items and the position after
+// the closing parenthesis.
+func readList(query string, pos int) ([]string, int, error) {
+	var items []string
+	i := pos + 1
+	for {
+		for i < len(query) && unicode.IsSpace(rune(query[i])) {
+			i++
+		}
+		if i >= len(query) {
+			return nil, 0, errorAt(pos, "unterminated list")
+		}
+
+		// Only a quoted item may be empty
+		if query[i] == '"' || query[i] == '\'' {
+			value, end, err := readQuoted(query, i)
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, value)
+			i = end
+		} else {
+			start := i
+			for i < len(query) && query[i] != ',' && query[i] != ')' {
+				i++
+			}
+			item := strings.TrimSpace(query[start:i])
+			if item == "" {
+				return nil, 0, errorAt(start, "empty list item")
+			}
+			items = append(items, item)
+		}
+
+		for i < len(query) && unicode.IsSpace(rune(query[i])) {
+			i++
+		}
+		if i >= len(query) {
+			return nil, 0, errorAt(pos, "unterminated list")
+		}
+		switch query[i] {
+		case ',':
+			i++
+		case ')':
+			return items, i + 1, nil
+		default:
+			return nil, 0, errorAt(i, "expected , or ) in list")
+		}
+	}
+}
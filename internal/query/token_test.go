@@ -0,0 +1,98 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenizeQuotingAndEscaping(t *testing.T) {
+	tests := []struct {
+		query string
+		value string
+	}{
+		{`title:"weekly review"`, "weekly review"},
+		{`title:'weekly review'`, "weekly review"},
+		{`title:"say \"hi\""`, `say "hi"`},
+		{`title:'it\'s'`, "it's"},
+		{`title:"back\\slash"`, `back\slash`},
+		{`title~"^\d+ (a|b)"`, `^\d+ (a|b)`},
+		{`title:"a:b AND c"`, "a:b AND c"},
+		{`title:""`, ""},
+		{`tag:client-*`, "client-*"},
+	}
+
+	for _, tt := range tests {
+		tokens, err := Tokenize(tt.query)
+		if err != nil {
+			t.Errorf("Tokenize(%q): %v", tt.query, err)
+			continue
+		}
+		if len(tokens) != 4 || tokens[2].Type != TokenValue || tokens[2].Value != tt.value {
+			t.Errorf("Tokenize(%q) = %v, want value %q", tt.query, tokens, tt.value)
+		}
+	}
+}
+
+func TestTokenizeOperators(t *testing.T) {
+	tokens, err := Tokenize(`title~x AND title!~y AND due>=today AND due<=eom`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []TokenType
+	for _, tok := range tokens {
+		types = append(types, tok.Type)
+	}
+	want := []TokenType{
+		TokenField, TokenMatch, TokenValue, TokenAND,
+		TokenField, TokenNotMatch, TokenValue, TokenAND,
+		TokenField, TokenGE, TokenValue, TokenAND,
+		TokenField, TokenLE, TokenValue, TokenEOF,
+	}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("token types = %v, want %v", types, want)
+	}
+}
+
+func TestTokenizeLists(t *testing.T) {
+	tests := []struct {
+		query string
+		items []string
+	}{
+		{`priority:(p1,p2)`, []string{"p1", "p2"}},
+		{`priority:( p1 , p2 )`, []string{"p1", "p2"}},
+		{`title:("a, b", 'c)', d)`, []string{"a, b", "c)", "d"}},
+		{`area:(work)`, []string{"work"}},
+	}
+
+	for _, tt := range tests {
+		tokens, err := Tokenize(tt.query)
+		if err != nil {
+			t.Errorf("Tokenize(%q): %v", tt.query, err)
+			continue
+		}
+		if tokens[2].Type != TokenList || !reflect.DeepEqual(tokens[2].Items, tt.items) {
+			t.Errorf("Tokenize(%q) list = %q, want %q", tt.query, tokens[2].Items, tt.items)
+		}
+	}
+
+	// Parentheses that don't follow an operator still group expressions
+	tokens, err := Tokenize(`(status:open)`)
+	if err != nil || tokens[0].Type != TokenLeftParen {
+		t.Errorf("Tokenize grouping = %v, %v", tokens, err)
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	for _, query := range []string{
+		`title:"unterminated`,
+		`title:'unterminated\'`,
+		`priority:(p1,p2`,
+		`priority:(p1,,p2)`,
+		`priority:()`,
+		`priority:("p1" p2)`,
+	} {
+		if _, err := Tokenize(query); err == nil {
+			t.Errorf("Tokenize(%q) succeeded, want error", query)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package recurrence
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ordinalWords are the spelled-out ordinals accepted in place of 1st..5th.
+var ordinalWords = map[string]int{
+	"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5, "last": -1,
+}
+
+var (
+	// every 2nd tuesday, every last fri of the month
+	nthWeekdayRe = regexp.MustCompile(`^every (\w+) (\w+)(?: of (?:the |each |every )?month)?$`)
+	// monthly on the 15th, every month on the last day
+	monthDayRe = regexp.MustCompile(`^(?:monthly|every month) on (?:the )?(\w+)(?: day)?$`)
+	// every 3 months on the 1st
+	monthsOnRe = regexp.MustCompile(`^every (\d+) months? on (?:the )?(\w+)(?: day)?$`)
+)
+
+// parseNatural recognizes the natural-language patterns and returns their
+// canonical form and rule. An empty canonical form with a nil error means
+// pattern is not one of them. pattern must already be lowercased and
+// trimmed.
+func parseNatural(pattern string) (string, *Rule, error) {
+	pattern = strings.Join(strings.Fields(pattern), " ")
+	rule := &Rule{Interval: 1, WeekStart: time.Monday, Freq: Monthly}
+
+	switch pattern {
+	case "every weekday", "every weekdays", "weekdays":
+		rule.Freq = Weekly
+		for wd := time.Monday; wd <= time.Friday; wd++ {
+			rule.ByDay = append(rule.ByDay, WeekdayNum{Weekday: wd})
+		}
+		return "every weekday", rule, nil
+	case "every quarter", "quarterly":
+		rule.Interval = 3
+		return "every quarter", rule, nil
+	case "every last day of month", "every last day of the month", "last day of month", "last day of the month":
+		rule.ByMonthDay = []int{-1}
+		return "every last day of month", rule, nil
+	}
+
+	if m := monthsOnRe.FindStringSubmatch(pattern); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n <= 0 {
+			return "", nil, fmt.Errorf("invalid recurrence interval: %s (must be positive)", m[1])
+		}
+		day, err := parseMonthDay(m[2])
+		if err != nil {
+			return "", nil, err
+		}
+		rule.Interval = n
+		rule.ByMonthDay = []int{day}
+		if n == 1 {
+			return "monthly on the " + monthDayName(day), rule, nil
+		}
+		return fmt.Sprintf("every %d months on the %s", n, monthDayName(day)), rule, nil
+	}
+
+	if m := monthDayRe.FindStringSubmatch(pattern); m != nil {
+		day, err := parseMonthDay(m[1])
+		if err != nil {
+			return "", nil, err
+		}
+		rule.ByMonthDay = []int{day}
+		return "monthly on the " + monthDayName(day), rule, nil
+	}
+
+	if m := nthWeekdayRe.FindStringSubmatch(pattern); m != nil {
+		n, ok := parseOrdinal(m[1])
+		wd, isDay := weekdayNames[m[2]]
+		if ok && isDay {
+			if n < -1 || n == 0 || n > 5 {
+				return "", nil, fmt.Errorf("invalid recurrence pattern: %q (a month has at most five of each weekday)", pattern)
+			}
+			rule.ByDay = []WeekdayNum{{N: n, Weekday: wd}}
+			name := "last"
+			if n > 0 {
+				name = ordinal(n)
+			}
+			return fmt.Sprintf("every %s %s", name, strings.ToLower(wd.String())), rule, nil
+		}
+	}
+
+	return "", nil, nil
+}
+
+// parseOrdinal reads "2nd", "second" or "last"; "last" is -1.
+func parseOrdinal(s string) (int, bool) {
+	if n, ok := ordinalWords[s]; ok {
+		return n, true
+	}
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// parseMonthDay reads a day of the month such as "15th", "15" or "last".
+func parseMonthDay(s string) (int, error) {
+	n, ok := parseOrdinal(s)
+	if !ok {
+		var err error
+		if n, err = strconv.Atoi(s); err != nil {
+			return 0, fmt.Errorf("invalid day of month: %q", s)
+		}
+	}
+	if n == -1 || (n >= 1 && n <= 31) {
+		return n, nil
+	}
+	return 0, fmt.Errorf("invalid day of month: %q (must be 1-31 or last)", s)
+}
+
+// monthDayName is the canonical spelling of a day of the month.
+func monthDayName(day int) string {
+	if day == -1 {
+		return "last day"
+	}
+	return ordinal(day)
+}
+
+// ordinal spells n as 1st, 2nd, 3rd, 4th, ... 11th, 12th, 13th, 21st.
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
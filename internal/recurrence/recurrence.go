@@ -32,7 +32,62 @@ var weekdayNames = map[string]time.Weekday{
 //   - daily, weekly, monthly, yearly
 //   - every <N>d, every <N>w, every <N>m, every <N>y
 //   - every monday, every mon,wed,fri
+//   - every weekday, every quarter, every last day of month
+//   - every 2nd tuesday, every last friday
+//   - monthly on the 15th, every 3 months on the 1st
+//   - after <N>d, after <N>w, after <N>m, after <N>y, counted from the
+//     date the task is completed rather than its due date
+//   - RFC 5545 rules such as RRULE:FREQ=MONTHLY;BYDAY=-1FR, normalized to
+//     canonical RRULE form
+//
+// Shorthand patterns may end with until:YYYY-MM-DD or count:N to limit
+// the series, as in "every 2w count:6".
+//
+// Two modifiers follow the pattern, before any limit. "workdays" counts a
+// day interval in workdays ("every 5d workdays"), and "shift to next
+// workday" moves a date that falls on a weekend or holiday forward
+// ("monthly, shift to next workday"); the shift also applies to RRULEs.
+// Workdays and holidays come from the calendar set with SetCalendar.
+//
+// The shorthand patterns are kept as written and map onto rules internally.
+// Natural-language patterns are normalized to one spelling, so "quarterly"
+// is stored as "every quarter" and "every second tue" as "every 2nd
+// tuesday".
 func ParsePattern(pattern string) (string, error) {
+	base, until, count, err := splitLimits(pattern)
+	if err != nil {
+		return "", err
+	}
+	base, workdays, shift := splitModifiers(base)
+
+	if isRRule(base) {
+		if count > 0 || !until.IsZero() {
+			return "", fmt.Errorf("use COUNT= or UNTIL= inside an RRULE instead of count: or until:")
+		}
+		if workdays {
+			return "", fmt.Errorf("workdays cannot modify an RRULE (use every <N>d workdays)")
+		}
+		rule, err := ParseRRule(base)
+		if err != nil {
+			return "", err
+		}
+		return withModifiers(rule.String(), false, shift), nil
+	}
+
+	normalized, err := parseShorthand(base)
+	if err != nil {
+		return "", err
+	}
+	if workdays {
+		if err := checkWorkdays(normalized); err != nil {
+			return "", err
+		}
+	}
+	return withLimits(withModifiers(normalized, workdays, shift), until, count), nil
+}
+
+// parseShorthand normalizes a shorthand pattern without its limits.
+func parseShorthand(pattern string) (string, error) {
 	pattern = strings.TrimSpace(strings.ToLower(pattern))
 	if pattern == "" {
 		return "", fmt.Errorf("empty recurrence pattern")
@@ -43,8 +98,24 @@ func ParsePattern(pattern string) (string, error) {
 		return pattern, nil
 	}
 
+	if canonical, _, err := parseNatural(pattern); err != nil || canonical != "" {
+		return canonical, err
+	}
+
+	// Completion-based pattern: after <N>d/w/m/y
+	if strings.HasPrefix(pattern, "after ") {
+		n, unit, ok := parseInterval(strings.TrimSpace(pattern[6:]))
+		if !ok {
+			return "", fmt.Errorf("invalid recurrence pattern: %q (expected after <N>d, <N>w, <N>m or <N>y)", pattern)
+		}
+		if n <= 0 {
+			return "", fmt.Errorf("invalid recurrence interval: %d (must be positive)", n)
+		}
+		return fmt.Sprintf("after %d%c", n, unit), nil
+	}
+
 	if !strings.HasPrefix(pattern, "every ") {
-		return "", fmt.Errorf("invalid recurrence pattern: %q (expected daily, weekly, monthly, yearly, or every ...)", pattern)
+		return "", fmt.Errorf("invalid recurrence pattern: %q (expected daily, weekly, monthly, yearly, every ..., after ..., or RRULE:...)", pattern)
 	}
 
 	spec := strings.TrimSpace(pattern[6:])
@@ -53,18 +124,11 @@ func ParsePattern(pattern string) (string, error) {
 	}
 
 	// Try interval+unit pattern: every <N>d/w/m/y
-	if len(spec) >= 2 {
-		unit := spec[len(spec)-1]
-		numStr := spec[:len(spec)-1]
-		if unit == 'd' || unit == 'w' || unit == 'm' || unit == 'y' {
-			n, err := strconv.Atoi(numStr)
-			if err == nil {
-				if n <= 0 {
-					return "", fmt.Errorf("invalid recurrence interval: %d (must be positive)", n)
-				}
-				return fmt.Sprintf("every %d%c", n, unit), nil
-			}
+	if n, unit, ok := parseInterval(spec); ok {
+		if n <= 0 {
+			return "", fmt.Errorf("invalid recurrence interval: %d (must be positive)", n)
 		}
+		return fmt.Sprintf("every %d%c", n, unit), nil
 	}
 
 	// Try day-of-week pattern: every mon,wed,fri
@@ -81,118 +145,263 @@ func ParsePattern(pattern string) (string, error) {
 	return "every " + strings.Join(days, ","), nil
 }
 
-// NextDueDate computes the next due date based on a recurrence pattern and the current due date.
-// It always advances past today so late completions still get a future date.
-func NextDueDate(pattern string, currentDue time.Time) (time.Time, error) {
-	pattern = strings.TrimSpace(strings.ToLower(pattern))
-	today := time.Now()
-	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+// splitLimits takes the trailing until:YYYY-MM-DD and count:N limits off
+// a pattern.
+func splitLimits(pattern string) (string, time.Time, int, error) {
+	var until time.Time
+	var count int
+	fields := strings.Fields(pattern)
+	for len(fields) > 0 {
+		name, value, ok := strings.Cut(strings.ToLower(fields[len(fields)-1]), ":")
+		if !ok || (name != "until" && name != "count") {
+			break
+		}
+		if name == "until" {
+			if !until.IsZero() {
+				return "", time.Time{}, 0, fmt.Errorf("duplicate until: limit")
+			}
+			t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+			if err != nil {
+				return "", time.Time{}, 0, fmt.Errorf("invalid until: date %q (use YYYY-MM-DD)", value)
+			}
+			until = t
+		} else {
+			if count > 0 {
+				return "", time.Time{}, 0, fmt.Errorf("duplicate count: limit")
+			}
+			n, err := positiveInt("count:", value)
+			if err != nil {
+				return "", time.Time{}, 0, err
+			}
+			count = n
+		}
+		fields = fields[:len(fields)-1]
+	}
+	if count > 0 && !until.IsZero() {
+		return "", time.Time{}, 0, fmt.Errorf("a recurrence can have count: or until:, not both")
+	}
+	return strings.TrimRight(strings.Join(fields, " "), ","), until, count, nil
+}
 
-	var next time.Time
-	var err error
+// withLimits appends the until: or count: limit to a normalized pattern.
+func withLimits(pattern string, until time.Time, count int) string {
+	if !until.IsZero() {
+		pattern += " until:" + until.Format("2006-01-02")
+	}
+	if count > 0 {
+		pattern += fmt.Sprintf(" count:%d", count)
+	}
+	return pattern
+}
 
-	switch pattern {
+// ParseRule returns the rule a pattern stands for, whether it is written as
+// an RRULE or in shorthand.
+func ParseRule(pattern string) (*Rule, error) {
+	normalized, err := ParsePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	base, until, count, _ := splitLimits(normalized)
+	base, workdays, shift := splitModifiers(base)
+	var rule *Rule
+	if isRRule(base) {
+		if rule, err = ParseRRule(base); err != nil {
+			return nil, err
+		}
+	} else {
+		rule = shorthandRule(base)
+		rule.Until, rule.Count = until, count
+	}
+	rule.workdays, rule.shift = workdays, shift
+	return rule, nil
+}
+
+// shorthandRule returns the rule for a normalized shorthand pattern
+// without limits.
+func shorthandRule(normalized string) *Rule {
+	if _, rule, _ := parseNatural(normalized); rule != nil {
+		return rule
+	}
+	rule := &Rule{Interval: 1, WeekStart: time.Monday}
+
+	switch normalized {
 	case "daily":
-		next = advanceByInterval(currentDue, 1, 'd', today)
+		rule.Freq = Daily
+		return rule
 	case "weekly":
-		next = advanceByInterval(currentDue, 1, 'w', today)
+		rule.Freq = Weekly
+		return rule
 	case "monthly":
-		next = advanceByInterval(currentDue, 1, 'm', today)
+		rule.Freq = Monthly
+		return rule
 	case "yearly":
-		next = advanceByInterval(currentDue, 1, 'y', today)
-	default:
-		if strings.HasPrefix(pattern, "every ") {
-			spec := strings.TrimSpace(pattern[6:])
-			next, err = parseEverySpec(spec, currentDue, today)
-			if err != nil {
-				return time.Time{}, err
-			}
-		} else {
-			return time.Time{}, fmt.Errorf("invalid recurrence pattern: %q", pattern)
-		}
+		rule.Freq = Yearly
+		return rule
+	}
+
+	spec := strings.TrimPrefix(normalized, "every ")
+	if after, ok := strings.CutPrefix(normalized, "after "); ok {
+		spec = after
+		rule.fromCompletion = true
+	}
+	if n, unit, ok := parseInterval(spec); ok {
+		rule.Interval = n
+		rule.Freq = map[byte]Frequency{'d': Daily, 'w': Weekly, 'm': Monthly, 'y': Yearly}[unit]
+		return rule
 	}
 
-	return next, nil
+	rule.Freq = Weekly
+	for _, p := range strings.Split(spec, ",") {
+		rule.ByDay = append(rule.ByDay, WeekdayNum{Weekday: weekdayNames[p]})
+	}
+	return rule
 }
 
-func parseEverySpec(spec string, currentDue, today time.Time) (time.Time, error) {
-	// Try interval+unit
-	if len(spec) >= 2 {
-		unit := spec[len(spec)-1]
-		numStr := spec[:len(spec)-1]
-		if unit == 'd' || unit == 'w' || unit == 'm' || unit == 'y' {
-			n, err := strconv.Atoi(numStr)
-			if err == nil && n > 0 {
-				return advanceByInterval(currentDue, n, unit, today), nil
-			}
-		}
+// ToRRule returns a pattern in canonical RRULE form, for export to
+// iCalendar.
+func ToRRule(pattern string) (string, error) {
+	rule, err := ParseRule(pattern)
+	if err != nil {
+		return "", err
+	}
+	if rule.fromCompletion {
+		return "", fmt.Errorf("%q repeats from the completion date and has no RRULE equivalent", pattern)
 	}
+	if rule.workdays || rule.shift {
+		return "", fmt.Errorf("%q depends on the workday calendar and has no RRULE equivalent", pattern)
+	}
+	return rule.String(), nil
+}
 
-	// Day-of-week pattern
-	parts := strings.Split(spec, ",")
-	var weekdays []time.Weekday
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		wd, ok := weekdayNames[p]
-		if !ok {
-			return time.Time{}, fmt.Errorf("unknown day: %q", p)
-		}
-		weekdays = append(weekdays, wd)
+// parseInterval splits an interval spec such as "2w" into its count and
+// unit.
+func parseInterval(spec string) (int, byte, bool) {
+	if len(spec) < 2 {
+		return 0, 0, false
 	}
+	unit := spec[len(spec)-1]
+	if unit != 'd' && unit != 'w' && unit != 'm' && unit != 'y' {
+		return 0, 0, false
+	}
+	n, err := strconv.Atoi(spec[:len(spec)-1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return n, unit, true
+}
 
-	if len(weekdays) == 0 {
-		return time.Time{}, fmt.Errorf("no weekdays specified")
+// NextDueDate computes the next due date based on a recurrence pattern and the current due date.
+// It always advances past today so late completions still get a future date.
+// Completion-based patterns count from currentDue, as if the task were
+// completed on time. It returns ErrSeriesEnded if the pattern's count or
+// until limit allows no further occurrence.
+func NextDueDate(pattern string, currentDue time.Time) (time.Time, error) {
+	next, err := NextInstance(pattern, currentDue, currentDue)
+	return next.Due, err
+}
+
+// Instance is the next occurrence in a series.
+type Instance struct {
+	// Due is the date the new task is due.
+	Due time.Time
+	// Scheduled is the date the pattern picked, before "shift to next
+	// workday" moved it. Later occurrences are computed from it, so a
+	// monthly task shifted off a weekend does not drift.
+	Scheduled time.Time
+	// Pattern is the pattern the new task should carry: the same pattern,
+	// except that one with a count has one fewer occurrence left.
+	Pattern string
+}
+
+// NextInstance is NextDueDate for creating the next task in a series once
+// the current one is completed. currentDue is the date the current task
+// was scheduled for, before any shift to a workday. Completion-based
+// patterns ("after 3d") count from completed rather than currentDue.
+func NextInstance(pattern string, currentDue, completed time.Time) (Instance, error) {
+	return NextInstanceAt(pattern, currentDue, completed, time.Now())
+}
+
+// NextInstanceAt is NextInstance with today given as a parameter, for
+// projecting a series as of a date other than the current one. Occurrences
+// before today are skipped, as NextInstance skips those already past.
+func NextInstanceAt(pattern string, currentDue, completed, today time.Time) (Instance, error) {
+	rule, err := ParseRule(pattern)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+
+	start := currentDue
+	if rule.fromCompletion {
+		start = time.Date(completed.Year(), completed.Month(), completed.Day(), 0, 0, 0, 0, currentDue.Location())
+	}
+	// A series with a count uses up an occurrence for every date skipped
+	// while catching up to today, so it is walked one occurrence at a time.
+	counted := rule.Count > 0
+	catchUp := today
+	if counted {
+		catchUp = start
+	}
+	next, err := rule.next(start, catchUp)
+	if err != nil {
+		return Instance{}, err
+	}
+	for counted {
+		rule.Count--
+		if !next.Before(today) {
+			break
+		}
+		if next, err = rule.next(next, next); err != nil {
+			return Instance{}, err
+		}
 	}
 
-	return nextMatchingWeekday(currentDue, weekdays, today), nil
+	inst := Instance{Due: next, Scheduled: next, Pattern: pattern}
+	if rule.shift {
+		inst.Due = workCalendar.NextWorkday(next)
+	}
+	if counted {
+		normalized, _ := ParsePattern(pattern)
+		base, _, _, _ := splitLimits(normalized)
+		if isRRule(base) {
+			inst.Pattern = withModifiers(rule.String(), false, rule.shift)
+		} else {
+			inst.Pattern = withLimits(base, time.Time{}, rule.Count)
+		}
+	}
+	return inst, nil
 }
 
 // advanceByInterval advances from currentDue by the given interval,
-// repeating until the result is strictly after today.
+// repeating until the result is not before today. Monthly and yearly steps
+// clamp to the end of shorter months (Jan 31 plus one month is Feb 28) and
+// are counted from currentDue, so catching up does not drift off the 31st.
 func advanceByInterval(currentDue time.Time, n int, unit byte, today time.Time) time.Time {
-	next := currentDue
-	for {
+	for step := 1; ; step++ {
+		var next time.Time
 		switch unit {
 		case 'd':
-			next = next.AddDate(0, 0, n)
+			next = currentDue.AddDate(0, 0, n*step)
 		case 'w':
-			next = next.AddDate(0, 0, n*7)
+			next = currentDue.AddDate(0, 0, n*7*step)
 		case 'm':
-			next = next.AddDate(0, n, 0)
+			next = addMonths(currentDue, n*step)
 		case 'y':
-			next = next.AddDate(n, 0, 0)
+			next = addMonths(currentDue, 12*n*step)
 		}
 		if !next.Before(today) {
-			break
+			return next
 		}
 	}
-	return next
 }
 
-// nextMatchingWeekday finds the next date after currentDue that falls on one of the given weekdays,
-// ensuring it's not before today.
-func nextMatchingWeekday(currentDue time.Time, weekdays []time.Weekday, today time.Time) time.Time {
-	// Build a set for fast lookup
-	daySet := make(map[time.Weekday]bool, len(weekdays))
-	for _, wd := range weekdays {
-		daySet[wd] = true
-	}
-
-	// Start from the day after currentDue
-	candidate := currentDue.AddDate(0, 0, 1)
-	// But if candidate is before today, start from today
-	if candidate.Before(today) {
-		candidate = today
-	}
-
-	// Search up to 7 days (guaranteed to find a match)
-	for i := 0; i < 7; i++ {
-		if daySet[candidate.Weekday()] {
-			return candidate
-		}
-		candidate = candidate.AddDate(0, 0, 1)
-	}
-
-	// Should never reach here if weekdays is non-empty
-	return candidate
+// addMonths adds months to t, keeping the day of the month where the
+// target month has it and using the month's last day otherwise.
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1,
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	day := min(t.Day(), daysIn(first.Year(), first.Month()))
+	return first.AddDate(0, 0, day-1)
 }
@@ -1,8 +1,11 @@
 package recurrence
 
 import (
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/calendar"
 )
 
 func TestParsePattern(t *testing.T) {
@@ -157,3 +160,459 @@ func TestNextDueDateInvalidPattern(t *testing.T) {
 		t.Error("expected error for invalid pattern")
 	}
 }
+
+func TestParsePatternRRule(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"RRULE:FREQ=MONTHLY;BYDAY=-1FR", "RRULE:FREQ=MONTHLY;BYDAY=-1FR", false},
+		{"rrule:freq=monthly;byday=2tu", "RRULE:FREQ=MONTHLY;BYDAY=2TU", false},
+		{"FREQ=WEEKLY;BYDAY=MO,TH;INTERVAL=2", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", false},
+		{"RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", "RRULE:FREQ=YEARLY;BYDAY=4TH;BYMONTH=11", false},
+		{"RRULE:FREQ=MONTHLY;BYMONTHDAY=1,-1;COUNT=6", "RRULE:FREQ=MONTHLY;BYMONTHDAY=1,-1;COUNT=6", false},
+		{"RRULE:FREQ=DAILY;INTERVAL=1;UNTIL=20991231T235959Z", "RRULE:FREQ=DAILY;UNTIL=20991231", false},
+		{"RRULE:FREQ=WEEKLY;WKST=SU", "RRULE:FREQ=WEEKLY;WKST=SU", false},
+
+		{"RRULE:", "", true},
+		{"RRULE:BYDAY=MO", "", true},
+		{"RRULE:FREQ=HOURLY", "", true},
+		{"RRULE:FREQ=WEEKLY;BYDAY=2TU", "", true},
+		{"RRULE:FREQ=MONTHLY;BYDAY=XX", "", true},
+		{"RRULE:FREQ=MONTHLY;BYMONTHDAY=32", "", true},
+		{"RRULE:FREQ=YEARLY;BYMONTH=13", "", true},
+		{"RRULE:FREQ=DAILY;COUNT=0", "", true},
+		{"RRULE:FREQ=DAILY;COUNT=2;UNTIL=20990101", "", true},
+		{"RRULE:FREQ=DAILY;BYSETPOS=1", "", true},
+		{"RRULE:FREQ=DAILY;FREQ=WEEKLY", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePattern(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePattern(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParsePattern(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToRRule(t *testing.T) {
+	tests := map[string]string{
+		"daily":                     "RRULE:FREQ=DAILY",
+		"monthly":                   "RRULE:FREQ=MONTHLY",
+		"every 2w":                  "RRULE:FREQ=WEEKLY;INTERVAL=2",
+		"every 3m":                  "RRULE:FREQ=MONTHLY;INTERVAL=3",
+		"every mon,wed,fri":         "RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR",
+		"every tuesday":             "RRULE:FREQ=WEEKLY;BYDAY=TU",
+		"every weekday":             "RRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		"every 2nd tuesday":         "RRULE:FREQ=MONTHLY;BYDAY=2TU",
+		"every last day of month":   "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1",
+		"monthly on the 15th":       "RRULE:FREQ=MONTHLY;BYMONTHDAY=15",
+		"every quarter":             "RRULE:FREQ=MONTHLY;INTERVAL=3",
+		"every 3 months on the 1st": "RRULE:FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1",
+	}
+	for pattern, want := range tests {
+		got, err := ToRRule(pattern)
+		if err != nil {
+			t.Errorf("ToRRule(%q) error = %v", pattern, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ToRRule(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestNextDueDateRRule(t *testing.T) {
+	// Dates far in the future so "today" never interferes; 2099-01-05 is a Monday
+	date := func(y, m, d int) time.Time {
+		return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		pattern    string
+		currentDue time.Time
+		want       time.Time
+	}{
+		{"RRULE:FREQ=MONTHLY;BYDAY=-1FR", date(2099, 1, 1), date(2099, 1, 30)},
+		{"RRULE:FREQ=MONTHLY;BYDAY=-1FR", date(2099, 1, 30), date(2099, 2, 27)},
+		{"RRULE:FREQ=MONTHLY;BYDAY=2TU", date(2099, 1, 1), date(2099, 1, 13)},
+		{"RRULE:FREQ=MONTHLY;BYMONTHDAY=-1", date(2099, 1, 31), date(2099, 2, 28)},
+		{"RRULE:FREQ=MONTHLY;BYMONTHDAY=1,15", date(2099, 1, 1), date(2099, 1, 15)},
+		{"RRULE:FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=10", date(2099, 1, 10), date(2099, 3, 10)},
+		{"RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", date(2099, 1, 1), date(2099, 11, 26)},
+		{"RRULE:FREQ=YEARLY;BYDAY=1MO", date(2099, 1, 5), date(2100, 1, 4)},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", date(2099, 1, 5), date(2099, 1, 8)},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", date(2099, 1, 8), date(2099, 1, 19)},
+		{"RRULE:FREQ=WEEKLY;BYMONTH=3", date(2099, 1, 5), date(2099, 3, 2)},
+		{"RRULE:FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", date(2099, 1, 9), date(2099, 1, 12)},
+		{"RRULE:FREQ=DAILY;INTERVAL=3", date(2099, 1, 1), date(2099, 1, 4)},
+		{"RRULE:FREQ=YEARLY", date(2099, 6, 15), date(2100, 6, 15)},
+		{"RRULE:FREQ=WEEKLY;UNTIL=20990112", date(2099, 1, 5), date(2099, 1, 12)},
+		{"every mon,wed,fri", date(2099, 1, 9), date(2099, 1, 12)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" from "+tt.currentDue.Format("2006-01-02"), func(t *testing.T) {
+			got, err := NextDueDate(tt.pattern, tt.currentDue)
+			if err != nil {
+				t.Fatalf("NextDueDate error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("NextDueDate = %s, want %s", got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestNextInstanceSeriesLimits(t *testing.T) {
+	monday := time.Date(2099, 1, 5, 0, 0, 0, 0, time.Local)
+
+	next, err := NextInstance("RRULE:FREQ=WEEKLY;COUNT=3", monday, monday)
+	if err != nil {
+		t.Fatalf("NextInstance error = %v", err)
+	}
+	if pattern := next.Pattern; pattern != "RRULE:FREQ=WEEKLY;COUNT=2" {
+		t.Errorf("next pattern = %q, want COUNT=2", pattern)
+	}
+
+	if _, err := NextDueDate("RRULE:FREQ=WEEKLY;COUNT=1", monday); err != ErrSeriesEnded {
+		t.Errorf("COUNT=1: error = %v, want ErrSeriesEnded", err)
+	}
+	if _, err := NextDueDate("RRULE:FREQ=WEEKLY;UNTIL=20990111", monday); err != ErrSeriesEnded {
+		t.Errorf("past UNTIL: error = %v, want ErrSeriesEnded", err)
+	}
+	if _, err := NextDueDate("RRULE:FREQ=MONTHLY;BYDAY=1MO;UNTIL=20990201", monday); err != ErrSeriesEnded {
+		t.Errorf("past UNTIL with BYDAY: error = %v, want ErrSeriesEnded", err)
+	}
+	if _, err := NextDueDate("RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", monday); err == nil || err == ErrSeriesEnded {
+		t.Errorf("impossible rule: error = %v, want a never-matches error", err)
+	}
+}
+
+func TestParsePatternNatural(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"every weekday", "every weekday", false},
+		{"Weekdays", "every weekday", false},
+		{"every 2nd tuesday", "every 2nd tuesday", false},
+		{"every second tue", "every 2nd tuesday", false},
+		{"every 1st mon of the month", "every 1st monday", false},
+		{"every last fri", "every last friday", false},
+		{"every last day of month", "every last day of month", false},
+		{"every last day of the month", "every last day of month", false},
+		{"monthly on the 15th", "monthly on the 15th", false},
+		{"monthly on 15", "monthly on the 15th", false},
+		{"every month on the 22nd", "monthly on the 22nd", false},
+		{"monthly on the last day", "monthly on the last day", false},
+		{"every quarter", "every quarter", false},
+		{"quarterly", "every quarter", false},
+		{"every 3 months on the 1st", "every 3 months on the 1st", false},
+		{"every  3  months on 1", "every 3 months on the 1st", false},
+		{"every 1 month on the 3rd", "monthly on the 3rd", false},
+
+		{"every 6th tuesday", "", true},
+		{"monthly on the 32nd", "", true},
+		{"every 0 months on the 1st", "", true},
+		{"every 3 months on the fifteenth day", "", true},
+		{"every 2nd funday", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePattern(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePattern(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParsePattern(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextDueDateNatural(t *testing.T) {
+	// 2099-01-09 is a Friday
+	date := func(y, m, d int) time.Time {
+		return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		pattern    string
+		currentDue time.Time
+		want       time.Time
+	}{
+		{"every weekday", date(2099, 1, 9), date(2099, 1, 12)},
+		{"every weekday", date(2099, 1, 12), date(2099, 1, 13)},
+		{"every 2nd tuesday", date(2099, 1, 1), date(2099, 1, 13)},
+		{"every 2nd tuesday", date(2099, 1, 13), date(2099, 2, 10)},
+		{"every last friday", date(2099, 1, 1), date(2099, 1, 30)},
+		{"every last day of month", date(2099, 1, 31), date(2099, 2, 28)},
+		{"every last day of month", date(2099, 2, 28), date(2099, 3, 31)},
+		{"monthly on the 15th", date(2099, 1, 20), date(2099, 2, 15)},
+		{"every quarter", date(2099, 1, 15), date(2099, 4, 15)},
+		{"every 3 months on the 1st", date(2099, 1, 1), date(2099, 4, 1)},
+		{"every 3 months on the 1st", date(2099, 1, 20), date(2099, 4, 1)},
+
+		// Month-end clamping
+		{"monthly", date(2099, 1, 31), date(2099, 2, 28)},
+		{"every 1m", date(2100, 1, 31), date(2100, 2, 28)},
+		{"every quarter", date(2099, 11, 30), date(2100, 2, 28)},
+		{"every 2m", date(2099, 12, 31), date(2100, 2, 28)},
+		{"monthly", date(2099, 3, 31), date(2099, 4, 30)},
+		{"yearly", date(2096, 2, 29), date(2097, 2, 28)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" from "+tt.currentDue.Format("2006-01-02"), func(t *testing.T) {
+			got, err := NextDueDate(tt.pattern, tt.currentDue)
+			if err != nil {
+				t.Fatalf("NextDueDate error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("NextDueDate = %s, want %s", got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestMonthlyCatchUpKeepsDay(t *testing.T) {
+	// Catching up from a long-overdue Jan 31 lands on a clamped month end,
+	// not on a day that drifted through February
+	today := time.Date(2024, 4, 10, 0, 0, 0, 0, time.Local)
+	got := advanceByInterval(time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local), 1, 'm', today)
+	if want := time.Date(2024, 4, 30, 0, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("advanceByInterval = %s, want %s", got.Format("2006-01-02"), want.Format("2006-01-02"))
+	}
+}
+
+func TestParsePatternLimits(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"after 3d", "after 3d", false},
+		{"After 2W", "after 2w", false},
+		{"weekly until:2099-06-30", "weekly until:2099-06-30", false},
+		{"every 2w count:5", "every 2w count:5", false},
+		{"after 3d COUNT:4", "after 3d count:4", false},
+		{"quarterly, until:2100-01-01", "every quarter until:2100-01-01", false},
+
+		{"after", "", true},
+		{"after 0d", "", true},
+		{"after tuesday", "", true},
+		{"weekly count:0", "", true},
+		{"weekly until:2099-13-01", "", true},
+		{"weekly count:2 until:2099-01-01", "", true},
+		{"weekly count:2 count:3", "", true},
+		{"RRULE:FREQ=DAILY count:3", "", true},
+		{"count:3", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePattern(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePattern(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParsePattern(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextInstanceFromCompletion(t *testing.T) {
+	due := time.Date(2099, 1, 5, 0, 0, 0, 0, time.Local)
+	completed := time.Date(2099, 1, 9, 18, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		pattern string
+		want    time.Time
+	}{
+		{"after 3d", time.Date(2099, 1, 12, 0, 0, 0, 0, time.Local)},
+		{"after 2w", time.Date(2099, 1, 23, 0, 0, 0, 0, time.Local)},
+		{"after 1m count:3", time.Date(2099, 2, 9, 0, 0, 0, 0, time.Local)},
+		{"every 3d", time.Date(2099, 1, 8, 0, 0, 0, 0, time.Local)}, // fixed schedule ignores completion
+	}
+	for _, tt := range tests {
+		next, err := NextInstance(tt.pattern, due, completed)
+		if err != nil {
+			t.Errorf("NextInstance(%q) error = %v", tt.pattern, err)
+			continue
+		}
+		if got := next.Due; !got.Equal(tt.want) {
+			t.Errorf("NextInstance(%q) = %s, want %s", tt.pattern, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+
+	if _, err := ToRRule("after 3d"); err == nil {
+		t.Error("ToRRule(after 3d) should fail: completion-based patterns have no RRULE form")
+	}
+}
+
+func TestNextInstanceShorthandLimits(t *testing.T) {
+	monday := time.Date(2099, 1, 5, 0, 0, 0, 0, time.Local)
+
+	next, err := NextInstance("every 2w count:3", monday, monday)
+	if err != nil {
+		t.Fatalf("NextInstance error = %v", err)
+	}
+	if next.Pattern != "every 2w count:2" || !next.Due.Equal(monday.AddDate(0, 0, 14)) {
+		t.Errorf("NextInstance = %s, %q; want 2099-01-19, \"every 2w count:2\"", next.Due.Format("2006-01-02"), next.Pattern)
+	}
+	if next, _ := NextInstance("weekly until:2099-02-01", monday, monday); next.Pattern != "weekly until:2099-02-01" {
+		t.Errorf("until: pattern changed to %q", next.Pattern)
+	}
+
+	if _, err := NextDueDate("every 2w count:1", monday); err != ErrSeriesEnded {
+		t.Errorf("count:1: error = %v, want ErrSeriesEnded", err)
+	}
+	if _, err := NextDueDate("weekly until:2099-01-11", monday); err != ErrSeriesEnded {
+		t.Errorf("past until: error = %v, want ErrSeriesEnded", err)
+	}
+	if _, err := NextInstance("after 3d until:2099-01-10", monday, monday.AddDate(0, 0, 8)); err != ErrSeriesEnded {
+		t.Errorf("after past until: error = %v, want ErrSeriesEnded", err)
+	}
+	if got, err := NextDueDate("every 2nd tuesday until:2099-02-28", monday); err != nil || got.Day() != 13 {
+		t.Errorf("every 2nd tuesday until: = %v, %v", got, err)
+	}
+	if rrule, _ := ToRRule("every 2w count:3"); rrule != "RRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=3" {
+		t.Errorf("ToRRule(every 2w count:3) = %q", rrule)
+	}
+}
+
+func TestNextInstanceCountsSkippedOccurrences(t *testing.T) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	// Due five days ago: the two remaining occurrences were both missed
+	if _, err := NextInstance("daily count:3", today.AddDate(0, 0, -5), today); err != ErrSeriesEnded {
+		t.Errorf("daily count:3 five days overdue: error = %v, want ErrSeriesEnded", err)
+	}
+
+	// Due three days ago: two occurrences missed, today's is the last
+	next, err := NextInstance("daily count:4", today.AddDate(0, 0, -3), today)
+	if err != nil || !next.Due.Equal(today) || next.Pattern != "daily count:1" {
+		t.Errorf("daily count:4 three days overdue = %s, %q, %v; want today, \"daily count:1\"",
+			next.Due.Format("2006-01-02"), next.Pattern, err)
+	}
+	next, err = NextInstance("RRULE:FREQ=DAILY;COUNT=5", today.AddDate(0, 0, -2), today)
+	if err != nil || !next.Due.Equal(today) || next.Pattern != "RRULE:FREQ=DAILY;COUNT=3" {
+		t.Errorf("COUNT=5 two days overdue = %s, %q, %v; want today, COUNT=3",
+			next.Due.Format("2006-01-02"), next.Pattern, err)
+	}
+
+	// Without a count, catching up skips straight to today
+	if next, err := NextInstance("daily", today.AddDate(0, 0, -5), today); err != nil || !next.Due.Equal(today) {
+		t.Errorf("daily five days overdue = %s, %v; want today", next.Due.Format("2006-01-02"), err)
+	}
+}
+
+func TestNextInstanceAt(t *testing.T) {
+	date := func(y, m, d int) time.Time { return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local) }
+	today := date(2024, 3, 4)
+
+	// Catching up stops at the given today, not the real one
+	if next, err := NextInstanceAt("daily", date(2024, 2, 28), date(2024, 2, 28), today); err != nil || !next.Due.Equal(today) {
+		t.Errorf("daily from 2024-02-28 = %s, %v; want 2024-03-04", next.Due.Format("2006-01-02"), err)
+	}
+	if next, err := NextInstanceAt("weekly", today, today, today); err != nil || !next.Due.Equal(date(2024, 3, 11)) {
+		t.Errorf("weekly from 2024-03-04 = %s, %v; want 2024-03-11", next.Due.Format("2006-01-02"), err)
+	}
+	next, err := NextInstanceAt("daily count:4", date(2024, 3, 1), date(2024, 3, 1), today)
+	if err != nil || !next.Due.Equal(today) || next.Pattern != "daily count:1" {
+		t.Errorf("daily count:4 from 2024-03-01 = %s, %q, %v; want 2024-03-04, \"daily count:1\"",
+			next.Due.Format("2006-01-02"), next.Pattern, err)
+	}
+}
+
+func TestWorkdayModifiers(t *testing.T) {
+	date := func(y, m, d int) time.Time { return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local) }
+	SetCalendar(calendar.New([]time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		[]time.Time{date(2099, 1, 7), date(2099, 3, 2)}))
+	defer SetCalendar(nil)
+
+	patterns := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"every 5d workdays", "every 5d workdays", false},
+		{"After 2d Workdays", "after 2d workdays", false},
+		{"monthly, shift to next workday", "monthly, shift to next workday", false},
+		{"monthly shift to next workday", "monthly, shift to next workday", false},
+		{"every 3d workdays, shift to next workday count:4", "every 3d workdays, shift to next workday count:4", false},
+		{"RRULE:FREQ=MONTHLY;BYMONTHDAY=1, shift to next workday", "RRULE:FREQ=MONTHLY;BYMONTHDAY=1, shift to next workday", false},
+		{"weekly workdays", "", true},
+		{"every 2w workdays", "", true},
+		{"RRULE:FREQ=DAILY;INTERVAL=5 workdays", "", true},
+	}
+	for _, tt := range patterns {
+		got, err := ParsePattern(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParsePattern(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+		}
+	}
+
+	// 2099-01-05 is a Monday and 2099-01-07 a holiday
+	if got, err := NextDueDate("every 5d workdays", date(2099, 1, 5)); err != nil || !got.Equal(date(2099, 1, 13)) {
+		t.Errorf("every 5d workdays = %s, %v; want 2099-01-13", got.Format("2006-01-02"), err)
+	}
+
+	// 2099-02-01 and 2099-03-01 are Sundays, and 2099-03-02 a holiday
+	next, err := NextInstance("monthly, shift to next workday", date(2099, 1, 1), date(2099, 1, 1))
+	if err != nil || !next.Due.Equal(date(2099, 2, 2)) || !next.Scheduled.Equal(date(2099, 2, 1)) {
+		t.Fatalf("shifted monthly = %+v, %v; want due 2099-02-02 scheduled 2099-02-01", next, err)
+	}
+	next, err = NextInstance(next.Pattern, next.Scheduled, next.Due)
+	if err != nil || !next.Due.Equal(date(2099, 3, 3)) || !next.Scheduled.Equal(date(2099, 3, 1)) {
+		t.Errorf("second shifted monthly = %+v, %v; want due 2099-03-03 scheduled 2099-03-01", next, err)
+	}
+
+	if _, err := ToRRule("monthly, shift to next workday"); err == nil {
+		t.Error("ToRRule should reject the workday modifiers")
+	}
+}
+
+func TestHolidays(t *testing.T) {
+	date := func(y, m, d int) time.Time { return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local) }
+	events := []calendar.Event{
+		{Start: date(2098, 12, 25), Days: 2, RRule: "FREQ=YEARLY"},
+		{Start: date(2098, 11, 27), Days: 1, RRule: "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH"},
+		{Start: date(2099, 5, 1), Days: 1, RRule: "FREQ=MONTHLY;COUNT=2"},
+		{Start: date(2099, 7, 4), Days: 1},
+	}
+
+	days, err := Holidays(events, date(2100, 12, 1))
+	if err != nil {
+		t.Fatalf("Holidays: %v", err)
+	}
+	var got []string
+	for _, d := range days {
+		got = append(got, d.Format("2006-01-02"))
+	}
+	want := "2098-12-25 2098-12-26 2099-12-25 2099-12-26 " +
+		"2098-11-27 2099-11-26 2100-11-25 " +
+		"2099-05-01 2099-06-01 " +
+		"2099-07-04"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Holidays = %v\nwant %s", got, want)
+	}
+
+	if _, err := Holidays([]calendar.Event{{Start: date(2099, 1, 1), Days: 1, RRule: "FREQ=HOURLY"}}, date(2100, 1, 1)); err == nil {
+		t.Error("Holidays should reject an unsupported RRULE")
+	}
+}
@@ -0,0 +1,444 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSeriesEnded is returned when a recurrence has no further occurrences,
+// because its COUNT is used up or the next date would fall after UNTIL.
+var ErrSeriesEnded = errors.New("recurrence has no further occurrences")
+
+// Frequency is the FREQ of a recurrence rule.
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// WeekdayNum is a BYDAY entry: a weekday, optionally limited to the Nth
+// one in the month or year (2TU is the second Tuesday, -1FR the last
+// Friday). N is 0 for every such weekday.
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Rule is a recurrence rule with the subset of RFC 5545 RRULE parts atask
+// supports. The shorthand patterns are parsed into the same form.
+type Rule struct {
+	Freq       Frequency
+	Interval   int // 1 if not set
+	ByDay      []WeekdayNum
+	ByMonthDay []int // negative values count back from the end of the month
+	ByMonth    []time.Month
+	Count      int       // occurrences left in the series, including this one; 0 for no limit
+	Until      time.Time // last date the series may fall on; zero for no limit
+	WeekStart  time.Weekday
+
+	fromCompletion bool // shorthand "after" patterns: count from the completion date
+	workdays       bool // the day interval counts workdays only
+	shift          bool // occurrences off a workday move to the next workday
+}
+
+// rruleDays are the two-letter weekday codes used by BYDAY and WKST.
+var rruleDays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// maxScanYears bounds the search for the next date matching a rule, so a
+// rule that can never match (BYMONTH=2;BYMONTHDAY=30) fails instead of
+// looping.
+const maxScanYears = 30
+
+// isRRule reports whether pattern is written as an RRULE rather than in
+// shorthand.
+func isRRule(pattern string) bool {
+	p := strings.ToUpper(strings.TrimSpace(pattern))
+	return strings.HasPrefix(p, "RRULE:") || strings.HasPrefix(p, "FREQ=")
+}
+
+// ParseRRule parses an RFC 5545 recurrence rule such as
+// "RRULE:FREQ=MONTHLY;BYDAY=-1FR". The "RRULE:" prefix is optional.
+// Supported parts are FREQ, INTERVAL, BYDAY, BYMONTHDAY, BYMONTH, COUNT,
+// UNTIL and WKST.
+func ParseRRule(s string) (*Rule, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("empty RRULE")
+	}
+
+	r := &Rule{Interval: 1, WeekStart: time.Monday}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid RRULE part %q (expected NAME=VALUE)", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate RRULE part %s", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch f := Frequency(value); f {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = f
+			default:
+				err = fmt.Errorf("unsupported FREQ %q (use DAILY, WEEKLY, MONTHLY or YEARLY)", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = positiveInt(name, value)
+		case "COUNT":
+			r.Count, err = positiveInt(name, value)
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseIntList(name, value, 31, true)
+		case "BYMONTH":
+			var months []int
+			months, err = parseIntList(name, value, 12, false)
+			for _, m := range months {
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "WKST":
+			wd, ok := rruleDays[value]
+			if !ok {
+				err = fmt.Errorf("invalid WKST %q", value)
+			}
+			r.WeekStart = wd
+		default:
+			err = fmt.Errorf("unsupported RRULE part %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("RRULE is missing FREQ")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("RRULE cannot have both COUNT and UNTIL")
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return nil, fmt.Errorf("BYDAY ordinals like %s need FREQ=MONTHLY or FREQ=YEARLY", formatWeekdayNum(d))
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return nil, fmt.Errorf("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	return r, nil
+}
+
+func positiveInt(name, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s %q (must be a positive number)", name, value)
+	}
+	return n, nil
+}
+
+// parseUntil accepts the RRULE date forms YYYYMMDD and YYYYMMDDTHHMMSS[Z],
+// and YYYY-MM-DD. Only the date is kept.
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102", "20060102T150405Z", "20060102T150405", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q (use YYYYMMDD)", value)
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY %q", item)
+		}
+		code := item[len(item)-2:]
+		wd, ok := rruleDays[code]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY %q (use MO, TU, WE, TH, FR, SA or SU)", item)
+		}
+		d := WeekdayNum{Weekday: wd}
+		if ord := item[:len(item)-2]; ord != "" {
+			n, err := strconv.Atoi(ord)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid BYDAY %q (ordinal must be 1 to 53 or -1 to -53)", item)
+			}
+			d.N = n
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// parseIntList parses a comma-separated list of values from 1 to max, or
+// also -max to -1 if negative is set.
+func parseIntList(name, value string, max int, negative bool) ([]int, error) {
+	var list []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n == 0 || n > max || n < -max || (n < 0 && !negative) {
+			return nil, fmt.Errorf("invalid %s %q", name, item)
+		}
+		list = append(list, n)
+	}
+	return list, nil
+}
+
+// String returns the rule in canonical RRULE form, with parts in a fixed
+// order and default values left out.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = formatWeekdayNum(d)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]int, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = int(m)
+		}
+		parts = append(parts, "BYMONTH="+joinInts(months))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayCode(r.WeekStart))
+	}
+	return "RRULE:" + strings.Join(parts, ";")
+}
+
+func formatWeekdayNum(d WeekdayNum) string {
+	if d.N == 0 {
+		return weekdayCode(d.Weekday)
+	}
+	return strconv.Itoa(d.N) + weekdayCode(d.Weekday)
+}
+
+func weekdayCode(wd time.Weekday) string {
+	for code, d := range rruleDays {
+		if d == wd {
+			return code
+		}
+	}
+	return ""
+}
+
+func joinInts(list []int) string {
+	strs := make([]string, len(list))
+	for i, n := range list {
+		strs[i] = strconv.Itoa(n)
+	}
+	return strings.Join(strs, ",")
+}
+
+// hasByParts reports whether the rule picks specific days. Rules without
+// BY parts simply step by their interval from the current date.
+func (r *Rule) hasByParts() bool {
+	return len(r.ByDay) > 0 || len(r.ByMonthDay) > 0 || len(r.ByMonth) > 0
+}
+
+// next returns the first occurrence after currentDue that is not before
+// today, treating currentDue as the start of the series.
+func (r *Rule) next(currentDue, today time.Time) (time.Time, error) {
+	if r.Count == 1 {
+		return time.Time{}, ErrSeriesEnded
+	}
+
+	if r.hasByParts() {
+		return r.nextMatching(currentDue, today)
+	}
+	var next time.Time
+	if r.workdays {
+		next = advanceWorkdays(currentDue, r.Interval, today)
+	} else {
+		next = advanceByInterval(currentDue, r.Interval, r.unit(), today)
+	}
+	if !r.Until.IsZero() && next.After(r.Until) {
+		return time.Time{}, ErrSeriesEnded
+	}
+	return next, nil
+}
+
+// unit is the advanceByInterval unit for the rule's frequency.
+func (r *Rule) unit() byte {
+	switch r.Freq {
+	case Daily:
+		return 'd'
+	case Weekly:
+		return 'w'
+	case Monthly:
+		return 'm'
+	}
+	return 'y'
+}
+
+// nextMatching scans forward day by day for the first date after
+// currentDue, and not before today, that the rule selects.
+func (r *Rule) nextMatching(currentDue, today time.Time) (time.Time, error) {
+	candidate := currentDue.AddDate(0, 0, 1)
+	if candidate.Before(today) {
+		candidate = today
+	}
+	limit := candidate.AddDate(maxScanYears, 0, 0)
+	for candidate.Before(limit) {
+		if !r.Until.IsZero() && candidate.After(r.Until) {
+			return time.Time{}, ErrSeriesEnded
+		}
+		if r.matches(candidate, currentDue) {
+			return candidate, nil
+		}
+		candidate = candidate.AddDate(0, 0, 1)
+	}
+	return time.Time{}, fmt.Errorf("recurrence %s never matches a date", r)
+}
+
+// matches reports whether the rule selects day in a series starting at
+// start. Parts the rule leaves out default to start's weekday, day and
+// month, as in RFC 5545.
+func (r *Rule) matches(day, start time.Time) bool {
+	if !r.inInterval(day, start) {
+		return false
+	}
+	if len(r.ByMonth) > 0 && !containsMonth(r.ByMonth, day.Month()) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 && !r.matchesMonthDay(day) {
+		return false
+	}
+	if len(r.ByDay) > 0 && !r.matchesByDay(day) {
+		return false
+	}
+
+	switch r.Freq {
+	case Weekly:
+		if len(r.ByDay) == 0 {
+			return day.Weekday() == start.Weekday()
+		}
+	case Monthly:
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			return day.Day() == start.Day()
+		}
+	case Yearly:
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			if len(r.ByMonth) == 0 && day.Month() != start.Month() {
+				return false
+			}
+			return day.Day() == start.Day()
+		}
+	}
+	return true
+}
+
+// inInterval reports whether day falls in a period (day, week, month or
+// year) that is a whole number of intervals after start's.
+func (r *Rule) inInterval(day, start time.Time) bool {
+	if r.Interval <= 1 {
+		return true
+	}
+	var periods int
+	switch r.Freq {
+	case Daily:
+		periods = dayNumber(day) - dayNumber(start)
+	case Weekly:
+		periods = (dayNumber(r.weekStart(day)) - dayNumber(r.weekStart(start))) / 7
+	case Monthly:
+		periods = (day.Year()-start.Year())*12 + int(day.Month()) - int(start.Month())
+	case Yearly:
+		periods = day.Year() - start.Year()
+	}
+	return periods%r.Interval == 0
+}
+
+func (r *Rule) weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) - int(r.WeekStart) + 7) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+func (r *Rule) matchesMonthDay(day time.Time) bool {
+	last := daysIn(day.Year(), day.Month())
+	for _, md := range r.ByMonthDay {
+		if md == day.Day() || (md < 0 && last+md+1 == day.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesByDay checks BYDAY. Ordinals count within the month for monthly
+// rules and yearly rules limited by BYMONTH, and within the year otherwise.
+func (r *Rule) matchesByDay(day time.Time) bool {
+	inMonth := r.Freq == Monthly || len(r.ByMonth) > 0
+	for _, d := range r.ByDay {
+		if d.Weekday != day.Weekday() {
+			continue
+		}
+		if d.N == 0 {
+			return true
+		}
+		var pos, length int
+		if inMonth {
+			pos, length = day.Day(), daysIn(day.Year(), day.Month())
+		} else {
+			pos, length = day.YearDay(), time.Date(day.Year(), 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+		}
+		if d.N > 0 && (pos-1)/7+1 == d.N {
+			return true
+		}
+		if d.N < 0 && (length-pos)/7+1 == -d.N {
+			return true
+		}
+	}
+	return false
+}
+
+func containsMonth(months []time.Month, m time.Month) bool {
+	for _, x := range months {
+		if x == m {
+			return true
+		}
+	}
+	return false
+}
+
+// daysIn returns the number of days in month m of year.
+func daysIn(year int, m time.Month) int {
+	return time.Date(year, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// dayNumber counts days since the Unix epoch for t's calendar date.
+func dayNumber(t time.Time) int {
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
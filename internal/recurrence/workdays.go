@@ -0,0 +1,113 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/calendar"
+)
+
+// The workday modifiers, as written at the end of a pattern.
+const (
+	workdaysSuffix = "workdays"
+	shiftSuffix    = "shift to next workday"
+)
+
+// workCalendar decides which days count for the workday modifiers.
+var workCalendar = calendar.Default()
+
+// SetCalendar sets the calendar the workday modifiers use. A nil calendar
+// restores the Monday to Friday default.
+func SetCalendar(c *calendar.Calendar) {
+	if c == nil {
+		c = calendar.Default()
+	}
+	workCalendar = c
+}
+
+// Holidays returns the days covered by events from a holiday file. A
+// recurring event is expanded to each of its occurrences up to through,
+// or until its COUNT or UNTIL ends the series.
+func Holidays(events []calendar.Event, through time.Time) ([]time.Time, error) {
+	var days []time.Time
+	for _, e := range events {
+		days = append(days, e.Dates()...)
+		if e.RRule == "" {
+			continue
+		}
+
+		rule, err := ParseRRule(e.RRule)
+		if err != nil {
+			return nil, fmt.Errorf("event on %s: %w", e.Start.Format("2006-01-02"), err)
+		}
+		for start := e.Start; ; {
+			next, err := rule.next(start, start)
+			if errors.Is(err, ErrSeriesEnded) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("event on %s: %w", e.Start.Format("2006-01-02"), err)
+			}
+			if next.After(through) {
+				break
+			}
+			if rule.Count > 0 {
+				rule.Count--
+			}
+			start = next
+			days = append(days, calendar.Event{Start: start, Days: e.Days}.Dates()...)
+		}
+	}
+	return days, nil
+}
+
+// splitModifiers takes the workday modifiers off a pattern without its
+// limits: a trailing "shift to next workday", optionally after a comma,
+// and before it a trailing "workdays".
+func splitModifiers(pattern string) (string, bool, bool) {
+	base := strings.TrimSpace(pattern)
+	shift := false
+	if len(base) >= len(shiftSuffix) && strings.EqualFold(base[len(base)-len(shiftSuffix):], shiftSuffix) {
+		base = strings.TrimRight(base[:len(base)-len(shiftSuffix)], ", ")
+		shift = true
+	}
+	workdays := false
+	if fields := strings.Fields(base); len(fields) > 1 && strings.EqualFold(fields[len(fields)-1], workdaysSuffix) {
+		base = strings.Join(fields[:len(fields)-1], " ")
+		workdays = true
+	}
+	return base, workdays, shift
+}
+
+// withModifiers appends the workday modifiers to a normalized pattern.
+func withModifiers(pattern string, workdays, shift bool) string {
+	if workdays {
+		pattern += " " + workdaysSuffix
+	}
+	if shift {
+		pattern += ", " + shiftSuffix
+	}
+	return pattern
+}
+
+// checkWorkdays reports an error unless a normalized shorthand pattern
+// steps by a number of days, the only interval workdays can count.
+func checkWorkdays(normalized string) error {
+	spec := strings.TrimPrefix(strings.TrimPrefix(normalized, "every "), "after ")
+	if _, unit, ok := parseInterval(spec); !ok || unit != 'd' {
+		return fmt.Errorf("workdays counts days, so it needs every <N>d or after <N>d (got %q)", normalized)
+	}
+	return nil
+}
+
+// advanceWorkdays steps n workdays at a time from currentDue until the
+// result is not before today.
+func advanceWorkdays(currentDue time.Time, n int, today time.Time) time.Time {
+	next := workCalendar.AddWorkdays(currentDue, n)
+	for next.Before(today) {
+		next = workCalendar.AddWorkdays(next, n)
+	}
+	return next
+}
@@ -0,0 +1,393 @@
+// Package search keeps a full-text index of task and project titles, tags
+// and bodies, and ranks matches with BM25.
+package search
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mph-llm-experiments/acore"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+// IndexFileName is the name of the search index kept in the notes directory,
+// next to the metadata index.
+const IndexFileName = ".atask-search"
+
+// indexVersion is bumped whenever the index layout or the tokenizer changes,
+// so that older index files are rebuilt instead of searched with stale terms.
+const indexVersion = 3
+
+// Field weights: a term in the title counts three times, in a tag twice.
+const (
+	titleWeight = 3
+	tagWeight   = 2
+	bodyWeight  = 1
+)
+
+// Document is a task or project as the index sees it.
+type Document struct {
+	Path    string
+	Type    string // denote.TypeTask or denote.TypeProject
+	ID      int
+	Title   string
+	Tags    []string
+	Body    string
+	ModTime time.Time
+}
+
+// DocEntry is the indexed form of a document. An entry is reindexed when the
+// file's mtime no longer matches ModTime.
+type DocEntry struct {
+	ModTime int64
+	Type    string
+	ID      int
+	Title   string
+	Tags    []string
+	Length  int      // weighted number of terms
+	Terms   []string // distinct terms, to remove the document's postings
+}
+
+// Index is an inverted index from terms to the documents containing them,
+// keyed by path relative to the notes directory.
+type Index struct {
+	Version  int
+	Docs     map[string]*DocEntry
+	Postings map[string]map[string]int // term -> document -> weighted frequency
+	TotalLen int
+
+	// Journal is how far into the undo journal the index has been brought
+	// up to date.
+	Journal denote.JournalPos
+
+	dir    string
+	bodies map[string]string // bodies read since loading, for snippets
+	loaded bool              // read from a current index file
+	dirty  bool
+}
+
+// LoadIndex reads the search index for dir. A missing, unreadable or
+// outdated index is not an error: an empty index is returned and filled by
+// Update.
+func LoadIndex(dir string) *Index {
+	idx := &Index{dir: dir}
+
+	f, err := os.Open(filepath.Join(dir, IndexFileName))
+	if err == nil {
+		defer f.Close()
+		if err := gob.NewDecoder(f).Decode(idx); err != nil || idx.Version != indexVersion {
+			idx.Docs, idx.Postings, idx.TotalLen, idx.Journal = nil, nil, 0, denote.JournalPos{}
+			idx.dirty = true
+		} else {
+			idx.loaded = true
+		}
+	}
+
+	idx.Version = indexVersion
+	if idx.Docs == nil || idx.Postings == nil {
+		idx.Docs = make(map[string]*DocEntry)
+		idx.Postings = make(map[string]map[string]int)
+		idx.TotalLen = 0
+	}
+	idx.bodies = make(map[string]string)
+	return idx
+}
+
+// Open loads the search index for dir and applies the writes journaled
+// since it was last saved: changed task and project files are reindexed and
+// removed or moved ones dropped. Only a missing or outdated index, or a
+// journal that was compacted or replaced, makes Open index every file
+// again. Files edited outside atask are picked up by Refresh or Rebuild.
+func Open(dir string) (*Index, error) {
+	idx := LoadIndex(dir)
+	if !idx.loaded {
+		return build(dir)
+	}
+
+	paths, next, err := denote.JournalChanges(dir, idx.Journal)
+	if errors.Is(err, denote.ErrJournalReplaced) {
+		return build(dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := idx.refresh(paths); err != nil {
+		return nil, err
+	}
+	if next != idx.Journal {
+		idx.Journal = next
+		idx.dirty = true
+	}
+	// The index is a cache; failing to persist it must not fail the search.
+	_ = idx.Save()
+	return idx, nil
+}
+
+// Refresh reindexes paths, relative to dir, in the saved search index, for
+// files changed without going through the journal, such as by a sync pull.
+// It does nothing if there is no index yet; Open builds one when needed.
+func Refresh(dir string, paths []string) error {
+	idx := LoadIndex(dir)
+	if !idx.loaded || len(paths) == 0 {
+		return nil
+	}
+	if err := idx.refresh(paths); err != nil {
+		return err
+	}
+	return idx.Save()
+}
+
+// Rebuild discards any existing search index for dir and indexes every
+// task and project again.
+func Rebuild(dir string) (*Index, error) {
+	if err := os.Remove(filepath.Join(dir, IndexFileName)); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove search index: %w", err)
+	}
+	return build(dir)
+}
+
+// build indexes every task and project file in dir, including archived
+// ones, and saves the index. The journal position is taken before the scan,
+// so writes made during it are applied again by the next Open.
+func build(dir string) (*Index, error) {
+	pos, err := denote.JournalEnd(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := denote.NewScanner(dir)
+	scanner.IncludeArchived = true
+
+	tasks, err := scanner.FindTasks()
+	if err != nil {
+		return nil, err
+	}
+	projects, err := scanner.FindProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]Document, 0, len(tasks)+len(projects))
+	for _, t := range tasks {
+		docs = append(docs, TaskDocument(t))
+	}
+	for _, p := range projects {
+		docs = append(docs, ProjectDocument(p))
+	}
+
+	idx := LoadIndex(dir)
+	idx.Update(docs)
+	idx.Journal = pos
+	idx.dirty = true
+	_ = idx.Save()
+	return idx, nil
+}
+
+// refresh reindexes each of paths, relative to the notes directory, from
+// the file on disk. Paths that no longer exist, or are not task or project
+// files in a directory the scanner reads, are removed from the index.
+func (idx *Index) refresh(paths []string) error {
+	for _, rel := range paths {
+		d, ok, err := idx.load(rel)
+		if err != nil {
+			return err
+		}
+		idx.remove(rel)
+		delete(idx.bodies, rel)
+		if ok {
+			idx.bodies[rel] = d.Body
+			idx.add(rel, d)
+		}
+	}
+	return nil
+}
+
+// load reads the document at rel, waiting for any write in progress on it:
+// journal entries are written before the change they record lands.
+func (idx *Index) load(rel string) (d Document, ok bool, err error) {
+	path := filepath.Join(idx.dir, rel)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return Document{}, false, nil
+	}
+	unlock, err := denote.LockFile(path)
+	if err != nil {
+		return Document{}, false, err
+	}
+	defer unlock()
+	d, ok = idx.read(rel)
+	return d, ok, nil
+}
+
+// read parses the document at rel. ok is false if rel is not a task or
+// project file in a directory the scanner reads, or it no longer exists or
+// parses, as a scan would then drop it too.
+func (idx *Index) read(rel string) (Document, bool) {
+	if !scanned(rel) {
+		return Document{}, false
+	}
+	_, _, fileType, err := acore.ParseFilename(filepath.Base(rel))
+	if err != nil {
+		return Document{}, false
+	}
+
+	path := filepath.Join(idx.dir, rel)
+	switch fileType {
+	case denote.TypeTask:
+		if t, err := denote.ParseTaskFile(path); err == nil {
+			return TaskDocument(t), true
+		}
+	case denote.TypeProject:
+		if p, err := denote.ParseProjectFile(path); err == nil {
+			return ProjectDocument(p), true
+		}
+	}
+	return Document{}, false
+}
+
+// body returns the body of the indexed document at rel for a snippet,
+// reading the file if it was not read since the index was loaded.
+func (idx *Index) body(rel string) (string, bool) {
+	if body, ok := idx.bodies[rel]; ok {
+		return body, true
+	}
+	d, ok := idx.read(rel)
+	if !ok {
+		return "", false
+	}
+	idx.bodies[rel] = d.Body
+	return d.Body, true
+}
+
+// scanned reports whether rel is in a directory Open's full scan reads: the
+// notes directory itself or one of the archive subdirectories.
+func scanned(rel string) bool {
+	dir := filepath.Dir(rel)
+	return dir == "." || filepath.Dir(dir) == denote.ArchiveDir
+}
+
+// TaskDocument returns the indexed fields of a task.
+func TaskDocument(t *denote.Task) Document {
+	return Document{Path: t.FilePath, Type: denote.TypeTask, ID: t.IndexID,
+		Title: t.Title, Tags: t.Tags, Body: t.Content, ModTime: t.ModTime}
+}
+
+// ProjectDocument returns the indexed fields of a project.
+func ProjectDocument(p *denote.Project) Document {
+	return Document{Path: p.FilePath, Type: denote.TypeProject, ID: p.IndexID,
+		Title: p.Title, Tags: p.Tags, Body: p.Content, ModTime: p.ModTime}
+}
+
+// Update makes the index match docs: documents whose mtime changed are
+// reindexed, new ones are added and those missing from docs are removed.
+func (idx *Index) Update(docs []Document) {
+	seen := make(map[string]bool, len(docs))
+	for _, d := range docs {
+		rel := idx.rel(d.Path)
+		seen[rel] = true
+		idx.bodies[rel] = d.Body
+		if e, ok := idx.Docs[rel]; ok && e.ModTime == d.ModTime.UnixNano() && e.ID == d.ID {
+			continue
+		}
+		idx.remove(rel)
+		idx.add(rel, d)
+	}
+	for rel := range idx.Docs {
+		if !seen[rel] {
+			idx.remove(rel)
+		}
+	}
+}
+
+// Count returns the number of indexed documents.
+func (idx *Index) Count() int {
+	return len(idx.Docs)
+}
+
+// add indexes d under rel, which must not be indexed yet.
+func (idx *Index) add(rel string, d Document) {
+	freq := make(map[string]int)
+	length := 0
+	addTerms := func(text string, weight int) {
+		for _, t := range Tokenize(text) {
+			freq[t] += weight
+			length += weight
+		}
+	}
+	addTerms(d.Title, titleWeight)
+	for _, tag := range d.Tags {
+		addTerms(tag, tagWeight)
+	}
+	addTerms(d.Body, bodyWeight)
+
+	e := &DocEntry{ModTime: d.ModTime.UnixNano(), Type: d.Type, ID: d.ID,
+		Title: d.Title, Tags: d.Tags, Length: length}
+	for t, n := range freq {
+		e.Terms = append(e.Terms, t)
+		if idx.Postings[t] == nil {
+			idx.Postings[t] = make(map[string]int)
+		}
+		idx.Postings[t][rel] = n
+	}
+	idx.Docs[rel] = e
+	idx.TotalLen += length
+	idx.dirty = true
+}
+
+// remove drops rel and its postings from the index.
+func (idx *Index) remove(rel string) {
+	e, ok := idx.Docs[rel]
+	if !ok {
+		return
+	}
+	for _, t := range e.Terms {
+		delete(idx.Postings[t], rel)
+		if len(idx.Postings[t]) == 0 {
+			delete(idx.Postings, t)
+		}
+	}
+	delete(idx.Docs, rel)
+	idx.TotalLen -= e.Length
+	idx.dirty = true
+}
+
+// rel returns path relative to the notes directory.
+func (idx *Index) rel(path string) string {
+	if rel, err := filepath.Rel(idx.dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// Save writes the index back to disk if it changed since it was loaded,
+// through a temporary file so readers never see a partial index.
+func (idx *Index) Save() error {
+	if !idx.dirty {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(idx.dir, IndexFileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	if err := gob.NewEncoder(tmp).Encode(idx); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to encode search index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(idx.dir, IndexFileName)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+
+	idx.dirty = false
+	return nil
+}
@@ -0,0 +1,277 @@
+package search
+
+import (
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+// BM25 parameters: k1 limits how much repeating a term helps, b how much
+// long documents are penalized.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// snippetWords is roughly how many words of context a snippet shows.
+const snippetWords = 24
+
+// Result is a document matching a search, best first.
+type Result struct {
+	Path       string   `json:"path"`
+	Type       string   `json:"type"`
+	ID         int      `json:"id"`
+	Title      string   `json:"title"`
+	Tags       []string `json:"tags,omitempty"`
+	Archived   bool     `json:"archived,omitempty"`
+	Score      float64  `json:"score"`
+	Snippet    string   `json:"snippet,omitempty"`
+	Highlights []Span   `json:"highlights,omitempty"` // byte ranges of matched words in Snippet
+}
+
+// Span is a byte range [Start, End) of a matched word.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Search returns the documents containing every term of text, ranked by
+// BM25 with title and tag matches weighted above body matches.
+func (idx *Index) Search(text string) []Result {
+	return idx.search(Tokenize(text), false)
+}
+
+// SearchPrefix is Search for text that is still being typed: each word
+// also matches longer words that start with it, so "oau tok" finds "OAuth
+// token". The last word counts even if it is a single letter or stopword.
+func (idx *Index) SearchPrefix(text string) []Result {
+	var words []string
+	spans := tokenSpans(text)
+	for _, s := range spans {
+		words = append(words, strings.ToLower(text[s.start:s.end]))
+	}
+	if last := trailingWord(text); last != "" && (len(spans) == 0 || spans[len(spans)-1].end != len(text)) {
+		words = append(words, strings.ToLower(last))
+	}
+	return idx.search(words, true)
+}
+
+// trailingWord returns the letters and digits at the end of text.
+func trailingWord(text string) string {
+	i := strings.LastIndexFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if i < 0 {
+		return text
+	}
+	_, size := utf8.DecodeRuneInString(text[i:])
+	return text[i+size:]
+}
+
+// search ranks the documents containing every term. In prefix mode the
+// terms are words as typed, each matching its stem or any term it starts.
+func (idx *Index) search(terms []string, prefix bool) []Result {
+	if len(terms) == 0 || len(idx.Docs) == 0 {
+		return nil
+	}
+
+	n := float64(len(idx.Docs))
+	avgLen := float64(idx.TotalLen) / n
+	if avgLen == 0 {
+		avgLen = 1
+	}
+
+	var scores map[string]float64
+	for _, term := range terms {
+		postings := []map[string]int{idx.Postings[term]}
+		if prefix {
+			postings = idx.prefixPostings(term)
+		}
+
+		termScores := make(map[string]float64)
+		for _, p := range postings {
+			df := float64(len(p))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			for rel, tf := range p {
+				dl := float64(idx.Docs[rel].Length)
+				f := float64(tf)
+				termScores[rel] += idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*dl/avgLen))
+			}
+		}
+
+		// Every term must match
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for rel := range scores {
+			if s, ok := termScores[rel]; ok {
+				scores[rel] += s
+			} else {
+				delete(scores, rel)
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for rel, score := range scores {
+		e := idx.Docs[rel]
+		r := Result{Path: filepath.Join(idx.dir, rel), Type: e.Type, ID: e.ID,
+			Title: e.Title, Tags: e.Tags, Score: math.Round(score*1000) / 1000,
+			Archived: strings.HasPrefix(rel, denote.ArchiveDir+string(filepath.Separator))}
+		if body, ok := idx.body(rel); ok {
+			r.Snippet, r.Highlights = snippet(body, terms, prefix)
+		}
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Path < results[j].Path
+	})
+	return results
+}
+
+// prefixPostings returns the postings of word's stem and of every term
+// starting with word.
+func (idx *Index) prefixPostings(word string) []map[string]int {
+	stemmed := stem(word)
+	var postings []map[string]int
+	for term, p := range idx.Postings {
+		if term == stemmed || strings.HasPrefix(term, word) {
+			postings = append(postings, p)
+		}
+	}
+	return postings
+}
+
+// Matches returns the paths of the documents containing every term of text.
+func (idx *Index) Matches(text string) map[string]bool {
+	matches := make(map[string]bool)
+	for _, r := range idx.Search(text) {
+		matches[r.Path] = true
+	}
+	return matches
+}
+
+// snippet picks the stretch of body with the most matched words and
+// returns it with the matches' positions. Bodies without a match give the
+// opening words instead, with no highlights.
+func snippet(body string, terms []string, prefix bool) (string, []Span) {
+	spans := tokenSpans(body)
+	if len(spans) == 0 {
+		return "", nil
+	}
+
+	want := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		if prefix {
+			t = stem(t)
+		}
+		want[t] = true
+	}
+	matched := func(s span) bool {
+		if want[s.term] {
+			return true
+		}
+		for _, t := range terms {
+			if prefix && strings.HasPrefix(s.term, t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Slide a window of snippetWords terms to the one with the most matches
+	bestStart, bestCount, count := 0, 0, 0
+	for i := range spans {
+		if matched(spans[i]) {
+			count++
+		}
+		if i >= snippetWords && matched(spans[i-snippetWords]) {
+			count--
+		}
+		if count > bestCount {
+			bestStart, bestCount = max(0, i-snippetWords+1), count
+		}
+	}
+	if bestCount > 0 {
+		// Open with a little context before the first match
+		for bestStart < len(spans) && !matched(spans[bestStart]) {
+			bestStart++
+		}
+		bestStart = max(0, bestStart-3)
+	}
+	endSpan := min(len(spans), bestStart+snippetWords) - 1
+
+	from, to := spans[bestStart].start, spans[endSpan].end
+	if bestStart == 0 {
+		from = 0
+	}
+	if endSpan == len(spans)-1 {
+		to = len(body)
+	}
+	text := body[from:to]
+	var highlights []Span
+	for _, s := range spans[bestStart : endSpan+1] {
+		if matched(s) {
+			highlights = append(highlights, Span{s.start - from, s.end - from})
+		}
+	}
+
+	// Collapse line breaks and runs of spaces, shifting the highlights
+	var b strings.Builder
+	shift := make([]int, len(text)+1)
+	space := false
+	for i, r := range text {
+		shift[i] = b.Len()
+		if r == '\n' || r == '\r' || r == '\t' || r == ' ' {
+			if !space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	shift[len(text)] = b.Len()
+	for i := range highlights {
+		highlights[i] = Span{shift[highlights[i].Start], shift[highlights[i].End]}
+	}
+
+	out := strings.TrimRight(b.String(), " ")
+	if bestStart > 0 {
+		out = "…" + out
+		for i := range highlights {
+			highlights[i].Start += len("…")
+			highlights[i].End += len("…")
+		}
+	}
+	if endSpan < len(spans)-1 {
+		out += "…"
+	}
+	return out, highlights
+}
+
+// Highlight returns snippet with each highlighted range wrapped by mark.
+func Highlight(snippet string, highlights []Span, mark func(string) string) string {
+	var b strings.Builder
+	pos := 0
+	for _, h := range highlights {
+		if h.Start < pos || h.End > len(snippet) {
+			continue
+		}
+		b.WriteString(snippet[pos:h.Start])
+		b.WriteString(mark(snippet[h.Start:h.End]))
+		pos = h.End
+	}
+	b.WriteString(snippet[pos:])
+	return b.String()
+}
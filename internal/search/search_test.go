@@ -0,0 +1,284 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mph-llm-experiments/acore"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := map[string][]string{
+		"OAuth token refresh":              {"oauth", "token", "refresh"},
+		"Refreshing the tokens":            {"refresh", "token"},
+		"refreshed, refreshes!":            {"refresh", "refresh"},
+		"Running queries":                  {"run", "query"},
+		"create created creating creates":  {"creat", "creat", "creat", "creat"},
+		"status pass passes":               {"status", "pass", "pass"},
+		"a 2 b 10 of it":                   {"2", "10"},
+		"re-import the ünïcode café notes": {"re", "import", "ünïcod", "café", "note"},
+	}
+	for text, want := range tests {
+		if got := Tokenize(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	text := "Refreshing the OAuth tokens fails after an hour."
+	tests := map[string]bool{
+		"token refresh":  true,
+		"oauth":          true,
+		"OAUTH TOKENS":   true,
+		"refresh hourly": false,
+		"auth":           false,
+		"the":            true, // only stopwords: substring match
+		"xyz the":        false,
+	}
+	for q, want := range tests {
+		if got := Contains(text, q); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", q, got, want)
+		}
+	}
+}
+
+func testIndex(docs ...Document) *Index {
+	idx := LoadIndex("/notes") // no index there: starts empty
+	idx.Update(docs)
+	return idx
+}
+
+func TestSearchRanksTitleAboveBody(t *testing.T) {
+	now := time.Now()
+	idx := testIndex(
+		Document{Path: "/notes/a", Type: denote.TypeTask, ID: 1, Title: "Groceries",
+			Body: "Remember to fix the OAuth token refresh some day.", ModTime: now},
+		Document{Path: "/notes/b", Type: denote.TypeTask, ID: 2, Title: "Fix OAuth token refresh",
+			Body: "The refresh token expires after an hour.", ModTime: now},
+		Document{Path: "/notes/c", Type: denote.TypeTask, ID: 3, Title: "OAuth docs",
+			Body: "Write up the login flow.", ModTime: now},
+		Document{Path: "/notes/d", Type: denote.TypeProject, ID: 1, Title: "Auth rework",
+			Tags: []string{"oauth"}, Body: "Token handling.", ModTime: now},
+	)
+
+	results := idx.Search("oauth token refresh")
+	var ids []int
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{2, 1}) {
+		t.Fatalf("Search ranked IDs %v, want [2 1]", ids)
+	}
+	if results[0].Score <= results[1].Score {
+		t.Errorf("scores not descending: %v", results)
+	}
+
+	if got := len(idx.Search("oauth token")); got != 3 {
+		t.Errorf("Search(oauth token) returned %d results, want 3", got)
+	}
+	if got := idx.Search("the"); got != nil {
+		t.Errorf("Search(the) = %v, want no results", got)
+	}
+}
+
+func TestSearchPrefix(t *testing.T) {
+	idx := testIndex(
+		Document{Path: "/notes/a", Title: "Rotate credentials", ModTime: time.Now()},
+		Document{Path: "/notes/b", Title: "Credit card statement", ModTime: time.Now()},
+	)
+	tests := map[string]int{
+		"cred":        2,
+		"credent":     1,
+		"rotate cr":   1,
+		"c":           2,
+		"credential ": 1,
+		"credential":  1,
+		"rot cred":    1,
+		"rotating cr": 1,
+		"the":         0,
+	}
+	for q, want := range tests {
+		if got := len(idx.SearchPrefix(q)); got != want {
+			t.Errorf("SearchPrefix(%q) returned %d results, want %d", q, got, want)
+		}
+	}
+}
+
+func TestSnippetHighlights(t *testing.T) {
+	body := "# Notes\n\n" + strings.Repeat("filler words here ", 20) +
+		"then the OAuth\ntoken  refresh broke again " + strings.Repeat("more filler ", 20)
+	text, highlights := snippet(body, Tokenize("token refresh"), false)
+
+	if !strings.HasPrefix(text, "…") || !strings.HasSuffix(text, "…") {
+		t.Errorf("snippet %q should be elided on both ends", text)
+	}
+	if strings.Contains(text, "\n") || strings.Contains(text, "  ") {
+		t.Errorf("snippet %q should collapse whitespace", text)
+	}
+	got := Highlight(text, highlights, func(s string) string { return "[" + s + "]" })
+	if !strings.Contains(got, "OAuth [token] [refresh] broke") {
+		t.Errorf("highlighted snippet = %q", got)
+	}
+}
+
+func writeTask(t *testing.T, dir string, id int, title, body string) string {
+	t.Helper()
+	task := &denote.Task{}
+	task.ID = acore.NewID()
+	task.Title = title
+	task.IndexID = id
+	task.Type = denote.TypeTask
+	task.Status = denote.TaskStatusOpen
+	filename := acore.BuildFilename(task.ID, task.Title, "task")
+	if err := acore.WriteFile(acore.NewLocalStore(dir), filename, task, body); err != nil {
+		t.Fatalf("write task: %v", err)
+	}
+	return filepath.Join(dir, filename)
+}
+
+// rewrite replaces old with new in the file at path.
+func rewrite(t *testing.T, op *denote.Op, path, old, new string) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := strings.Replace(string(content), old, new, 1)
+	if err := denote.WriteFileAtomic(op, path, []byte(updated), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenAppliesJournaledWrites(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTask(t, dir, 1, "Renew certificate", "The cert expires in May.")
+	offsite := writeTask(t, dir, 2, "Plan offsite", "Book the venue.")
+	review := writeTask(t, dir, 3, "Quarterly review", "Collect the numbers.")
+
+	idx, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if idx.Count() != 3 {
+		t.Fatalf("indexed %d documents, want 3", idx.Count())
+	}
+	if _, err := os.Stat(filepath.Join(dir, IndexFileName)); err != nil {
+		t.Fatalf("index not saved: %v", err)
+	}
+
+	// Rewrite one task, delete another and archive the third
+	op := denote.NewOp(dir, "test")
+	rewrite(t, op, path, "The cert expires in May.", "Rotate the signing keys.")
+	if err := denote.RemoveFile(op, offsite); err != nil {
+		t.Fatal(err)
+	}
+	archived := filepath.Join(dir, denote.ArchiveDir, "2026", filepath.Base(review))
+	os.MkdirAll(filepath.Dir(archived), 0755)
+	if err := denote.MoveFile(op, review, archived); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err = Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if idx.Count() != 2 {
+		t.Errorf("indexed %d documents after delete, want 2", idx.Count())
+	}
+	if got := idx.Search("signing key"); len(got) != 1 || got[0].Path != path || got[0].Snippet == "" {
+		t.Errorf("Search(signing key) = %v, want %s with a snippet", got, path)
+	}
+	if got := idx.Search("expires"); len(got) != 0 {
+		t.Errorf("Search(expires) still finds the old body: %v", got)
+	}
+	if got := idx.Search("venue"); len(got) != 0 {
+		t.Errorf("Search(venue) still finds the deleted task: %v", got)
+	}
+	if got := idx.Search("numbers"); len(got) != 1 || got[0].Path != archived || !got[0].Archived {
+		t.Errorf("Search(numbers) = %v, want the archived task", got)
+	}
+
+	// A fresh load reads the saved postings
+	if got := LoadIndex(dir).Search("signing"); len(got) != 1 {
+		t.Errorf("reloaded index found %d results, want 1", len(got))
+	}
+
+	// Moving a task into the trash drops it
+	trashed := filepath.Join(dir, ".trash", filepath.Base(path))
+	os.MkdirAll(filepath.Dir(trashed), 0755)
+	if err := denote.MoveFile(op, path, trashed); err != nil {
+		t.Fatal(err)
+	}
+	idx, err = Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if idx.Count() != 1 {
+		t.Errorf("indexed %d documents after trashing, want 1", idx.Count())
+	}
+}
+
+func TestOpenDoesNotRescan(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTask(t, dir, 1, "Renew certificate", "The cert expires in May.")
+	if _, err := Open(dir); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	// An edit made outside atask is not journaled
+	rewrite(t, nil, path, "The cert expires in May.", "Rotate the signing keys.")
+	idx, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if got := idx.Search("signing"); len(got) != 0 {
+		t.Errorf("Open rescanned the notes: Search(signing) = %v", got)
+	}
+
+	// Refresh picks up files changed by a sync pull
+	if err := Refresh(dir, []string{filepath.Base(path)}); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if got := LoadIndex(dir).Search("signing"); len(got) != 1 {
+		t.Errorf("Search(signing) after Refresh found %d results, want 1", len(got))
+	}
+
+	// Rebuild scans everything
+	writeTask(t, dir, 2, "Plan offsite", "Book the venue.")
+	idx, err = Rebuild(dir)
+	if err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	if got := idx.Search("venue"); len(got) != 1 {
+		t.Errorf("Search(venue) after Rebuild found %d results, want 1", len(got))
+	}
+}
+
+func TestOpenRebuildsWhenJournalReplaced(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTask(t, dir, 1, "Renew certificate", "The cert expires in May.")
+	rewrite(t, denote.NewOp(dir, "test"), path, "May", "June")
+	if _, err := Open(dir); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	// The journal is cleared and the notes edited by hand
+	if err := os.Remove(filepath.Join(dir, denote.JournalFileName)); err != nil {
+		t.Fatal(err)
+	}
+	rewrite(t, nil, path, "The cert expires in June.", "Rotate the signing keys.")
+
+	idx, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if got := idx.Search("signing"); len(got) != 1 {
+		t.Errorf("Search(signing) after the journal was replaced found %d results, want 1", len(got))
+	}
+}
@@ -0,0 +1,134 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// stopwords are common words left out of the index; a query made only of
+// stopwords matches nothing.
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "has": true, "in": true,
+	"is": true, "it": true, "of": true, "on": true, "or": true, "that": true,
+	"the": true, "to": true, "was": true, "were": true, "will": true, "with": true,
+}
+
+// span is a term and the byte range of the word it came from.
+type span struct {
+	term       string
+	start, end int
+}
+
+// Tokenize splits text into index terms: lowercased words and numbers,
+// without stopwords, reduced to a common stem so that "refreshing",
+// "refreshed" and "refreshes" all match "refresh".
+func Tokenize(text string) []string {
+	spans := tokenSpans(text)
+	terms := make([]string, len(spans))
+	for i, s := range spans {
+		terms[i] = s.term
+	}
+	return terms
+}
+
+// tokenSpans tokenizes text, recording where each term's word is.
+func tokenSpans(text string) []span {
+	var spans []span
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			spans = appendTerm(spans, text, start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = appendTerm(spans, text, start, len(text))
+	}
+	return spans
+}
+
+func appendTerm(spans []span, text string, start, end int) []span {
+	word := strings.ToLower(text[start:end])
+	if stopwords[word] || (utf8.RuneCountInString(word) < 2 && !isDigits(word)) {
+		return spans
+	}
+	return append(spans, span{term: stem(word), start: start, end: end})
+}
+
+// stem strips common English inflections. It is deliberately light: it only
+// has to map a word and its variants to the same term, not to a real root.
+func stem(word string) string {
+	if len(word) <= 3 || isDigits(word) {
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		word = word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "sses"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		word = word[:len(word)-1]
+	}
+
+	switch {
+	case strings.HasSuffix(word, "ing") && len(word) > 5:
+		word = undouble(word[:len(word)-3])
+	case strings.HasSuffix(word, "ed") && len(word) > 4:
+		word = undouble(word[:len(word)-2])
+	}
+
+	// "create", "created" and "creating" all end up as "creat"
+	if strings.HasSuffix(word, "e") && len(word) > 4 {
+		word = word[:len(word)-1]
+	}
+	return word
+}
+
+// undouble drops a doubled final consonant, as in "running" -> "run".
+func undouble(word string) string {
+	n := len(word)
+	if n < 3 || word[n-1] != word[n-2] || strings.ContainsRune("aeiouls", rune(word[n-1])) {
+		return word
+	}
+	return word[:n-1]
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Contains reports whether text contains every term of query, compared as
+// stems so that "token refresh" matches "refreshing the tokens". A query
+// with no terms (only stopwords or punctuation) matches as a plain
+// case-insensitive substring instead.
+func Contains(text, query string) bool {
+	want := Tokenize(query)
+	if len(want) == 0 {
+		return strings.Contains(strings.ToLower(text), strings.ToLower(strings.TrimSpace(query)))
+	}
+	have := make(map[string]bool)
+	for _, t := range Tokenize(text) {
+		have[t] = true
+	}
+	for _, t := range want {
+		if !have[t] {
+			return false
+		}
+	}
+	return true
+}
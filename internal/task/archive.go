@@ -0,0 +1,124 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mph-llm-experiments/acore"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+// ArchivedFile describes a task or project moved (or, in a dry run, due to
+// be moved) into the archive.
+type ArchivedFile struct {
+	Type        string    `json:"type"`
+	IndexID     int       `json:"index_id"`
+	Title       string    `json:"title"`
+	Status      string    `json:"status"`
+	CompletedAt time.Time `json:"completed_at"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+}
+
+// ArchiveCompleted moves done and dropped tasks, and completed and cancelled
+// projects, that were finished more than olderThan ago into
+// archive/YYYY/, where YYYY is the year they were finished. With dryRun the
+// files are only reported.
+func ArchiveCompleted(op *denote.Op, dir string, olderThan time.Duration, dryRun bool) ([]ArchivedFile, error) {
+	scanner := denote.NewScanner(dir)
+	scanner.FrontmatterOnly = true
+
+	tasks, err := scanner.FindTasks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan tasks: %w", err)
+	}
+	projects, err := scanner.FindProjects()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan projects: %w", err)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var files []ArchivedFile
+	add := func(fileType string, e acore.Entity, status, completedAt string, modTime time.Time) {
+		finished := finishedAt(completedAt, e, modTime)
+		if finished.After(cutoff) {
+			return
+		}
+		files = append(files, ArchivedFile{
+			Type:        fileType,
+			IndexID:     e.IndexID,
+			Title:       e.Title,
+			Status:      status,
+			CompletedAt: finished,
+			From:        e.FilePath,
+			To:          filepath.Join(dir, denote.ArchiveDir, strconv.Itoa(finished.Year()), filepath.Base(e.FilePath)),
+		})
+	}
+
+	for _, t := range tasks {
+		if t.Status == denote.TaskStatusDone || t.Status == denote.TaskStatusDropped {
+			add(denote.TypeTask, t.Entity, t.Status, t.CompletedAt, t.ModTime)
+		}
+	}
+	for _, p := range projects {
+		if p.Status == denote.ProjectStatusCompleted || p.Status == denote.ProjectStatusCancelled {
+			add(denote.TypeProject, p.Entity, p.Status, "", p.ModTime)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].CompletedAt.Before(files[j].CompletedAt)
+	})
+
+	if dryRun || len(files) == 0 {
+		return files, nil
+	}
+
+	unlock, err := denote.LockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for i, f := range files {
+		if err := os.MkdirAll(filepath.Dir(f.To), 0755); err != nil {
+			return files[:i], fmt.Errorf("failed to create archive directory: %w", err)
+		}
+		if _, err := os.Stat(f.To); err == nil {
+			return files[:i], fmt.Errorf("cannot archive %s: already exists in archive", filepath.Base(f.From))
+		}
+		if err := moveLocked(op, f.From, f.To); err != nil {
+			return files[:i], fmt.Errorf("failed to archive %s: %w", filepath.Base(f.From), err)
+		}
+	}
+
+	return files, nil
+}
+
+// moveLocked moves a note under the lock of its current path, so a write
+// to the note that is still in progress finishes before the move instead
+// of recreating the file at its old path afterwards.
+func moveLocked(op *denote.Op, from, to string) error {
+	unlock, err := denote.LockFile(from)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return denote.MoveFile(op, from, to)
+}
+
+// finishedAt returns when a task or project was finished: completedAt if
+// recorded, otherwise its modified timestamp, falling back to the file's
+// modification time.
+func finishedAt(completedAt string, e acore.Entity, modTime time.Time) time.Time {
+	for _, s := range []string{completedAt, e.Modified} {
+		if t, err := denote.ParseTimestamp(s); err == nil {
+			return t
+		}
+	}
+	return modTime
+}
@@ -0,0 +1,142 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+// finishedTask creates a task with the given status and timestamps.
+func finishedTask(t *testing.T, dir, title, status, completedAt, modified string) *denote.Task {
+	t.Helper()
+	task, err := CreateTask(nil, dir, title, "", nil, "")
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	task.Status = status
+	task.CompletedAt = completedAt
+	task.Modified = modified
+	if err := denote.SaveTask(nil, task); err != nil {
+		t.Fatalf("SaveTask: %v", err)
+	}
+	return task
+}
+
+func TestArchiveCompleted(t *testing.T) {
+	dir := t.TempDir()
+	recent := time.Now().Add(-24 * time.Hour).Format(time.RFC3339)
+
+	old := finishedTask(t, dir, "Old done", denote.TaskStatusDone, "2024-06-01T10:00:00Z", recent)
+	dropped := finishedTask(t, dir, "Dropped", denote.TaskStatusDropped, "", "2025-03-01T10:00:00Z")
+	fresh := finishedTask(t, dir, "Fresh done", denote.TaskStatusDone, recent, recent)
+	open := finishedTask(t, dir, "Still open", denote.TaskStatusOpen, "", "2024-01-01T10:00:00Z")
+
+	project, err := CreateProject(nil, dir, "Finished project", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	project.Status = denote.ProjectStatusCompleted
+	project.Modified = "2023-09-01T10:00:00Z"
+	if err := denote.SaveProject(nil, project); err != nil {
+		t.Fatalf("SaveProject: %v", err)
+	}
+
+	want := map[string]string{
+		project.FilePath: filepath.Join(dir, denote.ArchiveDir, "2023", filepath.Base(project.FilePath)),
+		old.FilePath:     filepath.Join(dir, denote.ArchiveDir, "2024", filepath.Base(old.FilePath)),
+		dropped.FilePath: filepath.Join(dir, denote.ArchiveDir, "2025", filepath.Base(dropped.FilePath)),
+	}
+	check := func(files []ArchivedFile) {
+		t.Helper()
+		if len(files) != len(want) {
+			t.Fatalf("archived %d files, want %d: %+v", len(files), len(want), files)
+		}
+		for i, f := range files {
+			if want[f.From] != f.To {
+				t.Errorf("%s archived to %s, want %s", filepath.Base(f.From), f.To, want[f.From])
+			}
+			if i > 0 && f.CompletedAt.Before(files[i-1].CompletedAt) {
+				t.Errorf("files not in completion order")
+			}
+		}
+	}
+
+	// A dry run only reports
+	files, err := ArchiveCompleted(nil, dir, 30*24*time.Hour, true)
+	if err != nil {
+		t.Fatalf("ArchiveCompleted dry run: %v", err)
+	}
+	check(files)
+	for from := range want {
+		if _, err := os.Stat(from); err != nil {
+			t.Errorf("dry run moved %s", filepath.Base(from))
+		}
+	}
+
+	files, err = ArchiveCompleted(nil, dir, 30*24*time.Hour, false)
+	if err != nil {
+		t.Fatalf("ArchiveCompleted: %v", err)
+	}
+	check(files)
+	for from, to := range want {
+		if _, err := os.Stat(from); !os.IsNotExist(err) {
+			t.Errorf("%s still at its old path", filepath.Base(from))
+		}
+		if _, err := os.Stat(to); err != nil {
+			t.Errorf("%s not in the archive: %v", filepath.Base(from), err)
+		}
+	}
+	for _, kept := range []string{fresh.FilePath, open.FilePath} {
+		if _, err := os.Stat(kept); err != nil {
+			t.Errorf("%s was archived", filepath.Base(kept))
+		}
+	}
+}
+
+func TestArchiveRefusesToOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	task := finishedTask(t, dir, "Done", denote.TaskStatusDone, "2024-06-01T10:00:00Z", "")
+	target := filepath.Join(dir, denote.ArchiveDir, "2024", filepath.Base(task.FilePath))
+	os.MkdirAll(filepath.Dir(target), 0755)
+	if err := os.WriteFile(target, []byte("in the way"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ArchiveCompleted(nil, dir, time.Hour, false)
+	if err == nil || len(files) != 0 {
+		t.Fatalf("ArchiveCompleted onto an existing file = %v, %v; want an error", files, err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "in the way" {
+		t.Errorf("archived file was overwritten")
+	}
+	if _, err := os.Stat(task.FilePath); err != nil {
+		t.Errorf("task moved despite the error: %v", err)
+	}
+}
+
+func TestArchiveWaitsForWriters(t *testing.T) {
+	dir := t.TempDir()
+	task := finishedTask(t, dir, "Done", denote.TaskStatusDone, "2024-06-01T10:00:00Z", "")
+
+	// A writer in another process holds the file while it is archived
+	unlock, err := denote.LockFile(task.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	held := 200 * time.Millisecond
+	go func() {
+		time.Sleep(held)
+		unlock()
+	}()
+
+	start := time.Now()
+	if _, err := ArchiveCompleted(nil, dir, time.Hour, false); err != nil {
+		t.Fatalf("ArchiveCompleted: %v", err)
+	}
+	if waited := time.Since(start); waited < held {
+		t.Errorf("archive finished after %v, before the writer released the file", waited)
+	}
+}
@@ -0,0 +1,113 @@
+package task
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+// Outcomes of an instance in a recurring series.
+const (
+	OutcomeOnTime  = "on time"
+	OutcomeLate    = "late"
+	OutcomeSkipped = "skipped"
+	OutcomePending = "pending"
+)
+
+// SeriesEntry is one instance of a recurring series.
+type SeriesEntry struct {
+	IndexID     int    `json:"index_id"`
+	ID          string `json:"id"`
+	DueDate     string `json:"due_date,omitempty"`
+	Status      string `json:"status"`
+	CompletedAt string `json:"completed_at,omitempty"`
+	Outcome     string `json:"outcome"`
+	DaysLate    int    `json:"days_late,omitempty"`
+}
+
+// Series is the history of a recurring task: every instance sharing a
+// series ID, oldest first, with how often it was done on time.
+type Series struct {
+	ID         string        `json:"series_id"`
+	Title      string        `json:"title"`
+	Recur      string        `json:"recur,omitempty"`
+	Entries    []SeriesEntry `json:"instances"`
+	OnTime     int           `json:"on_time"`
+	Late       int           `json:"late"`
+	Skipped    int           `json:"skipped"`
+	OnTimeRate float64       `json:"on_time_rate"` // on time / (on time + late + skipped)
+	Streak     int           `json:"streak"`       // most recent instances done on time in a row
+}
+
+// FindSeries collects the instances of the series with the given ID,
+// including archived ones. An instance done on or before its due date is
+// on time, one done after it late, and a dropped instance (see atask skip)
+// skipped.
+func FindSeries(dir, seriesID string) (*Series, error) {
+	scanner := denote.NewScanner(dir)
+	scanner.FrontmatterOnly = true
+	scanner.IncludeArchived = true
+
+	tasks, err := scanner.FindTasks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan tasks: %w", err)
+	}
+
+	var members []*denote.Task
+	for _, t := range tasks {
+		if t.Series() == seriesID {
+			members = append(members, t)
+		}
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("series %s not found", seriesID)
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		if members[i].DueDate != members[j].DueDate {
+			return members[i].DueDate < members[j].DueDate
+		}
+		return members[i].IndexID < members[j].IndexID
+	})
+
+	latest := members[len(members)-1]
+	s := &Series{ID: seriesID, Title: latest.Title, Recur: latest.Recur, Entries: []SeriesEntry{}}
+	for _, t := range members {
+		e := SeriesEntry{IndexID: t.IndexID, ID: t.ID, DueDate: t.DueDate, Status: t.Status, Outcome: OutcomePending}
+		switch t.Status {
+		case denote.TaskStatusDone:
+			finished := finishedAt(t.CompletedAt, t.Entity, t.ModTime).Local()
+			e.CompletedAt = finished.Format("2006-01-02")
+			e.Outcome = OutcomeOnTime
+			if due, err := time.ParseInLocation("2006-01-02", t.DueDate, time.Local); err == nil {
+				done := time.Date(finished.Year(), finished.Month(), finished.Day(), 0, 0, 0, 0, time.Local)
+				if done.After(due) {
+					e.Outcome = OutcomeLate
+					e.DaysLate = int(math.Round(done.Sub(due).Hours() / 24))
+				}
+			}
+		case denote.TaskStatusDropped:
+			e.Outcome = OutcomeSkipped
+		}
+
+		switch e.Outcome {
+		case OutcomeOnTime:
+			s.OnTime++
+			s.Streak++
+		case OutcomeLate:
+			s.Late++
+			s.Streak = 0
+		case OutcomeSkipped:
+			s.Skipped++
+			s.Streak = 0
+		}
+		s.Entries = append(s.Entries, e)
+	}
+
+	if finished := s.OnTime + s.Late + s.Skipped; finished > 0 {
+		s.OnTimeRate = math.Round(float64(s.OnTime)/float64(finished)*1000) / 1000
+	}
+	return s, nil
+}
@@ -0,0 +1,83 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/recurrence"
+)
+
+func TestFindSeries(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := CreateTask(nil, dir, "Water plants", "", nil, "")
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	first.DueDate = "2026-03-02"
+	first.Recur = "every 2w"
+
+	// done on time, done late, skipped, done on time, then still open
+	finish := []struct {
+		status, completed string
+	}{
+		{denote.TaskStatusDone, "2026-03-02T18:00:00Z"},
+		{denote.TaskStatusDone, "2026-03-19T09:00:00Z"},
+		{denote.TaskStatusDropped, "2026-03-30T09:00:00Z"},
+		{denote.TaskStatusDone, "2026-04-10T09:00:00Z"},
+	}
+	dues := []string{"2026-03-16", "2026-03-30", "2026-04-13", "2026-04-27"}
+
+	current := first
+	for i, f := range finish {
+		current.SetStatus(f.status)
+		current.CompletedAt = f.completed
+		if err := UpdateTaskFile(nil, current.FilePath, current); err != nil {
+			t.Fatalf("UpdateTaskFile: %v", err)
+		}
+		due, _ := time.ParseInLocation("2006-01-02", dues[i], time.Local)
+		next, err := CloneTaskForRecurrence(nil, dir, current, recurrence.Instance{Due: due, Scheduled: due, Pattern: current.Recur})
+		if err != nil {
+			t.Fatalf("CloneTaskForRecurrence: %v", err)
+		}
+		if next.SeriesID != first.ID {
+			t.Fatalf("instance %d has series_id %q, want %q", i+2, next.SeriesID, first.ID)
+		}
+		current = next
+	}
+
+	// An unrelated task stays out of the series
+	if _, err := CreateTask(nil, dir, "Water plants", "", nil, ""); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	s, err := FindSeries(dir, current.Series())
+	if err != nil {
+		t.Fatalf("FindSeries: %v", err)
+	}
+	var outcomes []string
+	for _, e := range s.Entries {
+		outcomes = append(outcomes, e.Outcome)
+	}
+	want := []string{OutcomeOnTime, OutcomeLate, OutcomeSkipped, OutcomeOnTime, OutcomePending}
+	if len(outcomes) != len(want) {
+		t.Fatalf("outcomes = %q, want %q", outcomes, want)
+	}
+	for i := range want {
+		if outcomes[i] != want[i] {
+			t.Fatalf("outcomes = %q, want %q", outcomes, want)
+		}
+	}
+	if s.Entries[1].DaysLate != 3 {
+		t.Errorf("late instance DaysLate = %d, want 3", s.Entries[1].DaysLate)
+	}
+	if s.OnTime != 2 || s.Late != 1 || s.Skipped != 1 || s.OnTimeRate != 0.5 || s.Streak != 1 {
+		t.Errorf("series stats = %d on time, %d late, %d skipped, rate %v, streak %d; want 2, 1, 1, 0.5, 1",
+			s.OnTime, s.Late, s.Skipped, s.OnTimeRate, s.Streak)
+	}
+
+	if _, err := FindSeries(dir, "no-such-series"); err == nil {
+		t.Error("FindSeries of an unknown ID should fail")
+	}
+}
@@ -0,0 +1,307 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mph-llm-experiments/acore"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/recurrence"
+)
+
+// Service creates and updates the tasks in a notes directory. It checks
+// its input before writing anything and reports bad values as a
+// *FieldError, so callers such as the action queue get the task or a
+// typed error rather than command output to parse.
+type Service struct {
+	Dir string
+	Op  *denote.Op // journals the service's writes; nil records nothing
+}
+
+// NewService returns a service for the tasks in dir whose writes are
+// journaled under op.
+func NewService(dir string, op *denote.Op) *Service {
+	return &Service{Dir: dir, Op: op}
+}
+
+// FieldError reports an invalid value for one field of a request.
+type FieldError struct {
+	Field   string
+	Value   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("invalid %s %q: %s", e.Field, e.Value, e.Message)
+}
+
+// CreateRequest describes a new task. Empty fields are left unset.
+type CreateRequest struct {
+	Title    string
+	Priority string
+	Due      string // YYYY-MM-DD or natural language
+	Area     string
+	Project  string // project index_id
+	Tags     []string
+	Estimate int
+	Recur    string
+	People   []string // ULIDs of related contacts
+}
+
+// UpdateRequest describes changes to a task. Empty fields are left as
+// they are.
+type UpdateRequest struct {
+	Title    string
+	Status   string
+	Priority string
+	Due      string // YYYY-MM-DD or natural language
+	Area     string
+	Project  string // project index_id
+	PlanFor  string // natural language or YYYY-MM-DD; "none" clears it
+	People   []string
+}
+
+// Create writes a new task and returns it as saved.
+func (s *Service) Create(req CreateRequest) (*denote.Task, error) {
+	if strings.TrimSpace(req.Title) == "" {
+		return nil, &FieldError{Field: "title", Message: "must not be empty"}
+	}
+	if err := checkPriority(req.Priority); err != nil {
+		return nil, err
+	}
+	if req.Estimate < 0 {
+		return nil, &FieldError{Field: "estimate", Value: strconv.Itoa(req.Estimate), Message: "must not be negative"}
+	}
+	due, err := parseDate("due", req.Due)
+	if err != nil {
+		return nil, err
+	}
+	var recur string
+	if req.Recur != "" {
+		if due == "" {
+			return nil, &FieldError{Field: "recur", Value: req.Recur, Message: "a due date is required when recur is set"}
+		}
+		if recur, err = recurrence.ParsePattern(req.Recur); err != nil {
+			return nil, &FieldError{Field: "recur", Value: req.Recur, Message: err.Error()}
+		}
+	}
+	projectID, err := s.projectID(req.Project)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := CreateTask(s.Op, s.Dir, req.Title, "", req.Tags, req.Area)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+	if req.Priority == "" && due == "" && projectID == "" && req.Estimate == 0 && recur == "" && len(req.People) == 0 {
+		return created, nil
+	}
+
+	created.TaskMetadata.Priority = req.Priority
+	created.TaskMetadata.DueDate = due
+	created.TaskMetadata.ProjectID = projectID
+	created.TaskMetadata.Estimate = req.Estimate
+	created.TaskMetadata.Recur = recur
+	addPeople(created, req.People)
+	if err := UpdateTaskFile(s.Op, created.FilePath, created); err != nil {
+		return nil, fmt.Errorf("failed to update task metadata: %w", err)
+	}
+	return denote.ParseTaskFile(created.FilePath)
+}
+
+// Update applies req to the task with the given index_id or ULID and
+// returns it as saved.
+func (s *Service) Update(id string, req UpdateRequest) (*denote.Task, error) {
+	t, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Status != "" && !denote.IsValidTaskStatus(req.Status) {
+		return nil, &FieldError{Field: "status", Value: req.Status, Message: "use open, done, paused, delegated or dropped"}
+	}
+	if err := checkPriority(req.Priority); err != nil {
+		return nil, err
+	}
+	due, err := parseDate("due", req.Due)
+	if err != nil {
+		return nil, err
+	}
+	var planFor string
+	if !strings.EqualFold(req.PlanFor, "none") {
+		if planFor, err = parseDate("plan_for", req.PlanFor); err != nil {
+			return nil, err
+		}
+	}
+	projectID, err := s.projectID(req.Project)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != "" {
+		t.Title = req.Title
+	}
+	if req.Status != "" {
+		t.SetStatus(req.Status)
+	}
+	if req.Priority != "" {
+		t.TaskMetadata.Priority = req.Priority
+	}
+	if due != "" {
+		t.SetDueDate(due)
+	}
+	if req.Area != "" {
+		t.TaskMetadata.Area = req.Area
+	}
+	if projectID != "" {
+		t.TaskMetadata.ProjectID = projectID
+	}
+	if req.PlanFor != "" {
+		t.PlannedFor = planFor
+	}
+	addPeople(t, req.People)
+
+	if err := UpdateTaskFile(s.Op, t.FilePath, t); err != nil {
+		return nil, fmt.Errorf("failed to update task %d: %w", t.IndexID, err)
+	}
+	return denote.ParseTaskFile(t.FilePath)
+}
+
+// ActionResult is the outcome of running a built-in action.
+type ActionResult struct {
+	Task    *denote.Task `json:"task"`
+	Created bool         `json:"created"`
+}
+
+// IsBuiltinAction reports whether the service runs the action type itself.
+func IsBuiltinAction(actionType string) bool {
+	return actionType == denote.ActionTypeTaskCreate || actionType == denote.ActionTypeTaskUpdate
+}
+
+// RunAction carries out a task_create or task_update action from its
+// fields. The add_person field takes comma-separated ULIDs.
+func (s *Service) RunAction(action *denote.Action) (*ActionResult, error) {
+	f := action.Fields
+	switch action.ActionType {
+	case denote.ActionTypeTaskCreate:
+		req := CreateRequest{
+			Title:    f["title"],
+			Priority: f["priority"],
+			Due:      f["due"],
+			Area:     f["area"],
+			Project:  f["project"],
+			Tags:     splitList(f["tags"]),
+			Recur:    f["recur"],
+			People:   splitList(f["add_person"]),
+		}
+		if req.Title == "" {
+			req.Title = action.Title
+		}
+		if v := f["estimate"]; v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, &FieldError{Field: "estimate", Value: v, Message: "must be a number"}
+			}
+			req.Estimate = n
+		}
+		t, err := s.Create(req)
+		if err != nil {
+			return nil, err
+		}
+		return &ActionResult{Task: t, Created: true}, nil
+
+	case denote.ActionTypeTaskUpdate:
+		target := f["target_id"]
+		if target == "" {
+			return nil, &FieldError{Field: "target_id", Message: "required for task_update"}
+		}
+		t, err := s.Update(target, UpdateRequest{
+			Title:    f["title"],
+			Status:   f["status"],
+			Priority: f["priority"],
+			Due:      f["due"],
+			Area:     f["area"],
+			Project:  f["project"],
+			PlanFor:  f["plan_for"],
+			People:   splitList(f["add_person"]),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &ActionResult{Task: t}, nil
+	}
+	return nil, fmt.Errorf("%s is not a built-in action type", action.ActionType)
+}
+
+// find looks a task up by index_id, falling back to its ULID.
+func (s *Service) find(id string) (*denote.Task, error) {
+	if num, err := strconv.Atoi(id); err == nil {
+		if t, err := FindTaskByID(s.Dir, num); err == nil {
+			return t, nil
+		}
+	}
+	return FindTaskByEntityID(s.Dir, id)
+}
+
+// projectID resolves a project index_id to the value stored in a task's
+// project_id. An empty id gives an empty result.
+func (s *Service) projectID(id string) (string, error) {
+	if id == "" {
+		return "", nil
+	}
+	num, err := strconv.Atoi(id)
+	if err != nil {
+		return "", &FieldError{Field: "project", Value: id, Message: "must be a numeric index_id"}
+	}
+	p, err := FindProjectByID(s.Dir, num)
+	if err != nil {
+		return "", &FieldError{Field: "project", Value: id, Message: "project not found"}
+	}
+	return strconv.Itoa(p.IndexID), nil
+}
+
+func checkPriority(priority string) error {
+	if priority != "" && !denote.IsValidPriority(priority) {
+		return &FieldError{Field: "priority", Value: priority, Message: "use p1, p2 or p3"}
+	}
+	return nil
+}
+
+// parseDate parses a natural-language or YYYY-MM-DD date field. An empty
+// value gives an empty result.
+func parseDate(field, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	parsed, err := denote.ParseNaturalDate(value)
+	if err != nil {
+		return "", &FieldError{Field: field, Value: value, Message: err.Error()}
+	}
+	// ParseNaturalDate passes through what it does not recognize
+	if _, err := time.Parse("2006-01-02", parsed); err != nil {
+		return "", &FieldError{Field: field, Value: value, Message: "not a date (try: 2d, 1w, friday, jan 15, 2024-01-15)"}
+	}
+	return parsed, nil
+}
+
+// addPeople links the task to contacts, on both sides of the relation.
+func addPeople(t *denote.Task, people []string) {
+	for _, person := range people {
+		acore.AddRelation(&t.RelatedPeople, person)
+		acore.SyncRelation(t.Type, t.ID, person)
+	}
+}
+
+// splitList splits a comma-separated field, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,85 @@
+package task
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+func TestServiceRunAction(t *testing.T) {
+	dir := t.TempDir()
+	svc := NewService(dir, nil)
+
+	project, err := CreateProject(nil, dir, "Garden", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	projectRef := strconv.Itoa(project.IndexID)
+
+	create := &denote.Action{}
+	create.Title = "Plant tomatoes"
+	create.ActionType = denote.ActionTypeTaskCreate
+	create.Fields = map[string]string{
+		"priority": "p2",
+		"due":      "2099-05-01",
+		"project":  projectRef,
+		"tags":     "garden, spring",
+		"estimate": "3",
+		"recur":    "yearly",
+	}
+	result, err := svc.RunAction(create)
+	if err != nil {
+		t.Fatalf("RunAction(task_create): %v", err)
+	}
+	created := result.Task
+	if !result.Created || created.Title != "Plant tomatoes" || created.Priority != "p2" || created.DueDate != "2099-05-01" ||
+		created.ProjectID != projectRef || created.Estimate != 3 || created.Recur != "yearly" {
+		t.Errorf("created task = %+v", created.TaskMetadata)
+	}
+	if len(created.Tags) != 3 || created.Tags[1] != "garden" || created.Tags[2] != "spring" {
+		t.Errorf("tags = %v", created.Tags)
+	}
+
+	update := &denote.Action{}
+	update.ActionType = denote.ActionTypeTaskUpdate
+	update.Fields = map[string]string{"target_id": strconv.Itoa(created.IndexID), "priority": "p1", "status": "paused"}
+	result, err = svc.RunAction(update)
+	if err != nil {
+		t.Fatalf("RunAction(task_update): %v", err)
+	}
+	if result.Created || result.Task.Priority != "p1" || result.Task.Status != denote.TaskStatusPaused {
+		t.Errorf("updated task = %+v", result.Task.TaskMetadata)
+	}
+	if reread, err := denote.ParseTaskFile(created.FilePath); err != nil || reread.Priority != "p1" {
+		t.Errorf("update not saved: %v", err)
+	}
+
+	bad := []struct {
+		fields map[string]string
+		field  string
+	}{
+		{map[string]string{"target_id": created.ID, "priority": "urgent"}, "priority"},
+		{map[string]string{"target_id": created.ID, "status": "finished"}, "status"},
+		{map[string]string{"target_id": created.ID, "due": "someday soon"}, "due"},
+		{map[string]string{"target_id": created.ID, "project": "garden"}, "project"},
+		{map[string]string{"priority": "p1"}, "target_id"},
+	}
+	for _, b := range bad {
+		update.Fields = b.fields
+		_, err := svc.RunAction(update)
+		if fe, ok := err.(*FieldError); !ok || fe.Field != b.field {
+			t.Errorf("RunAction(%v) error = %v, want a %s FieldError", b.fields, err, b.field)
+		}
+	}
+
+	// Invalid input is rejected before a task is written
+	create.Fields = map[string]string{"recur": "weekly"}
+	if _, err := svc.RunAction(create); err == nil {
+		t.Error("task_create with recur but no due date succeeded")
+	}
+	tasks, err := denote.NewScanner(dir).FindTasks()
+	if err != nil || len(tasks) != 1 {
+		t.Errorf("found %d tasks, want 1 (%v)", len(tasks), err)
+	}
+}
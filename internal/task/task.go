@@ -5,9 +5,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/mph-llm-experiments/acore"
 	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/policy"
+	"github.com/mph-llm-experiments/atask/internal/recurrence"
 )
 
 // storeAndName creates a LocalStore from the directory of an absolute path
@@ -16,8 +19,17 @@ func storeAndName(path string) (acore.Store, string) {
 	return acore.NewLocalStore(filepath.Dir(path)), filepath.Base(path)
 }
 
-// CreateTask creates a new task file with YAML frontmatter using acore conventions.
-func CreateTask(dir, title, content string, tags []string, area string) (*denote.Task, error) {
+// CreateTask creates a new task file with YAML frontmatter using acore
+// conventions, journaled under op.
+func CreateTask(op *denote.Op, dir, title, content string, tags []string, area string) (*denote.Task, error) {
+	// Hold the directory lock while drawing an index_id so concurrent
+	// creators (CLI, TUI, agents) can't be handed the same number.
+	unlock, err := denote.LockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	// Get ID counter
 	store := acore.NewLocalStore(dir)
 	counter, err := acore.NewIndexCounter(store, "atask")
@@ -57,13 +69,23 @@ func CreateTask(dir, title, content string, tags []string, area string) (*denote
 	if err := acore.WriteFile(store, filename, task, content); err != nil {
 		return nil, fmt.Errorf("failed to write task file: %w", err)
 	}
+	if err := denote.RecordChange(op, filepath, nil); err != nil {
+		return nil, err
+	}
 
 	// Return the created task
 	return denote.ParseTaskFile(filepath)
 }
 
-// CreateProject creates a new project file with YAML frontmatter using acore conventions.
-func CreateProject(dir, title, content string, tags []string) (*denote.Project, error) {
+// CreateProject creates a new project file with YAML frontmatter using acore
+// conventions, journaled under op.
+func CreateProject(op *denote.Op, dir, title, content string, tags []string) (*denote.Project, error) {
+	unlock, err := denote.LockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	store := acore.NewLocalStore(dir)
 	counter, err := acore.NewIndexCounter(store, "atask")
 	if err != nil {
@@ -100,6 +122,9 @@ func CreateProject(dir, title, content string, tags []string) (*denote.Project,
 	if err := acore.WriteFile(store, filename, project, content); err != nil {
 		return nil, fmt.Errorf("failed to write project file: %w", err)
 	}
+	if err := denote.RecordChange(op, filepath, nil); err != nil {
+		return nil, err
+	}
 
 	return denote.ParseProjectFile(filepath)
 }
@@ -107,74 +132,77 @@ func CreateProject(dir, title, content string, tags []string) (*denote.Project,
 // FindTaskByID finds a task by its sequential ID
 func FindTaskByID(dir string, id int) (*denote.Task, error) {
 	scanner := denote.NewScanner(dir)
-	tasks, err := scanner.FindTasks()
+	task, err := scanner.LookupTask(func(task *denote.Task) bool {
+		return task.IndexID == id
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	for _, task := range tasks {
-		if task.IndexID == id {
-			return task, nil
-		}
+	if task == nil {
+		return nil, fmt.Errorf("task %d not found", id)
 	}
 
-	return nil, fmt.Errorf("task %d not found", id)
+	return task, nil
 }
 
 // FindProjectByID finds a project by its sequential ID
 func FindProjectByID(dir string, id int) (*denote.Project, error) {
 	scanner := denote.NewScanner(dir)
-	projects, err := scanner.FindProjects()
+	project, err := scanner.LookupProject(func(project *denote.Project) bool {
+		return project.IndexID == id
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	for _, project := range projects {
-		if project.IndexID == id {
-			return project, nil
-		}
+	if project == nil {
+		return nil, fmt.Errorf("project %d not found", id)
 	}
 
-	return nil, fmt.Errorf("project %d not found", id)
+	return project, nil
 }
 
 // FindTaskByEntityID finds a task by its ULID (or legacy Denote ID)
 func FindTaskByEntityID(dir string, entityID string) (*denote.Task, error) {
 	scanner := denote.NewScanner(dir)
-	tasks, err := scanner.FindTasks()
+	task, err := scanner.LookupTask(func(task *denote.Task) bool {
+		return task.ID == entityID
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	for _, task := range tasks {
-		if task.ID == entityID {
-			return task, nil
-		}
+	if task == nil {
+		return nil, fmt.Errorf("task with ID %s not found", entityID)
 	}
 
-	return nil, fmt.Errorf("task with ID %s not found", entityID)
+	return task, nil
 }
 
 // FindProjectByEntityID finds a project by its ULID (or legacy Denote ID)
 func FindProjectByEntityID(dir string, entityID string) (*denote.Project, error) {
 	scanner := denote.NewScanner(dir)
-	projects, err := scanner.FindProjects()
+	project, err := scanner.LookupProject(func(project *denote.Project) bool {
+		return project.ID == entityID
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	for _, project := range projects {
-		if project.ID == entityID {
-			return project, nil
-		}
+	if project == nil {
+		return nil, fmt.Errorf("project with ID %s not found", entityID)
 	}
 
-	return nil, fmt.Errorf("project with ID %s not found", entityID)
+	return project, nil
 }
 
 // CloneTaskForRecurrence creates a new task based on an existing recurring task
-// with a new due date.
-func CloneTaskForRecurrence(dir string, original *denote.Task, newDueDate string) (*denote.Task, error) {
+// for the next instance computed by recurrence.NextInstance, with its due
+// date and recurrence pattern. The new task joins the original's series.
+func CloneTaskForRecurrence(op *denote.Op, dir string, original *denote.Task, next recurrence.Instance) (*denote.Task, error) {
+	unlock, err := denote.LockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	store := acore.NewLocalStore(dir)
 	counter, err := acore.NewIndexCounter(store, "atask")
 	if err != nil {
@@ -200,12 +228,16 @@ func CloneTaskForRecurrence(dir string, original *denote.Task, newDueDate string
 	task.Modified = now
 	task.Status = denote.TaskStatusOpen
 	task.Priority = original.TaskMetadata.Priority
-	task.DueDate = newDueDate
+	task.DueDate = next.Due.Format("2006-01-02")
+	if !next.Scheduled.Equal(next.Due) {
+		task.ScheduledDate = next.Scheduled.Format("2006-01-02")
+	}
 	task.Estimate = original.TaskMetadata.Estimate
 	task.ProjectID = original.TaskMetadata.ProjectID
 	task.Area = original.TaskMetadata.Area
 	task.Assignee = original.TaskMetadata.Assignee
-	task.Recur = original.TaskMetadata.Recur
+	task.Recur = next.Pattern
+	task.SeriesID = original.Series()
 	// StartDate and TodayDate intentionally left empty
 
 	filename := acore.BuildFilename(id, original.Title, "task")
@@ -218,6 +250,9 @@ func CloneTaskForRecurrence(dir string, original *denote.Task, newDueDate string
 	if err := acore.WriteFile(store, filename, task, body); err != nil {
 		return nil, fmt.Errorf("failed to write cloned task: %w", err)
 	}
+	if err := denote.RecordChange(op, filepath, nil); err != nil {
+		return nil, err
+	}
 
 	return denote.ParseTaskFile(filepath)
 }
@@ -235,13 +270,22 @@ func extractBody(content string) string {
 	return rest[idx+3:]
 }
 
-// CreateAction creates a new action file in the queue/ subdirectory.
-func CreateAction(dir, title, actionType, proposedBy, body string, fields map[string]string) (*denote.Action, error) {
+// CreateAction creates a new action file in the queue/ subdirectory. If
+// pol is not nil it decides the action, under the same lock that numbers
+// and writes it so concurrent proposals are counted against rate limits,
+// and the decision is recorded in the action's frontmatter.
+func CreateAction(op *denote.Op, dir, title, actionType, proposedBy, body string, fields map[string]string, pol *policy.Policy) (*denote.Action, error) {
 	queueDir := filepath.Join(dir, "queue")
 	if err := os.MkdirAll(queueDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create queue directory: %w", err)
 	}
 
+	unlock, err := denote.LockDir(queueDir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	queueStore := acore.NewLocalStore(queueDir)
 	counter, err := acore.NewIndexCounter(queueStore, "atask-action")
 	if err != nil {
@@ -270,6 +314,23 @@ func CreateAction(dir, title, actionType, proposedBy, body string, fields map[st
 	action.ProposedBy = proposedBy
 	action.Fields = fields
 
+	if pol != nil {
+		var history []*denote.Action
+		if pol.NeedsHistory() {
+			scanner := denote.NewScanner(dir)
+			if history, err = scanner.FindActions(); err != nil {
+				return nil, fmt.Errorf("failed to scan actions: %w", err)
+			}
+			archived, err := scanner.FindArchivedActions()
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan archived actions: %w", err)
+			}
+			history = append(history, archived...)
+		}
+		decision := pol.Evaluate(action, history, time.Now())
+		action.Policy = &decision
+	}
+
 	filename := acore.BuildFilename(id, title, "action")
 	fp := filepath.Join(queueDir, filename)
 	action.FilePath = fp
@@ -277,6 +338,9 @@ func CreateAction(dir, title, actionType, proposedBy, body string, fields map[st
 	if err := acore.WriteFile(queueStore, filename, action, body); err != nil {
 		return nil, fmt.Errorf("failed to write action file: %w", err)
 	}
+	if err := denote.RecordChange(op, fp, nil); err != nil {
+		return nil, err
+	}
 
 	return denote.ParseActionFile(fp)
 }
@@ -284,46 +348,44 @@ func CreateAction(dir, title, actionType, proposedBy, body string, fields map[st
 // FindActionByID finds an action by its index_id in the queue/ subdirectory.
 func FindActionByID(dir string, id int) (*denote.Action, error) {
 	scanner := denote.NewScanner(dir)
-	actions, err := scanner.FindActions()
+	action, err := scanner.LookupAction(func(action *denote.Action) bool {
+		return action.IndexID == id
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	for _, action := range actions {
-		if action.IndexID == id {
-			return action, nil
-		}
+	if action == nil {
+		return nil, fmt.Errorf("action %d not found", id)
 	}
 
-	return nil, fmt.Errorf("action %d not found", id)
+	return action, nil
 }
 
 // FindActionByEntityID finds an action by its ULID.
 func FindActionByEntityID(dir string, entityID string) (*denote.Action, error) {
 	scanner := denote.NewScanner(dir)
-	actions, err := scanner.FindActions()
+	action, err := scanner.LookupAction(func(action *denote.Action) bool {
+		return action.ID == entityID
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	for _, action := range actions {
-		if action.ID == entityID {
-			return action, nil
-		}
+	if action == nil {
+		return nil, fmt.Errorf("action with ID %s not found", entityID)
 	}
 
-	return nil, fmt.Errorf("action with ID %s not found", entityID)
+	return action, nil
 }
 
 // ArchiveAction moves an action file to the queue/archive/ subdirectory.
-func ArchiveAction(dir string, action *denote.Action) error {
+func ArchiveAction(op *denote.Op, dir string, action *denote.Action) error {
 	archiveDir := filepath.Join(dir, "queue", "archive")
 	if err := os.MkdirAll(archiveDir, 0755); err != nil {
 		return fmt.Errorf("failed to create archive directory: %w", err)
 	}
 
 	newPath := filepath.Join(archiveDir, filepath.Base(action.FilePath))
-	if err := os.Rename(action.FilePath, newPath); err != nil {
+	if err := denote.MoveFile(op, action.FilePath, newPath); err != nil {
 		return fmt.Errorf("failed to archive action: %w", err)
 	}
 
@@ -0,0 +1,52 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/policy"
+)
+
+func TestCreateActionRateLimitConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(t.TempDir(), policy.FileName)
+	rules := "[[rule]]\nname = \"bumps\"\naction_type = \"task_update\"\ndecision = \"approve\"\nrate_limit = \"2/day\"\n"
+	if err := os.WriteFile(file, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pol, err := policy.Load(file)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	// Proposals racing each other must not all see room under the limit
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fields := map[string]string{"target_id": "1", "priority": "p1"}
+			if _, err := CreateAction(nil, dir, "Bump", "task_update", "agent-1", "", fields, pol); err != nil {
+				t.Errorf("CreateAction: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	actions, err := denote.NewScanner(dir).FindActions()
+	if err != nil {
+		t.Fatalf("FindActions: %v", err)
+	}
+	approved := 0
+	for _, a := range actions {
+		if a.Policy != nil && a.Policy.Decision == policy.Approve {
+			approved++
+		}
+	}
+	if len(actions) != 6 || approved != 2 {
+		t.Errorf("%d actions with %d approved, want 6 with 2", len(actions), approved)
+	}
+}
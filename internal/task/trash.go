@@ -0,0 +1,272 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+// TrashDir is the subdirectory of the notes directory that holds deleted
+// tasks and projects until they are restored or the trash is emptied.
+const TrashDir = ".trash"
+
+// trashMetaSuffix is appended to a trashed file's name for its sidecar.
+const trashMetaSuffix = ".json"
+
+// ClearedRef records a task whose project_id was cleared when its project
+// was moved to the trash, so a restore can link it back.
+type ClearedRef struct {
+	TaskID    string `json:"task_id"`
+	IndexID   int    `json:"index_id"`
+	Title     string `json:"title"`
+	ProjectID string `json:"project_id"`
+}
+
+// TrashEntry describes a file in the trash.
+type TrashEntry struct {
+	Name         string       `json:"name"`
+	Path         string       `json:"path"`
+	OriginalPath string       `json:"original_path"`
+	Type         string       `json:"type"`
+	ID           string       `json:"id"`
+	IndexID      int          `json:"index_id"`
+	Title        string       `json:"title"`
+	DeletedAt    time.Time    `json:"deleted_at"`
+	Cleared      []ClearedRef `json:"cleared,omitempty"`
+}
+
+// Trash moves a task or project file into the trash. Deleting a project also
+// clears project_id on every task that points at it; those tasks are
+// recorded in the entry so RestoreFromTrash can re-link them.
+func Trash(op *denote.Op, dir, path string) (*TrashEntry, error) {
+	t, err := denote.ParseTaskFrontmatter(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	entry := &TrashEntry{
+		Name:      filepath.Base(path),
+		Type:      t.Type,
+		ID:        t.ID,
+		IndexID:   t.IndexID,
+		Title:     t.Title,
+		DeletedAt: time.Now(),
+	}
+
+	if t.Type == denote.TypeProject {
+		cleared, err := clearProjectRefs(op, dir, t.ID, t.IndexID)
+		if err != nil {
+			return nil, err
+		}
+		entry.Cleared = cleared
+	}
+
+	unlock, err := denote.LockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	trashDir := filepath.Join(dir, TrashDir)
+	if err := os.MkdirAll(trashDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create trash directory: %w", err)
+	}
+
+	entry.Path = filepath.Join(trashDir, entry.Name)
+	entry.OriginalPath = filepath.Join(dir, entry.Name)
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode trash metadata: %w", err)
+	}
+	if err := denote.WriteFileAtomic(op, entry.Path+trashMetaSuffix, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write trash metadata: %w", err)
+	}
+	if err := moveLocked(op, path, entry.Path); err != nil {
+		denote.RemoveFile(op, entry.Path+trashMetaSuffix)
+		return nil, fmt.Errorf("failed to move %s to trash: %w", entry.Name, err)
+	}
+
+	return entry, nil
+}
+
+// clearProjectRefs removes project_id from every task assigned to the
+// project, matching either its index_id or its ULID.
+func clearProjectRefs(op *denote.Op, dir, projectID string, indexID int) ([]ClearedRef, error) {
+	scanner := denote.NewScanner(dir)
+	scanner.FrontmatterOnly = true
+	tasks, err := scanner.FindTasks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan tasks: %w", err)
+	}
+
+	var cleared []ClearedRef
+	for _, t := range tasks {
+		if t.ProjectID == "" || (t.ProjectID != strconv.Itoa(indexID) && t.ProjectID != projectID) {
+			continue
+		}
+		ref := ClearedRef{TaskID: t.ID, IndexID: t.IndexID, Title: t.Title, ProjectID: t.ProjectID}
+		if err := denote.UpdateTaskProjectID(op, t.FilePath, ""); err != nil {
+			return cleared, fmt.Errorf("failed to clear project from task %d: %w", t.IndexID, err)
+		}
+		cleared = append(cleared, ref)
+	}
+	return cleared, nil
+}
+
+// ListTrash returns the entries in the trash, most recently deleted first.
+// Files without a sidecar (for example, moved there by hand) are listed
+// using their frontmatter and modification time.
+func ListTrash(dir string) ([]TrashEntry, error) {
+	trashDir := filepath.Join(dir, TrashDir)
+	dirEntries, err := os.ReadDir(trashDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read trash: %w", err)
+	}
+
+	var entries []TrashEntry
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), ".md") {
+			continue
+		}
+		entry, err := readTrashEntry(dir, de.Name())
+		if err != nil {
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].DeletedAt.After(entries[j].DeletedAt)
+	})
+	return entries, nil
+}
+
+func readTrashEntry(dir, name string) (*TrashEntry, error) {
+	path := filepath.Join(dir, TrashDir, name)
+	entry := &TrashEntry{}
+
+	if data, err := os.ReadFile(path + trashMetaSuffix); err == nil {
+		if err := json.Unmarshal(data, entry); err != nil {
+			return nil, fmt.Errorf("failed to decode trash metadata for %s: %w", name, err)
+		}
+	} else {
+		t, err := denote.ParseTaskFrontmatter(path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		entry.Type = t.Type
+		entry.ID = t.ID
+		entry.IndexID = t.IndexID
+		entry.Title = t.Title
+		entry.DeletedAt = info.ModTime()
+	}
+
+	// Paths are derived rather than trusted so a moved notes directory
+	// still restores into the right place.
+	entry.Name = name
+	entry.Path = path
+	entry.OriginalPath = filepath.Join(dir, name)
+	return entry, nil
+}
+
+// FindInTrash returns the most recently deleted entry matching identifier,
+// which may be an index_id or a ULID.
+func FindInTrash(dir, identifier string) (*TrashEntry, error) {
+	entries, err := ListTrash(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	indexID, numErr := strconv.Atoi(identifier)
+	for i := range entries {
+		e := &entries[i]
+		if (numErr == nil && e.IndexID == indexID) || e.ID == identifier {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("no trashed task or project with ID %s", identifier)
+}
+
+// RestoreFromTrash moves a trashed file back to its original location. For
+// projects, tasks recorded as cleared are linked to the project again unless
+// they have since been assigned elsewhere. It returns the restored entry and
+// the tasks that were re-linked.
+func RestoreFromTrash(op *denote.Op, dir, identifier string) (*TrashEntry, []ClearedRef, error) {
+	entry, err := FindInTrash(dir, identifier)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unlock, err := denote.LockDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := os.Stat(entry.OriginalPath); err == nil {
+		unlock()
+		return nil, nil, fmt.Errorf("cannot restore: %s already exists", entry.Name)
+	}
+	if err := moveLocked(op, entry.Path, entry.OriginalPath); err != nil {
+		unlock()
+		return nil, nil, fmt.Errorf("failed to restore %s: %w", entry.Name, err)
+	}
+	denote.RemoveFile(op, entry.Path+trashMetaSuffix)
+	unlock()
+
+	var relinked []ClearedRef
+	for _, ref := range entry.Cleared {
+		t, err := FindTaskByEntityID(dir, ref.TaskID)
+		if err != nil || t.ProjectID != "" {
+			continue
+		}
+		if err := denote.UpdateTaskProjectID(op, t.FilePath, ref.ProjectID); err != nil {
+			return entry, relinked, fmt.Errorf("failed to re-link task %d: %w", t.IndexID, err)
+		}
+		relinked = append(relinked, ref)
+	}
+
+	return entry, relinked, nil
+}
+
+// EmptyTrash permanently removes entries deleted more than olderThan ago.
+// A zero duration removes everything.
+func EmptyTrash(dir string, olderThan time.Duration) ([]TrashEntry, error) {
+	entries, err := ListTrash(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := denote.LockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	cutoff := time.Now().Add(-olderThan)
+	var removed []TrashEntry
+	for _, e := range entries {
+		if olderThan > 0 && e.DeletedAt.After(cutoff) {
+			continue
+		}
+		if err := os.Remove(e.Path); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove %s: %w", e.Name, err)
+		}
+		os.Remove(e.Path + trashMetaSuffix)
+		removed = append(removed, e)
+	}
+	return removed, nil
+}
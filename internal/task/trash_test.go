@@ -0,0 +1,162 @@
+package task
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+func TestTrashProjectRestoreRelinksTasks(t *testing.T) {
+	dir := t.TempDir()
+
+	project, err := CreateProject(nil, dir, "Garden", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	projectRef := strconv.Itoa(project.IndexID)
+
+	linked, err := CreateTask(nil, dir, "Plant tomatoes", "", nil, "")
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	moved, err := CreateTask(nil, dir, "Buy soil", "", nil, "")
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	for _, path := range []string{linked.FilePath, moved.FilePath} {
+		if err := denote.UpdateTaskProjectID(nil, path, projectRef); err != nil {
+			t.Fatalf("UpdateTaskProjectID: %v", err)
+		}
+	}
+
+	entry, err := Trash(nil, dir, project.FilePath)
+	if err != nil {
+		t.Fatalf("Trash: %v", err)
+	}
+	if len(entry.Cleared) != 2 {
+		t.Fatalf("cleared %d tasks, want 2", len(entry.Cleared))
+	}
+	if _, err := os.Stat(project.FilePath); !os.IsNotExist(err) {
+		t.Fatalf("project file still present after Trash")
+	}
+
+	// A task reassigned while the project was in the trash keeps its new
+	// project on restore.
+	if err := denote.UpdateTaskProjectID(nil, moved.FilePath, "elsewhere"); err != nil {
+		t.Fatalf("UpdateTaskProjectID: %v", err)
+	}
+
+	entries, err := ListTrash(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ListTrash = %d entries, %v; want 1", len(entries), err)
+	}
+
+	restored, relinked, err := RestoreFromTrash(nil, dir, projectRef)
+	if err != nil {
+		t.Fatalf("RestoreFromTrash: %v", err)
+	}
+	if restored.Title != "Garden" || len(relinked) != 1 || relinked[0].TaskID != linked.ID {
+		t.Fatalf("restored %q, relinked %+v; want Garden with %s re-linked", restored.Title, relinked, linked.ID)
+	}
+
+	got, err := denote.ParseTaskFile(linked.FilePath)
+	if err != nil {
+		t.Fatalf("ParseTaskFile: %v", err)
+	}
+	if got.ProjectID != projectRef {
+		t.Errorf("project_id = %q, want %q", got.ProjectID, projectRef)
+	}
+	got, err = denote.ParseTaskFile(moved.FilePath)
+	if err != nil {
+		t.Fatalf("ParseTaskFile: %v", err)
+	}
+	if got.ProjectID != "elsewhere" {
+		t.Errorf("reassigned task project_id = %q, want elsewhere", got.ProjectID)
+	}
+}
+
+func TestEmptyTrashOlderThan(t *testing.T) {
+	dir := t.TempDir()
+
+	old, err := CreateTask(nil, dir, "Old task", "", nil, "")
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	recent, err := CreateTask(nil, dir, "Recent task", "", nil, "")
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	entry, err := Trash(nil, dir, old.FilePath)
+	if err != nil {
+		t.Fatalf("Trash: %v", err)
+	}
+	// Backdate the sidecar so the entry looks 40 days old.
+	entry.DeletedAt = time.Now().AddDate(0, 0, -40)
+	data, _ := json.Marshal(entry)
+	if err := os.WriteFile(entry.Path+trashMetaSuffix, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Trash(nil, dir, recent.FilePath); err != nil {
+		t.Fatalf("Trash: %v", err)
+	}
+
+	removed, err := EmptyTrash(dir, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("EmptyTrash: %v", err)
+	}
+	if len(removed) != 1 || removed[0].Title != "Old task" {
+		t.Fatalf("removed %+v, want only Old task", removed)
+	}
+
+	entries, _ := ListTrash(dir)
+	if len(entries) != 1 || entries[0].Title != "Recent task" {
+		t.Fatalf("trash after empty = %+v, want Recent task", entries)
+	}
+}
+
+func TestTrashAndRestoreWaitForWriters(t *testing.T) {
+	dir := t.TempDir()
+	task, err := CreateTask(nil, dir, "Plant tomatoes", "", nil, "")
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	// holdLock keeps path locked for a while, as a writer in another
+	// process would, and returns how long.
+	holdLock := func(path string) time.Duration {
+		unlock, err := denote.LockFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		held := 200 * time.Millisecond
+		go func() {
+			time.Sleep(held)
+			unlock()
+		}()
+		return held
+	}
+
+	held := holdLock(task.FilePath)
+	start := time.Now()
+	entry, err := Trash(nil, dir, task.FilePath)
+	if err != nil {
+		t.Fatalf("Trash: %v", err)
+	}
+	if waited := time.Since(start); waited < held {
+		t.Errorf("Trash finished after %v, before the writer released the file", waited)
+	}
+
+	held = holdLock(entry.Path)
+	start = time.Now()
+	if _, _, err := RestoreFromTrash(nil, dir, strconv.Itoa(task.IndexID)); err != nil {
+		t.Fatalf("RestoreFromTrash: %v", err)
+	}
+	if waited := time.Since(start); waited < held {
+		t.Errorf("RestoreFromTrash finished after %v, before the writer released the file", waited)
+	}
+}
@@ -0,0 +1,102 @@
+package task
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/recurrence"
+)
+
+// UpcomingEntry is a task due on a day of the forecast. Projected entries
+// are future instances of a recurring task that do not exist on disk yet;
+// their IndexID and ID are those of the task they are projected from.
+type UpcomingEntry struct {
+	Date      string `json:"date"`
+	IndexID   int    `json:"index_id"`
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Status    string `json:"status"`
+	Priority  string `json:"priority,omitempty"`
+	Area      string `json:"area,omitempty"`
+	Recur     string `json:"recur,omitempty"`
+	Projected bool   `json:"projected,omitempty"`
+}
+
+// UpcomingDay groups the entries due on one date.
+type UpcomingDay struct {
+	Date    string          `json:"date"`
+	Entries []UpcomingEntry `json:"tasks"`
+}
+
+// Forecast lists the unfinished tasks due from today through the given
+// number of days ahead, grouped by day, together with the future instances
+// of recurring tasks projected with recurrence.NextInstanceAt as if each
+// instance were completed on its due date. Tasks already overdue are
+// returned separately, oldest first.
+func Forecast(tasks []*denote.Task, today time.Time, days int) (overdue []UpcomingEntry, upcoming []UpcomingDay) {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)
+	end := today.AddDate(0, 0, days)
+
+	byDate := make(map[string][]UpcomingEntry)
+	for _, t := range tasks {
+		if t.Status == denote.TaskStatusDone || t.Status == denote.TaskStatusDropped || t.DueDate == "" {
+			continue
+		}
+		due, err := time.ParseInLocation("2006-01-02", t.DueDate, time.Local)
+		if err != nil {
+			continue
+		}
+
+		entry := UpcomingEntry{Date: t.DueDate, IndexID: t.IndexID, ID: t.ID, Title: t.Title,
+			Status: t.Status, Priority: t.Priority, Area: t.Area, Recur: t.Recur}
+		switch {
+		case due.Before(today):
+			overdue = append(overdue, entry)
+		case !due.After(end):
+			byDate[t.DueDate] = append(byDate[t.DueDate], entry)
+		}
+
+		if t.Recur == "" {
+			continue
+		}
+		// Project from the scheduled date, which a shift to a workday may
+		// have moved the due date off
+		scheduled, err := time.ParseInLocation("2006-01-02", t.RecurrenceDate(), time.Local)
+		if err != nil {
+			continue
+		}
+		pattern := t.Recur
+		for {
+			next, err := recurrence.NextInstanceAt(pattern, scheduled, scheduled, today)
+			if err != nil || next.Due.After(end) || !next.Scheduled.After(scheduled) {
+				break
+			}
+			projected := entry
+			projected.Date = next.Due.Format("2006-01-02")
+			projected.Status = denote.TaskStatusOpen
+			projected.Recur = next.Pattern
+			projected.Projected = true
+			byDate[projected.Date] = append(byDate[projected.Date], projected)
+			scheduled, pattern = next.Scheduled, next.Pattern
+		}
+	}
+
+	sort.SliceStable(overdue, func(i, j int) bool {
+		if overdue[i].Date != overdue[j].Date {
+			return overdue[i].Date < overdue[j].Date
+		}
+		return overdue[i].IndexID < overdue[j].IndexID
+	})
+	for date, entries := range byDate {
+		sort.SliceStable(entries, func(i, j int) bool {
+			if entries[i].Projected != entries[j].Projected {
+				return !entries[i].Projected
+			}
+			return entries[i].IndexID < entries[j].IndexID
+		})
+		upcoming = append(upcoming, UpcomingDay{Date: date, Entries: entries})
+	}
+	sort.Slice(upcoming, func(i, j int) bool { return upcoming[i].Date < upcoming[j].Date })
+	return overdue, upcoming
+}
@@ -0,0 +1,74 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+func TestForecast(t *testing.T) {
+	newTask := func(id int, due, recur, status string) *denote.Task {
+		task := &denote.Task{}
+		task.IndexID = id
+		task.Title = "Task"
+		task.DueDate = due
+		task.Recur = recur
+		task.Status = status
+		return task
+	}
+	// A Monday
+	today := time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local)
+
+	tasks := []*denote.Task{
+		newTask(1, "2024-03-05", "", denote.TaskStatusOpen),
+		newTask(2, "2024-03-04", "weekly", denote.TaskStatusOpen),
+		newTask(3, "2024-03-06", "every 2w count:2", denote.TaskStatusPaused),
+		newTask(4, "2024-03-01", "", denote.TaskStatusOpen),
+		newTask(5, "2024-03-05", "", denote.TaskStatusDone),
+		newTask(6, "2024-05-01", "", denote.TaskStatusOpen),
+		newTask(7, "", "daily", denote.TaskStatusOpen),
+		newTask(8, "2024-02-26", "weekly", denote.TaskStatusOpen),
+	}
+
+	overdue, days := Forecast(tasks, today, 16)
+
+	if len(overdue) != 2 || overdue[0].IndexID != 8 || overdue[1].IndexID != 4 {
+		t.Errorf("overdue = %+v, want tasks 8 and 4", overdue)
+	}
+
+	type item struct {
+		id        int
+		projected bool
+	}
+	want := map[string][]item{
+		// The overdue weekly task's next instance catches up to today
+		"2024-03-04": {{2, false}, {8, true}},
+		"2024-03-05": {{1, false}},
+		"2024-03-06": {{3, false}},
+		"2024-03-11": {{2, true}, {8, true}},
+		"2024-03-18": {{2, true}, {8, true}},
+		"2024-03-20": {{3, true}}, // count:2 allows one more instance
+	}
+	if len(days) != len(want) {
+		t.Fatalf("got %d days %+v, want %d", len(days), days, len(want))
+	}
+	for i, d := range days {
+		if i > 0 && days[i-1].Date >= d.Date {
+			t.Errorf("days out of order: %s after %s", d.Date, days[i-1].Date)
+		}
+		w := want[d.Date]
+		if len(d.Entries) != len(w) {
+			t.Errorf("%s: %+v, want %v", d.Date, d.Entries, w)
+			continue
+		}
+		for j, e := range d.Entries {
+			if e.IndexID != w[j].id || e.Projected != w[j].projected || e.Date != d.Date {
+				t.Errorf("%s: entry %+v, want %v", d.Date, e, w[j])
+			}
+		}
+	}
+	if r := days[5].Entries[0].Recur; r != "every 2w count:1" {
+		t.Errorf("projected instance recur = %q, want the decremented count", r)
+	}
+}
@@ -5,9 +5,15 @@ import (
 	"github.com/mph-llm-experiments/atask/internal/denote"
 )
 
-// UpdateTaskFile updates the task metadata in a file using acore.
-func UpdateTaskFile(path string, task *denote.Task) error {
+// UpdateTaskFile updates the task metadata in a file using acore, journaled
+// under op. The write is refused with denote.ErrStaleRead if the file
+// changed since task was parsed.
+func UpdateTaskFile(op *denote.Op, path string, task *denote.Task) error {
 	task.Modified = acore.Now()
-	store, name := storeAndName(path)
-	return acore.UpdateFrontmatter(store, name, task)
+	modTime, err := denote.WriteFrontmatter(op, path, task, task.ModTime)
+	if err != nil {
+		return err
+	}
+	task.ModTime = modTime
+	return nil
 }
@@ -56,6 +56,12 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleTagsEditKeys(msg)
 	case ModeEstimateEdit:
 		return m.handleEstimateEditKeys(msg)
+	case ModeTrash:
+		return m.handleTrashKeys(msg)
+	case ModeQueryPicker:
+		return m.handleQueryPickerKeys(msg)
+	case ModeQueryBar:
+		return m.handleQueryBarKeys(msg)
 	default:
 		return m.handleNormalKeys(msg)
 	}
@@ -375,6 +381,9 @@ func (m Model) handleTaskModeKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "/":
 		m.mode = ModeSearch
 		m.searchInput = m.searchQuery
+		// Pick up files edited outside the TUI
+		m.searchIndex = nil
+		m.searchHits = nil
 		
 	case "enter":
 		if len(m.filtered) > 0 && m.cursor < len(m.filtered) {
@@ -496,8 +505,12 @@ func (m Model) handleTaskModeKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		
 	case "f":
-		// Filter menu
-		m.mode = ModeFilterMenu
+		// Saved query picker if any are configured, otherwise the filter menu
+		if len(m.config.Queries) > 0 {
+			m.mode = ModeQueryPicker
+		} else {
+			m.mode = ModeFilterMenu
+		}
 		
 	case "s":
 		// State change menu - for tasks and projects
@@ -505,6 +518,10 @@ func (m Model) handleTaskModeKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.mode = ModeStateMenu
 		}
 		
+	case "F":
+		// Query filter bar
+		m.openQueryBar()
+
 	case "S":
 		// Sort mode (uppercase S since lowercase is now for state)
 		m.mode = ModeSort
@@ -697,11 +714,32 @@ func (m Model) handleTaskModeKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			// Restore default state filter when going back to tasks
 			m.stateFilter = m.config.Tasks.DefaultStateFilter
 		}
+		m.recheckQuery()
 		m.cursor = 0
 		m.applyFilters()
 		m.sortFiles()
 		m.loadVisibleMetadata()
 		
+	case "U":
+		// Undo the last journaled change (lowercase 'u' is update)
+		undone, err := denote.Undo(m.config.NotesDirectory, 1, false)
+		if err != nil {
+			m.statusMsg = fmt.Sprintf("Undo failed: %v", err)
+		} else if len(undone) == 0 {
+			m.statusMsg = "Nothing to undo"
+		} else {
+			m.statusMsg = fmt.Sprintf("Undid: %s (%d files)", undone[0].Label, len(undone[0].Files))
+			m.scanFiles()
+		}
+		
+	case "X":
+		// Open the trash (uppercase counterpart of 'x' delete)
+		if err := m.loadTrash(); err != nil {
+			m.statusMsg = fmt.Sprintf("Error reading trash: %v", err)
+		} else {
+			m.mode = ModeTrash
+		}
+		
 	case "T":
 		// Go to task list (opposite of 'P' for projects, uppercase for filter)
 		if m.projectFilter {
@@ -712,6 +750,7 @@ func (m Model) handleTaskModeKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			if m.stateFilter == "" {
 				m.stateFilter = m.config.Tasks.DefaultStateFilter
 			}
+			m.recheckQuery()
 			m.applyFilters()
 			m.sortFiles()
 			m.loadVisibleMetadata()
@@ -1038,26 +1077,17 @@ func (m Model) handleConfirmDeleteKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "y", "Y":
 		// Handle project deletion specially
 		if m.projectViewTab == 0 && m.viewingProject != nil {
-			// Delete the project and clear project_id from affected tasks
+			// Trash the project; this also clears project_id from affected
+			// tasks and records them so a restore can re-link them
 			projectPath := m.viewingFile.Path
 			projectTitle := m.viewingProject.Title
 			
-			// First, clear project_id from all affected tasks
-			for _, task := range m.affectedTasks {
-				if err := m.clearProjectFromTask(task.FilePath); err != nil {
-					m.statusMsg = fmt.Sprintf("Error updating task: %v", err)
-					m.mode = returnMode
-					return m, nil
-				}
-			}
-			
-			// Then delete the project file
-			if err := m.deleteFile(projectPath); err != nil {
+			if entry, err := m.deleteFile(projectPath); err != nil {
 				m.statusMsg = fmt.Sprintf("Error deleting project: %v", err)
 			} else {
 				m.statusMsg = fmt.Sprintf("Deleted project: %s", projectTitle)
-				if len(m.affectedTasks) > 0 {
-					m.statusMsg += fmt.Sprintf(" (cleared from %d tasks)", len(m.affectedTasks))
+				if len(entry.Cleared) > 0 {
+					m.statusMsg += fmt.Sprintf(" (cleared from %d tasks)", len(entry.Cleared))
 				}
 				// Go back to main task list
 				m.mode = ModeNormal
@@ -1075,7 +1105,7 @@ func (m Model) handleConfirmDeleteKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				filePath := task.FilePath
 				fileTitle := task.Title
 				
-				if err := m.deleteFile(filePath); err != nil {
+				if _, err := m.deleteFile(filePath); err != nil {
 					m.statusMsg = fmt.Sprintf("Error deleting: %v", err)
 				} else {
 					m.statusMsg = fmt.Sprintf("Deleted: %s", fileTitle)
@@ -1092,7 +1122,7 @@ func (m Model) handleConfirmDeleteKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				filePath := file.Path
 				fileTitle := file.Title
 				
-				if err := m.deleteFile(filePath); err != nil {
+				if _, err := m.deleteFile(filePath); err != nil {
 					m.statusMsg = fmt.Sprintf("Error deleting: %v", err)
 				} else {
 					m.statusMsg = fmt.Sprintf("Deleted: %s", fileTitle)
@@ -1114,6 +1144,45 @@ func (m Model) handleConfirmDeleteKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+func (m Model) handleTrashKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q", "X":
+		m.mode = ModeNormal
+		
+	case "j", "down", "k", "up", "ctrl+d", "ctrl+u":
+		if len(m.trashEntries) > 0 {
+			nav := NewNavigationHandler(len(m.trashEntries), false)
+			nav.cursor = m.trashCursor
+			m.trashCursor = nav.HandleKey(msg.String())
+		}
+		
+	case "enter", "r":
+		// Restore the selected entry
+		if m.trashCursor < len(m.trashEntries) {
+			entry := m.trashEntries[m.trashCursor]
+			identifier := entry.ID
+			if identifier == "" {
+				identifier = strconv.Itoa(entry.IndexID)
+			}
+			restored, relinked, err := task.RestoreFromTrash(m.op, m.config.NotesDirectory, identifier)
+			if err != nil {
+				m.statusMsg = fmt.Sprintf("Error restoring: %v", err)
+			} else {
+				m.statusMsg = fmt.Sprintf("Restored %s: %s", restored.Type, restored.Title)
+				if len(relinked) > 0 {
+					m.statusMsg += fmt.Sprintf(" (re-linked %d tasks)", len(relinked))
+				}
+				m.scanFiles()
+			}
+			if err := m.loadTrash(); err != nil {
+				m.statusMsg = fmt.Sprintf("Error reading trash: %v", err)
+			}
+		}
+	}
+	
+	return m, nil
+}
+
 func (m Model) handleConfirmClearTodayKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "y", "Y":
@@ -1189,6 +1258,10 @@ func (m Model) handleFilterMenuKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.sortFiles()
 		m.loadVisibleMetadata()
 
+	case "e":
+		// Query expression
+		m.openQueryBar()
+
 	case "c":
 		// Clear all filters
 		m.areaFilter = ""
@@ -1197,6 +1270,8 @@ func (m Model) handleFilterMenuKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.soonFilter = false
 		m.todayFilter = false
 		m.looseFilter = false
+		m.queryText = ""
+		m.queryFilter = nil
 		m.mode = ModeNormal
 		m.statusMsg = "All filters cleared"
 		m.applyFilters()
@@ -1423,7 +1498,7 @@ func (m Model) handleProjectSelectKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			} else if m.projectSelectFor == "update" && m.projectSelectTask != nil {
 				// Clear project assignment
 				m.projectSelectTask.TaskMetadata.ProjectID = ""
-				if err := task.UpdateTaskFile(m.projectSelectTask.FilePath, m.projectSelectTask); err != nil {
+				if err := task.UpdateTaskFile(m.op, m.projectSelectTask.FilePath, m.projectSelectTask); err != nil {
 					m.statusMsg = fmt.Sprintf("Error updating task: %v", err)
 				} else {
 					m.statusMsg = "Removed from project"
@@ -1445,7 +1520,7 @@ func (m Model) handleProjectSelectKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			} else if m.projectSelectFor == "update" && m.projectSelectTask != nil {
 				// Update task with selected project (using index_id)
 				m.projectSelectTask.TaskMetadata.ProjectID = strconv.Itoa(selected.IndexID)
-				if err := task.UpdateTaskFile(m.projectSelectTask.FilePath, m.projectSelectTask); err != nil {
+				if err := task.UpdateTaskFile(m.op, m.projectSelectTask.FilePath, m.projectSelectTask); err != nil {
 					m.statusMsg = fmt.Sprintf("Error updating task: %v", err)
 				} else {
 					m.statusMsg = fmt.Sprintf("Added to project: %s", selected.Title)
@@ -1511,8 +1586,8 @@ func (m Model) handleDateEditKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 			if file.IsTask() && !isBeginDate {
 				if t, err := denote.ParseTaskFile(file.Path); err == nil {
-					t.TaskMetadata.DueDate = parsedDate
-					if err := task.UpdateTaskFile(file.Path, t); err != nil {
+					t.SetDueDate(parsedDate)
+					if err := task.UpdateTaskFile(m.op, file.Path, t); err != nil {
 						m.statusMsg = fmt.Sprintf(ErrorFormat, err)
 					} else {
 						if parsedDate == "" {
@@ -1530,7 +1605,7 @@ func (m Model) handleDateEditKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					} else {
 						project.ProjectMetadata.DueDate = parsedDate
 					}
-					if err := denote.UpdateProjectFile(file.Path, project); err != nil {
+					if err := denote.UpdateProjectFile(m.op, file.Path, project); err != nil {
 						m.statusMsg = fmt.Sprintf(ErrorFormat, err)
 					} else {
 						if parsedDate == "" {
@@ -1637,7 +1712,7 @@ func (m Model) handleTagsEditKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					t.Tags = append(t.Tags, newTags...)
 
 					// Update the metadata
-					if err := task.UpdateTaskFile(file.Path, t); err != nil {
+					if err := task.UpdateTaskFile(m.op, file.Path, t); err != nil {
 						m.statusMsg = fmt.Sprintf(ErrorFormat, err)
 					} else {
 						if len(newTags) == 0 {
@@ -1655,7 +1730,7 @@ func (m Model) handleTagsEditKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					project.Tags = append(project.Tags, newTags...)
 
 					// Update the metadata
-					if err := denote.UpdateProjectFile(file.Path, project); err != nil {
+					if err := denote.UpdateProjectFile(m.op, file.Path, project); err != nil {
 						m.statusMsg = fmt.Sprintf(ErrorFormat, err)
 					} else {
 						if len(newTags) == 0 {
@@ -1759,7 +1834,7 @@ func (m Model) handleEstimateEditKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			
 			// Update the task
 			if file.IsTask() {
-				if err := denote.UpdateTaskEstimate(file.Path, estimate); err != nil {
+				if err := denote.UpdateTaskEstimate(m.op, file.Path, estimate); err != nil {
 					m.statusMsg = fmt.Sprintf("Failed to update estimate: %v", err)
 				} else {
 					if estimate == 0 {
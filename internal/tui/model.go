@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,7 +14,9 @@ import (
 	"github.com/mph-llm-experiments/acore"
 	"github.com/mph-llm-experiments/atask/internal/config"
 	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/query"
 	"github.com/mph-llm-experiments/atask/internal/recurrence"
+	"github.com/mph-llm-experiments/atask/internal/search"
 	"github.com/mph-llm-experiments/atask/internal/task"
 )
 
@@ -23,6 +24,10 @@ type Model struct {
 	// Config
 	config *config.Config
 	
+	// Undo journal operation for the message being handled; nil when it
+	// isn't a key press, so nothing is journaled
+	op *denote.Op
+	
 	// Denote files
 	files      []denote.File
 	filtered   []denote.File
@@ -46,6 +51,20 @@ type Model struct {
 	todayFilter    bool  // Filter to show only tasks due today
 	looseFilter    bool  // Filter to show only tasks with no project
 	projectFilter  bool  // Filter to show only projects
+	queryText      string       // Applied query expression, or @name for a saved query
+	queryFilter    *query.Query // Parsed queryText; replaces the filters above
+	queryCursor    int          // Cursor in the saved query picker
+	searchIndex    *search.Index   // Full-text index for searchQuery; nil until needed
+	searchHits     map[string]bool // Paths matching searchHitsFor
+	searchHitsFor  string
+
+	// Query filter bar
+	queryInput      string       // Expression being typed
+	queryInputErr   error        // Parse error for queryInput, if any
+	queryPrevText   string       // queryText to restore on Esc
+	queryPrevFilter *query.Query // queryFilter to restore on Esc
+	queryHistory    []string     // Recent expressions, oldest first
+	historyIndex    int          // Position in queryHistory while browsing
 	
 	// Preview
 	previewFile     *denote.File
@@ -81,6 +100,7 @@ type Model struct {
 	// Display
 	err        error
 	statusMsg  string
+	parseErrors []denote.ParseError // files skipped during the last scan
 	lastKey    string
 	fieldRenderer *FieldRenderer
 	
@@ -93,6 +113,10 @@ type Model struct {
 	projectSelectCursor int
 	projectSelectFor    string // "create" or "update"
 	projectSelectTask   *denote.Task // For update mode
+	
+	// Trash view
+	trashEntries []task.TrashEntry
+	trashCursor  int
 }
 
 type Mode int
@@ -121,6 +145,9 @@ const (
 	ModeDateEdit
 	ModeTagsEdit
 	ModeEstimateEdit
+	ModeTrash
+	ModeQueryPicker
+	ModeQueryBar
 )
 
 // ViewMode removed - we're always in task mode now
@@ -179,6 +206,10 @@ func (m *Model) scanFiles() error {
 	}
 	
 	m.files = files
+	m.parseErrors = scanner.ParseErrors()
+	// Files may have changed, so refresh the search index on next use
+	m.searchIndex = nil
+	m.searchHits = nil
 	
 	m.applyFilters()
 	m.sortFiles()
@@ -197,7 +228,8 @@ func (m *Model) loadVisibleMetadata() {
 
 func (m *Model) hasAnyFilter() bool {
 	return m.areaFilter != "" || m.priorityFilter != "" || m.stateFilter != "" ||
-		m.soonFilter || m.todayFilter || m.looseFilter || m.searchQuery != "" || m.projectFilter
+		m.soonFilter || m.todayFilter || m.looseFilter || m.searchQuery != "" || m.projectFilter ||
+		m.queryText != ""
 }
 
 func (m *Model) applyFilters() {
@@ -206,10 +238,12 @@ func (m *Model) applyFilters() {
 	// Build set of inactive project IDs (paused, cancelled, or not yet begun)
 	// to hide their tasks (only when any filter is active)
 	hiddenProjectIDs := make(map[string]bool)
+	var projects []*denote.Project
 	if m.hasAnyFilter() {
 		for _, f := range m.files {
 			if f.IsProject() {
 				if proj, err := denote.ParseProjectFile(f.Path); err == nil {
+					projects = append(projects, proj)
 					if proj.ProjectMetadata.Status == denote.ProjectStatusPaused ||
 						proj.ProjectMetadata.Status == denote.ProjectStatusCancelled ||
 						proj.HasNotBegun() {
@@ -220,6 +254,8 @@ func (m *Model) applyFilters() {
 		}
 	}
 
+	projectsByID := query.ProjectsByID(projects)
+
 	for _, f := range m.files {
 		// Always in task mode - only show tasks and projects
 		if !f.IsTask() && !f.IsProject() {
@@ -241,23 +277,9 @@ func (m *Model) applyFilters() {
 				if tagQuery != "" && !f.MatchesTag(tagQuery) {
 					continue
 				}
-			} else {
-				// Regular search mode
-				matches := f.MatchesSearch(m.searchQuery)
-				
-				// For tasks, also search in metadata
-				if !matches && f.IsTask() {
-					// Always load fresh metadata for search
-					if task, err := denote.ParseTaskFile(f.Path); err == nil {
-						if m.taskMatchesSearch(task, m.searchQuery) {
-							matches = true
-						}
-					}
-				}
-				
-				if !matches {
-					continue
-				}
+			} else if !m.searchMatches(m.searchQuery)[f.Path] {
+				// Full-text search of titles, tags and bodies
+				continue
 			}
 		}
 		
@@ -275,6 +297,23 @@ func (m *Model) applyFilters() {
 				projectMeta = project
 			}
 		}
+
+		// A query replaces the other filters and decides alone, matching
+		// 'atask query' in the task list and 'atask project query' in the
+		// project list
+		if m.queryFilter != nil {
+			var record query.Record
+			if taskMeta != nil && !m.projectFilter {
+				record = query.NewTaskRecord(taskMeta, projectsByID)
+			} else if projectMeta != nil && m.projectFilter {
+				record = projectMeta
+			}
+			if record == nil || !m.queryFilter.Match(record, m.config) {
+				continue
+			}
+			filtered = append(filtered, f)
+			continue
+		}
 			
 			// Hide tasks belonging to inactive projects (paused, cancelled, or not yet begun)
 			if taskMeta != nil && taskMeta.ProjectID != "" && hiddenProjectIDs[taskMeta.ProjectID] {
@@ -384,6 +423,13 @@ func (m *Model) sortFiles() {
 	// Sort without cached metadata - SortTaskFiles will read fresh from disk
 	denote.SortTaskFiles(m.filtered, m.sortBy, m.reverseSort, nil, nil)
 
+	// A saved query's ORDER BY and LIMIT decide the order and the rows, as
+	// they do for 'atask query @name', so "today" tasks stay where they are
+	if q := m.queryFilter; q != nil && (len(q.OrderBy) > 0 || q.Limit > 0) {
+		m.applyQueryClauses()
+		return
+	}
+
 	// Pre-compute today status for all tasks to avoid repeated file reads
 	todayStatus := make(map[string]bool)
 	for _, file := range m.filtered {
@@ -435,6 +481,7 @@ func (m Model) Init() tea.Cmd {
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m.op = nil
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -442,6 +489,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 		
 	case tea.KeyMsg:
+		// Journal whatever this key press writes as one undoable operation
+		m.op = denote.NewOp(m.config.NotesDirectory, "tui "+msg.String())
 		return m.handleKeyPress(msg)
 		
 	// Removed noteCreatedMsg case - we only create tasks now
@@ -561,6 +610,7 @@ func (m *Model) loadProjectsForSelection() {
 }
 
 func (m Model) createTask() tea.Cmd {
+	op := denote.NewOp(m.config.NotesDirectory, "tui create task")
 	return func() tea.Msg {
 		// Parse tags
 		tags := []string{}
@@ -569,7 +619,7 @@ func (m Model) createTask() tea.Cmd {
 		}
 		
 		// Create the task
-		newTask, err := task.CreateTask(m.config.NotesDirectory, m.createTitle, "", tags, m.createArea)
+		newTask, err := task.CreateTask(op, m.config.NotesDirectory, m.createTitle, "", tags, m.createArea)
 		if err != nil {
 			return err
 		}
@@ -606,7 +656,7 @@ func (m Model) createTask() tea.Cmd {
 		
 		// Write updated metadata if needed
 		if needsUpdate {
-			if err := task.UpdateTaskFile(newTask.FilePath, newTask); err != nil {
+			if err := task.UpdateTaskFile(op, newTask.FilePath, newTask); err != nil {
 				return err
 			}
 		}
@@ -616,6 +666,7 @@ func (m Model) createTask() tea.Cmd {
 }
 
 func (m Model) create() tea.Cmd {
+	op := denote.NewOp(m.config.NotesDirectory, "tui create")
 	return func() tea.Msg {
 		// Parse tags
 		tags := []string{}
@@ -641,7 +692,7 @@ func (m Model) create() tea.Cmd {
 				}
 			}
 			
-			project, err := task.CreateProject(m.config.NotesDirectory, m.createTitle, "", tags)
+			project, err := task.CreateProject(op, m.config.NotesDirectory, m.createTitle, "", tags)
 			if err != nil {
 				return err
 			}
@@ -650,7 +701,7 @@ func (m Model) create() tea.Cmd {
 			if m.areaFilter != "" {
 				project.ProjectMetadata.Area = m.areaFilter
 				// Write back the updated metadata
-				if err := denote.UpdateProjectFile(project.FilePath, project); err != nil {
+				if err := denote.UpdateProjectFile(op, project.FilePath, project); err != nil {
 					return fmt.Errorf(ErrorFailedTo, "update project area", err)
 				}
 			}
@@ -658,7 +709,7 @@ func (m Model) create() tea.Cmd {
 			return projectCreatedMsg{path: project.FilePath}
 		} else {
 			// Create a task
-			newTask, err := task.CreateTask(m.config.NotesDirectory, m.createTitle, "", tags, m.createArea)
+			newTask, err := task.CreateTask(op, m.config.NotesDirectory, m.createTitle, "", tags, m.createArea)
 			if err != nil {
 				return err
 			}
@@ -676,7 +727,7 @@ func (m *Model) updateTaskPriority(priority string) error {
 	file := m.filtered[m.cursor]
 
 	if file.IsTask() {
-		if err := denote.UpdateTaskPriority(file.Path, priority); err != nil {
+		if err := denote.UpdateTaskPriority(m.op, file.Path, priority); err != nil {
 			return err
 		}
 		if priority == "" {
@@ -690,7 +741,7 @@ func (m *Model) updateTaskPriority(priority string) error {
 			return err
 		}
 		project.ProjectMetadata.Priority = priority
-		if err := denote.UpdateProjectFile(file.Path, project); err != nil {
+		if err := denote.UpdateProjectFile(m.op, file.Path, project); err != nil {
 			return err
 		}
 		if priority == "" {
@@ -731,7 +782,7 @@ func (m *Model) toggleTodayTag() error {
 	}
 
 	task.Modified = acore.Now()
-	if err := acore.UpdateFrontmatter(acore.NewLocalStore(filepath.Dir(file.Path)), filepath.Base(file.Path), task); err != nil {
+	if err := denote.SaveTask(m.op, task); err != nil {
 		return err
 	}
 
@@ -756,7 +807,7 @@ func (m *Model) clearAllTodayTags() error {
 		}
 		task.TaskMetadata.TodayDate = ""
 		task.Modified = acore.Now()
-		if err := acore.UpdateFrontmatter(acore.NewLocalStore(filepath.Dir(file.Path)), filepath.Base(file.Path), task); err != nil {
+		if err := denote.SaveTask(m.op, task); err != nil {
 			continue
 		}
 		count++
@@ -785,16 +836,16 @@ func (m *Model) updateTaskField(field, value string) error {
 	case "priority":
 		task.TaskMetadata.Priority = value
 	case "status":
-		task.TaskMetadata.Status = value
+		task.SetStatus(value)
 	case "due_date":
 		if value != "" {
 			parsed, err := denote.ParseNaturalDate(value)
 			if err != nil {
 				return fmt.Errorf("invalid date: %s (try: 2d, 1w, friday, jan 15, 2024-01-15)", value)
 			}
-			task.TaskMetadata.DueDate = parsed
+			task.SetDueDate(parsed)
 		} else {
-			task.TaskMetadata.DueDate = ""
+			task.SetDueDate("")
 		}
 	case "area":
 		task.TaskMetadata.Area = value
@@ -815,7 +866,7 @@ func (m *Model) updateTaskField(field, value string) error {
 	}
 
 	task.Modified = acore.Now()
-	if err := acore.UpdateFrontmatter(acore.NewLocalStore(filepath.Dir(m.viewingFile.Path)), filepath.Base(m.viewingFile.Path), task); err != nil {
+	if err := denote.SaveTask(m.op, task); err != nil {
 		return fmt.Errorf("failed to update task: %w", err)
 	}
 
@@ -879,7 +930,7 @@ func (m *Model) updateProjectField(field, value string) error {
 	}
 
 	project.Modified = acore.Now()
-	if err := acore.UpdateFrontmatter(acore.NewLocalStore(filepath.Dir(m.viewingFile.Path)), filepath.Base(m.viewingFile.Path), project); err != nil {
+	if err := denote.SaveProject(m.op, project); err != nil {
 		return fmt.Errorf("failed to update project: %w", err)
 	}
 
@@ -897,12 +948,21 @@ func (m *Model) updateProjectField(field, value string) error {
 
 // editFile opens a file in the external editor
 func (m Model) editFile(path string) tea.Cmd {
+	// Snapshot the file so the editor session can be undone
+	before, _ := os.ReadFile(path)
+	op := denote.NewOp(m.config.NotesDirectory, "tui edit")
+	
 	// Use tea.ExecProcess to properly suspend the TUI
 	cmd := exec.Command(m.config.Editor, path)
 	return tea.ExecProcess(cmd, func(err error) tea.Msg {
 		if err != nil {
 			return fmt.Errorf("failed to edit file: %w", err)
 		}
+		if before != nil {
+			if err := denote.RecordChange(op, path, before); err != nil {
+				return fmt.Errorf("failed to journal edit: %w", err)
+			}
+		}
 		// Return a message to trigger file check and potential rename
 		return fileEditedMsg{path: path}
 	})
@@ -957,63 +1017,28 @@ func (m *Model) loadProjectTasks() {
 	m.projectTasksCursor = 0
 }
 
-// taskMatchesSearch performs fuzzy search on task metadata
-func (m *Model) taskMatchesSearch(task *denote.Task, query string) bool {
-	query = strings.ToLower(query)
-	
-	// Helper function for fuzzy matching
-	fuzzyMatch := func(text, pattern string) bool {
-		if pattern == "" {
-			return true
-		}
-		
-		patternIdx := 0
-		for _, ch := range text {
-			if patternIdx < len(pattern) && ch == rune(pattern[patternIdx]) {
-				patternIdx++
-			}
-		}
-		
-		return patternIdx == len(pattern)
-	}
-	
-	// Search in status
-	if fuzzyMatch(strings.ToLower(task.Status), query) {
-		return true
-	}
-	
-	// Search in priority
-	if fuzzyMatch(strings.ToLower(task.Priority), query) {
-		return true
-	}
-	
-	// Search in area
-	if fuzzyMatch(strings.ToLower(task.Area), query) {
-		return true
-	}
-	
-	// Search in assignee
-	if fuzzyMatch(strings.ToLower(task.Assignee), query) {
-		return true
+// searchMatches returns the paths of the files matching the / search, with
+// the word being typed matched as a prefix. The search index is brought up
+// to date the first time it is needed after the files were rescanned.
+func (m *Model) searchMatches(text string) map[string]bool {
+	if m.searchHits != nil && m.searchHitsFor == text {
+		return m.searchHits
 	}
-	
-	// Search in project name by looking through files
-	if task.ProjectID != "" {
-		for _, file := range m.files {
-			if file.IsProject() {
-				if proj, err := denote.ParseProjectFile(file.Path); err == nil {
-					if strconv.Itoa(proj.IndexID) == task.ProjectID {
-						if fuzzyMatch(strings.ToLower(proj.Title), query) {
-							return true
-						}
-						break
-					}
-				}
-			}
+	if m.searchIndex == nil {
+		idx, err := search.Open(m.config.NotesDirectory)
+		if err != nil {
+			m.statusMsg = fmt.Sprintf("Search failed: %v", err)
+			return nil
 		}
+		m.searchIndex = idx
 	}
-	
-	return false
+
+	hits := make(map[string]bool)
+	for _, r := range m.searchIndex.SearchPrefix(text) {
+		hits[r.Path] = true
+	}
+	m.searchHits, m.searchHitsFor = hits, text
+	return hits
 }
 
 // updateCurrentTaskStatus updates the status of the currently selected task
@@ -1028,7 +1053,7 @@ func (m *Model) updateCurrentTaskStatus(newStatus string) error {
 	}
 	
 	// Update the task status
-	err := denote.UpdateTaskStatus(file.Path, newStatus)
+	err := denote.UpdateTaskStatus(m.op, file.Path, newStatus)
 	if err != nil {
 		return err
 	}
@@ -1053,29 +1078,35 @@ func (m *Model) adjustScrollOffset() {
 }
 
 // handleTaskRecurrence checks if a task has a recurrence pattern and creates the next instance.
-// Returns a status message about the new task, or empty string if not recurring.
+// Returns a status message about the new task or why none was created, or empty string if not recurring.
 func (m *Model) handleTaskRecurrence(filePath string) string {
 	t, err := denote.ParseTaskFile(filePath)
-	if err != nil || t.TaskMetadata.Recur == "" || t.TaskMetadata.DueDate == "" {
+	if err != nil {
+		return fmt.Sprintf(" | ↻ Next instance not created: %v", err)
+	}
+	if t.TaskMetadata.Recur == "" || t.TaskMetadata.DueDate == "" {
 		return ""
 	}
 
-	currentDue, err := time.ParseInLocation("2006-01-02", t.TaskMetadata.DueDate, time.Now().Location())
+	currentDue, err := time.ParseInLocation("2006-01-02", t.RecurrenceDate(), time.Now().Location())
 	if err != nil {
-		return ""
+		return fmt.Sprintf(" | ↻ Next instance not created: invalid due date %q", t.RecurrenceDate())
 	}
 
-	nextDue, err := recurrence.NextDueDate(t.TaskMetadata.Recur, currentDue)
+	next, err := recurrence.NextInstance(t.TaskMetadata.Recur, currentDue, t.CompletedTime())
+	if err == recurrence.ErrSeriesEnded {
+		return " | ↻ Series ended"
+	}
 	if err != nil {
-		return ""
+		return fmt.Sprintf(" | ↻ Next instance not created: %v", err)
 	}
 
-	newTask, err := task.CloneTaskForRecurrence(m.config.NotesDirectory, t, nextDue.Format("2006-01-02"))
+	newTask, err := task.CloneTaskForRecurrence(m.op, m.config.NotesDirectory, t, next)
 	if err != nil {
-		return ""
+		return fmt.Sprintf(" | ↻ Next instance not created: %v", err)
 	}
 
-	return fmt.Sprintf(" | ↻ Created next: ID %d (due %s)", newTask.IndexID, nextDue.Format("2006-01-02"))
+	return fmt.Sprintf(" | ↻ Created next: ID %d (due %s)", newTask.IndexID, next.Due.Format("2006-01-02"))
 }
 
 // updateCurrentProjectStatus updates the status of the currently selected project
@@ -1099,7 +1130,7 @@ func (m *Model) updateCurrentProjectStatus(newStatus string) error {
 	project.ProjectMetadata.Status = newStatus
 
 	// Write back
-	err = denote.UpdateProjectFile(file.Path, project)
+	err = denote.UpdateProjectFile(m.op, file.Path, project)
 	if err != nil {
 		return fmt.Errorf("failed to update project: %v", err)
 	}
@@ -1107,9 +1138,26 @@ func (m *Model) updateCurrentProjectStatus(newStatus string) error {
 	return nil
 }
 
-// deleteFile deletes a file from the filesystem
-func (m *Model) deleteFile(path string) error {
-	return os.Remove(path)
+// deleteFile moves a task or project to the trash. Projects also have
+// their project_id cleared from assigned tasks.
+func (m *Model) deleteFile(path string) (*task.TrashEntry, error) {
+	return task.Trash(m.op, m.config.NotesDirectory, path)
+}
+
+// loadTrash refreshes the entries shown in the trash view
+func (m *Model) loadTrash() error {
+	entries, err := task.ListTrash(m.config.NotesDirectory)
+	if err != nil {
+		return err
+	}
+	m.trashEntries = entries
+	if m.trashCursor >= len(m.trashEntries) {
+		m.trashCursor = len(m.trashEntries) - 1
+	}
+	if m.trashCursor < 0 {
+		m.trashCursor = 0
+	}
+	return nil
 }
 
 // findTasksAffectedByProjectDeletion finds all tasks that reference the current project
@@ -1134,14 +1182,6 @@ func (m *Model) findTasksAffectedByProjectDeletion() {
 	}
 }
 
-// clearProjectFromTask removes the project_id from a task
-func (m *Model) clearProjectFromTask(taskPath string) error {
-	if err := denote.UpdateTaskProjectID(taskPath, ""); err != nil {
-		return err
-	}
-	return nil
-}
-
 // updateProjectTaskStatus updates the status of the currently selected task in project view
 func (m *Model) updateProjectTaskStatus(newStatus string) error {
 	if m.projectTasksCursor >= len(m.projectTasks) {
@@ -1151,7 +1191,7 @@ func (m *Model) updateProjectTaskStatus(newStatus string) error {
 	task := &m.projectTasks[m.projectTasksCursor]
 	
 	// Update the task status
-	err := denote.UpdateTaskStatus(task.FilePath, newStatus)
+	err := denote.UpdateTaskStatus(m.op, task.FilePath, newStatus)
 	if err != nil {
 		return err
 	}
@@ -1212,6 +1252,12 @@ func (m Model) View() string {
 		return m.renderTagsEditPopup()
 	case ModeEstimateEdit:
 		return m.renderEstimateEditPopup()
+	case ModeTrash:
+		return m.renderTrash()
+	case ModeQueryPicker:
+		return m.renderQueryPicker()
+	case ModeQueryBar:
+		return m.renderNormal()
 	default:
 		return m.renderNormal()
 	}
@@ -1222,6 +1268,13 @@ func (m *Model) addLogEntry() error {
 		return fmt.Errorf("no file selected or empty log input")
 	}
 	
+	// Hold the file lock across read-modify-write
+	unlock, err := denote.LockFile(m.loggingFile.Path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	
 	// Read the file
 	content, err := os.ReadFile(m.loggingFile.Path)
 	if err != nil {
@@ -1287,7 +1340,7 @@ func (m *Model) addLogEntry() error {
 	
 	// Write back to file
 	newContent := strings.Join(newLines, "\n")
-	if err := os.WriteFile(m.loggingFile.Path, []byte(newContent), 0644); err != nil {
+	if err := denote.WriteFileAtomic(m.op, m.loggingFile.Path, []byte(newContent), 0644); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 	
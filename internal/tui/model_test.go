@@ -0,0 +1,46 @@
+package tui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mph-llm-experiments/acore"
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+func TestHandleTaskRecurrenceReportsErrors(t *testing.T) {
+	dir := t.TempDir()
+	m := Model{config: &config.Config{NotesDirectory: dir}}
+
+	write := func(title, recur string) string {
+		t.Helper()
+		task := &denote.Task{}
+		task.ID = acore.NewID()
+		task.Title = title
+		task.IndexID = 1
+		task.Type = denote.TypeTask
+		task.Status = denote.TaskStatusDone
+		task.DueDate = "2099-01-05"
+		task.Recur = recur
+		filename := acore.BuildFilename(task.ID, title, "task")
+		if err := acore.WriteFile(acore.NewLocalStore(dir), filename, task, ""); err != nil {
+			t.Fatalf("write task: %v", err)
+		}
+		return filepath.Join(dir, filename)
+	}
+
+	if msg := m.handleTaskRecurrence(write("Weekly review", "weekly")); !strings.Contains(msg, "Created next") {
+		t.Errorf("valid pattern: status %q, want the new instance", msg)
+	}
+	if msg := m.handleTaskRecurrence(write("Broken", "every blue moon")); !strings.Contains(msg, "not created") {
+		t.Errorf("invalid pattern: status %q, want the error", msg)
+	}
+	if msg := m.handleTaskRecurrence(filepath.Join(dir, "missing__task.md")); !strings.Contains(msg, "not created") {
+		t.Errorf("unreadable task: status %q, want the error", msg)
+	}
+	if msg := m.handleTaskRecurrence(write("Once", "")); msg != "" {
+		t.Errorf("non-recurring task: status %q, want none", msg)
+	}
+}
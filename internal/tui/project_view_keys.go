@@ -351,7 +351,7 @@ func (m Model) handleProjectViewKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 // updateTaskPriorityFromProject updates a task priority from the project view
 func (m *Model) updateTaskPriorityFromProject(task *denote.Task, priority string) error {
 	task.TaskMetadata.Priority = priority
-	if err := denote.UpdateTaskPriority(task.FilePath, priority); err != nil {
+	if err := denote.UpdateTaskPriority(m.op, task.FilePath, priority); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,228 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/mph-llm-experiments/atask/internal/query"
+)
+
+// QueryHistoryFileName is the file in the task directory that keeps recent
+// query filter bar expressions, one per line, oldest first.
+const QueryHistoryFileName = ".atask-query-history"
+
+// maxQueryHistory is how many expressions the history keeps.
+const maxQueryHistory = 50
+
+// openQueryBar starts editing the query expression that filters the list.
+func (m *Model) openQueryBar() {
+	m.mode = ModeQueryBar
+	m.queryInput = m.queryText
+	m.queryInputErr = nil
+	m.queryPrevText = m.queryText
+	m.queryPrevFilter = m.queryFilter
+	m.queryHistory = loadQueryHistory(m.config.NotesDirectory)
+	m.historyIndex = len(m.queryHistory)
+}
+
+// handleQueryBarKeys edits the query expression, re-filtering the list as
+// it changes. Enter keeps the filter, Esc restores the previous one, and
+// up/down browse the history.
+func (m Model) handleQueryBarKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = ModeNormal
+		m.queryText = m.queryPrevText
+		m.queryFilter = m.queryPrevFilter
+		m.queryInputErr = nil
+		m.refilter()
+
+	case "enter":
+		if m.queryInputErr != nil {
+			return m, nil
+		}
+		m.mode = ModeNormal
+		if m.queryText != "" {
+			m.queryHistory = addQueryHistory(m.queryHistory, m.queryText)
+			if err := saveQueryHistory(m.config.NotesDirectory, m.queryHistory); err != nil {
+				m.statusMsg = fmt.Sprintf("Failed to save query history: %v", err)
+			}
+		}
+
+	case "up", "ctrl+p":
+		if m.historyIndex > 0 {
+			m.historyIndex--
+			m.setQueryInput(m.queryHistory[m.historyIndex])
+		}
+
+	case "down", "ctrl+n":
+		if m.historyIndex < len(m.queryHistory) {
+			m.historyIndex++
+			if m.historyIndex == len(m.queryHistory) {
+				m.setQueryInput("")
+			} else {
+				m.setQueryInput(m.queryHistory[m.historyIndex])
+			}
+		}
+
+	case "ctrl+u":
+		m.setQueryInput("")
+
+	case "backspace":
+		if len(m.queryInput) > 0 {
+			_, size := utf8.DecodeLastRuneInString(m.queryInput)
+			m.setQueryInput(m.queryInput[:len(m.queryInput)-size])
+		}
+
+	case " ":
+		m.setQueryInput(m.queryInput + " ")
+
+	default:
+		if msg.Type == tea.KeyRunes {
+			m.setQueryInput(m.queryInput + string(msg.Runes))
+		}
+	}
+
+	return m, nil
+}
+
+// setQueryInput updates the expression and, if it parses, filters the list
+// with it. An expression that doesn't parse leaves the last valid filter in
+// place and is reported below the input.
+func (m *Model) setQueryInput(input string) {
+	m.queryInput = input
+	text := strings.TrimSpace(input)
+	if text == "" {
+		m.queryInputErr = nil
+		m.queryText = ""
+		m.queryFilter = nil
+		m.refilter()
+		return
+	}
+
+	// Parse the untrimmed input so error positions line up with it
+	q, err := parseFilterQuery(input, m)
+	m.queryInputErr = err
+	if err != nil {
+		return
+	}
+	m.queryText = text
+	m.queryFilter = q
+	m.refilter()
+}
+
+// parseFilterQuery parses an expression for the filter bar, against the
+// fields of the records the list shows. Result clauses are only accepted
+// from a saved query; sortFiles applies its ORDER BY and LIMIT, and FIELDS
+// has no effect on the list.
+func parseFilterQuery(text string, m *Model) (*query.Query, error) {
+	q, err := query.ParseFor(text, m.config, m.queryRecords())
+	if err != nil {
+		return nil, err
+	}
+	if q.Saved == "" && (len(q.OrderBy) > 0 || q.Limit > 0 || len(q.Fields) > 0) {
+		return nil, fmt.Errorf("ORDER BY, LIMIT and FIELDS are not supported here")
+	}
+	return q, nil
+}
+
+// queryRecords returns the type of record the list shows.
+func (m *Model) queryRecords() query.RecordType {
+	if m.projectFilter {
+		return query.ProjectRecords
+	}
+	return query.TaskRecords
+}
+
+// recheckQuery re-parses the query filter after the list switches between
+// tasks and projects, and drops it if it compares fields the new list's
+// records don't have.
+func (m *Model) recheckQuery() {
+	if m.queryFilter == nil {
+		return
+	}
+	q, err := parseFilterQuery(m.queryText, m)
+	if err != nil {
+		m.queryText = ""
+		m.queryFilter = nil
+		m.statusMsg += fmt.Sprintf(" (query cleared: %v)", err)
+		return
+	}
+	m.queryFilter = q
+}
+
+// refilter re-applies the filters and resets the cursor.
+func (m *Model) refilter() {
+	m.cursor = 0
+	m.scrollOffset = 0
+	m.applyFilters()
+	m.sortFiles()
+	m.loadVisibleMetadata()
+}
+
+// renderQueryBar renders the expression being typed with either its parse
+// error or the number of matches.
+func (m Model) renderQueryBar() string {
+	label := "Query: "
+	prompt := label + m.queryInput + "█"
+	if m.queryInputErr != nil {
+		// Point at the error unless it is inside a saved query
+		if pe, ok := m.queryInputErr.(*query.ParseError); ok && pe.SavedQuery == "" && pe.Pos <= len(m.queryInput) {
+			caret := strings.Repeat(" ", utf8.RuneCountInString(label+m.queryInput[:pe.Pos])) + "^"
+			return "\n" + prompt + "\n" + overdueStyle.Render(caret+" "+pe.Error())
+		}
+		return "\n" + prompt + "\n" + overdueStyle.Render(m.queryInputErr.Error())
+	}
+
+	kind := "tasks"
+	if m.projectFilter {
+		kind = "projects"
+	}
+	status := fmt.Sprintf("%d %s", len(m.filtered), kind)
+	if len(m.filtered) == 1 {
+		status = strings.TrimSuffix(status, "s")
+	}
+	help := fmt.Sprintf(" (%s; Enter to keep, Esc to cancel, ↑/↓ history)", status)
+	return "\n" + prompt + helpStyle.Render(help)
+}
+
+// loadQueryHistory reads the saved query history; a missing or unreadable
+// file is an empty history.
+func loadQueryHistory(dir string) []string {
+	data, err := os.ReadFile(filepath.Join(dir, QueryHistoryFileName))
+	if err != nil {
+		return nil
+	}
+	var history []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			history = append(history, line)
+		}
+	}
+	return history
+}
+
+// addQueryHistory appends text to the history, moving it to the end if it
+// is already present and dropping the oldest entries beyond the limit.
+func addQueryHistory(history []string, text string) []string {
+	updated := make([]string, 0, len(history)+1)
+	for _, h := range history {
+		if h != text {
+			updated = append(updated, h)
+		}
+	}
+	updated = append(updated, text)
+	if len(updated) > maxQueryHistory {
+		updated = updated[len(updated)-maxQueryHistory:]
+	}
+	return updated
+}
+
+func saveQueryHistory(dir string, history []string) error {
+	data := strings.Join(history, "\n") + "\n"
+	return os.WriteFile(filepath.Join(dir, QueryHistoryFileName), []byte(data), 0644)
+}
@@ -0,0 +1,69 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/mph-llm-experiments/atask/internal/config"
+)
+
+func TestQueryHistoryRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	if history := loadQueryHistory(dir); history != nil {
+		t.Errorf("history without a file = %q, want none", history)
+	}
+
+	var history []string
+	for _, text := range []string{"status:open", "area:work", "title:\"café\"", "status:open"} {
+		history = addQueryHistory(history, text)
+	}
+	// Repeats move to the end instead of being stored twice
+	want := []string{"area:work", "title:\"café\"", "status:open"}
+	if !reflect.DeepEqual(history, want) {
+		t.Fatalf("history = %q, want %q", history, want)
+	}
+
+	if err := saveQueryHistory(dir, history); err != nil {
+		t.Fatalf("saveQueryHistory: %v", err)
+	}
+	if got := loadQueryHistory(dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded %q, want %q", got, want)
+	}
+
+	// Blank lines and surrounding space in a hand-edited file are ignored
+	data := "\n  status:open  \n\narea:work\n"
+	if err := os.WriteFile(filepath.Join(dir, QueryHistoryFileName), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadQueryHistory(dir); !reflect.DeepEqual(got, []string{"status:open", "area:work"}) {
+		t.Errorf("loaded %q from a hand-edited file", got)
+	}
+}
+
+func TestQueryHistoryLimit(t *testing.T) {
+	var history []string
+	for i := 0; i < maxQueryHistory+5; i++ {
+		history = addQueryHistory(history, fmt.Sprintf("index_id:%d", i))
+	}
+	if len(history) != maxQueryHistory {
+		t.Fatalf("history has %d entries, want %d", len(history), maxQueryHistory)
+	}
+	if history[0] != "index_id:5" || history[len(history)-1] != fmt.Sprintf("index_id:%d", maxQueryHistory+4) {
+		t.Errorf("kept %q ... %q, want the newest entries", history[0], history[len(history)-1])
+	}
+}
+
+func TestQueryBarBackspaceRemovesRunes(t *testing.T) {
+	m := Model{config: &config.Config{NotesDirectory: t.TempDir()}, mode: ModeQueryBar}
+	m.queryInput = "title:café"
+
+	updated, _ := m.handleQueryBarKeys(tea.KeyMsg{Type: tea.KeyBackspace})
+	if got := updated.(Model).queryInput; got != "title:caf" {
+		t.Errorf("after backspace input = %q, want %q", got, "title:caf")
+	}
+}
@@ -0,0 +1,171 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+	"github.com/mph-llm-experiments/atask/internal/query"
+)
+
+// handleQueryPickerKeys handles the saved query picker, which the filter key
+// opens when the config defines [queries]. Entry 0 opens the fixed filter
+// menu; the others apply a saved query in place of those filters.
+func (m Model) handleQueryPickerKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	names := m.config.QueryNames()
+
+	switch msg.String() {
+	case "esc", "ctrl+c", "q":
+		m.mode = ModeNormal
+
+	case "enter":
+		if m.queryCursor == 0 {
+			m.mode = ModeFilterMenu
+		} else if m.queryCursor-1 < len(names) {
+			m.applySavedQuery(names[m.queryCursor-1])
+			m.mode = ModeNormal
+		}
+
+	case "o", "0":
+		m.mode = ModeFilterMenu
+
+	case "e":
+		m.openQueryBar()
+
+	case "c", "x":
+		m.queryText = ""
+		m.queryFilter = nil
+		m.mode = ModeNormal
+		m.statusMsg = "Saved query cleared"
+		m.applyFilters()
+		m.sortFiles()
+		m.loadVisibleMetadata()
+
+	case "j", "down", "k", "up", "g", "G", "ctrl+d", "ctrl+u":
+		nav := NewNavigationHandler(len(names)+1, false)
+		nav.cursor = m.queryCursor
+		m.queryCursor = nav.HandleKey(msg.String())
+
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		num, _ := strconv.Atoi(msg.String())
+		if num <= len(names) {
+			m.queryCursor = num
+			m.applySavedQuery(names[num-1])
+			m.mode = ModeNormal
+		}
+	}
+
+	return m, nil
+}
+
+// applySavedQuery filters the list with the named saved query and switches
+// to its sort settings, if it has any.
+func (m *Model) applySavedQuery(name string) {
+	q, err := query.ParseFor("@"+name, m.config, m.queryRecords())
+	if err != nil {
+		m.statusMsg = fmt.Sprintf("Error: %v", err)
+		return
+	}
+
+	m.queryText = "@" + name
+	m.queryFilter = q
+	if saved := m.config.Queries[name]; saved.Sort != "" {
+		m.sortBy = saved.Sort
+		m.reverseSort = saved.Reverse
+	}
+	m.statusMsg = fmt.Sprintf("Showing @%s", name)
+	m.applyFilters()
+	m.sortFiles()
+	m.loadVisibleMetadata()
+}
+
+// applyQueryClauses orders the filtered list by the query filter's ORDER BY,
+// with the list's own sort breaking ties, and truncates it to its LIMIT.
+// Only a saved query can bring these clauses into the list.
+func (m *Model) applyQueryClauses() {
+	var projects []*denote.Project
+	for _, f := range m.files {
+		if f.IsProject() {
+			if proj, err := denote.ParseProjectFile(f.Path); err == nil {
+				projects = append(projects, proj)
+			}
+		}
+	}
+	projectsByID := query.ProjectsByID(projects)
+
+	// applyFilters only keeps files it could read, so each has a record
+	records := make(map[string]query.Record, len(m.filtered))
+	for _, f := range m.filtered {
+		if f.IsTask() {
+			if task, err := denote.ParseTaskFile(f.Path); err == nil {
+				records[f.Path] = query.NewTaskRecord(task, projectsByID)
+			}
+		} else if proj, err := denote.ParseProjectFile(f.Path); err == nil {
+			records[f.Path] = proj
+		}
+	}
+
+	q := m.queryFilter
+	q.SortSlice(m.filtered, func(i int) query.Record { return records[m.filtered[i].Path] })
+	if q.Limit > 0 && len(m.filtered) > q.Limit {
+		m.filtered = m.filtered[:q.Limit]
+	}
+	if m.cursor >= len(m.filtered) && len(m.filtered) > 0 {
+		m.cursor = len(m.filtered) - 1
+	}
+}
+
+func (m Model) renderQueryPicker() string {
+	prompt := titleStyle.Render("Saved Queries")
+
+	var lines []string
+	selector := " "
+	if m.queryCursor == 0 {
+		selector = ">"
+	}
+	optionsLine := fmt.Sprintf("%s 0. Filter options...", selector)
+	if m.queryCursor == 0 {
+		lines = append(lines, selectedStyle.Render(optionsLine))
+	} else {
+		lines = append(lines, helpStyle.Render(optionsLine))
+	}
+
+	for i, name := range m.config.QueryNames() {
+		selector := " "
+		if i+1 == m.queryCursor {
+			selector = ">"
+		}
+		number := "   "
+		if i < 9 {
+			number = fmt.Sprintf("%d. ", i+1)
+		}
+		active := " "
+		if m.queryText == "@"+name {
+			active = "●"
+		}
+
+		saved := m.config.Queries[name]
+		line := fmt.Sprintf("%s %s%s @%-12s %s", selector, number, active, name, saved.Query)
+		if saved.Sort != "" {
+			order := "↑"
+			if saved.Reverse {
+				order = "↓"
+			}
+			line += fmt.Sprintf("  (sort: %s %s)", saved.Sort, order)
+		}
+
+		if i+1 == m.queryCursor {
+			lines = append(lines, selectedStyle.Render(line))
+		} else {
+			lines = append(lines, baseStyle.Render(line))
+		}
+	}
+
+	list := strings.Join(lines, "\n")
+
+	help := helpStyle.Render("\n\nj/k or ↑/↓: navigate • 1-9: quick select • Enter: apply • 0/o: filter options • e: expression • c: clear • Esc: cancel")
+
+	return prompt + "\n\n" + list + help
+}
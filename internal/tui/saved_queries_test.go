@@ -0,0 +1,67 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mph-llm-experiments/acore"
+	"github.com/mph-llm-experiments/atask/internal/config"
+	"github.com/mph-llm-experiments/atask/internal/denote"
+)
+
+func TestSavedQueryOrderAndLimit(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		NotesDirectory: dir,
+		Queries: map[string]config.SavedQuery{
+			"top":  {Query: "status:open ORDER BY priority LIMIT 2"},
+			"open": {Query: "status:open"},
+		},
+	}
+
+	for i, tc := range []struct{ title, priority string }{
+		{"Alpha", "p3"},
+		{"Bravo", "p1"},
+		{"Charlie", ""},
+		{"Delta", "p2"},
+	} {
+		task := &denote.Task{}
+		task.ID = acore.NewID()
+		task.Title = tc.title
+		task.IndexID = i + 1
+		task.Type = denote.TypeTask
+		task.Status = denote.TaskStatusOpen
+		task.Priority = tc.priority
+		if tc.title == "Alpha" {
+			task.TodayDate = time.Now().Format("2006-01-02")
+		}
+		filename := acore.BuildFilename(task.ID, tc.title, "task")
+		if err := acore.WriteFile(acore.NewLocalStore(dir), filename, task, ""); err != nil {
+			t.Fatalf("write task: %v", err)
+		}
+	}
+
+	m := Model{config: cfg, sortBy: "title"}
+	if err := m.scanFiles(); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	titles := func() []string {
+		var got []string
+		for _, f := range m.filtered {
+			got = append(got, f.Title)
+		}
+		return got
+	}
+
+	m.applySavedQuery("top")
+	if got, want := titles(), []string{"Bravo", "Delta"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("@top shows %v, want %v", got, want)
+	}
+
+	// Without result clauses the list keeps its sort, with today tasks first
+	m.applySavedQuery("open")
+	if got, want := titles(), []string{"Alpha", "Bravo", "Charlie", "Delta"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("@open shows %v, want %v", got, want)
+	}
+}
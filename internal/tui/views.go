@@ -99,23 +99,28 @@ func (m Model) renderHeader() string {
 			filterInfo = append(filterInfo, fmt.Sprintf("Search: %s", m.searchQuery))
 		}
 	}
-	if m.areaFilter != "" {
-		filterInfo = append(filterInfo, fmt.Sprintf("Area: %s", m.areaFilter))
-	}
-	if m.priorityFilter != "" {
-		filterInfo = append(filterInfo, fmt.Sprintf("Priority: %s", m.priorityFilter))
-	}
-	if m.stateFilter != "" {
-		filterInfo = append(filterInfo, fmt.Sprintf("State: %s", m.stateFilter))
-	}
-	if m.looseFilter {
-		filterInfo = append(filterInfo, "Loose")
-	}
-	if m.soonFilter {
-		filterInfo = append(filterInfo, fmt.Sprintf("Soon: %dd", m.config.SoonHorizon))
-	}
-	if m.todayFilter {
-		filterInfo = append(filterInfo, "Due today")
+	if m.queryText != "" {
+		// The query replaces the other filters
+		filterInfo = append(filterInfo, fmt.Sprintf("Query: %s", m.queryText))
+	} else {
+		if m.areaFilter != "" {
+			filterInfo = append(filterInfo, fmt.Sprintf("Area: %s", m.areaFilter))
+		}
+		if m.priorityFilter != "" {
+			filterInfo = append(filterInfo, fmt.Sprintf("Priority: %s", m.priorityFilter))
+		}
+		if m.stateFilter != "" {
+			filterInfo = append(filterInfo, fmt.Sprintf("State: %s", m.stateFilter))
+		}
+		if m.looseFilter {
+			filterInfo = append(filterInfo, "Loose")
+		}
+		if m.soonFilter {
+			filterInfo = append(filterInfo, fmt.Sprintf("Soon: %dd", m.config.SoonHorizon))
+		}
+		if m.todayFilter {
+			filterInfo = append(filterInfo, "Due today")
+		}
 	}
 	
 	// Sort info
@@ -147,6 +152,11 @@ func (m Model) renderHeader() string {
 		status += " | " + strings.Join(filterInfo, " | ")
 	}
 	status += " | " + sortInfo
+	if n := len(m.parseErrors); n == 1 {
+		status += " | ⚠ 1 file could not be parsed"
+	} else if n > 1 {
+		status += fmt.Sprintf(" | ⚠ %d files could not be parsed", n)
+	}
 	if m.statusMsg != "" {
 		status += " | " + m.statusMsg
 	}
@@ -574,6 +584,9 @@ func (m Model) renderFooter() string {
 		help := MsgFuzzyMatch
 		return "\n" + prompt + helpStyle.Render(help)
 	}
+	if m.mode == ModeQueryBar {
+		return m.renderQueryBar()
+	}
 	
 	// Show appropriate hotkeys based on current view
 	var help []string
@@ -590,6 +603,7 @@ func (m Model) renderFooter() string {
 			"x:delete",
 			"E:edit",
 			"f:filter",
+			"F:query",
 			"T:tasks",
 			"S:sort",
 			"?:help",
@@ -611,6 +625,7 @@ func (m Model) renderFooter() string {
 			"E:edit",
 			"l:log",
 			"f:filter",
+			"F:query",
 			"P:projects",
 			"S:sort",
 			"?:help",
@@ -649,7 +664,8 @@ Actions (lowercase):
   s       Change task state (open/done/etc)
   t       Edit tags
   u       Update task metadata
-  x       Delete task/project
+  U       Undo last change
+  x       Delete task/project (moves it to the trash)
   /       Fuzzy search (use #tag for tag search)
 
 Priority:
@@ -661,7 +677,9 @@ Filters & Views (uppercase):
   P       Toggle projects view
   T       Toggle tasks view
   S       Sort options menu
-  f       Filter menu (area/priority/state/soon)
+  f       Filter menu (saved queries, area/priority/state/soon)
+  F       Query filter bar (area:work AND due<+7d)
+  X       Trash (restore deleted tasks/projects)
   
 Other:
   ?       Toggle this help
@@ -913,7 +931,7 @@ func (m Model) renderConfirmDelete() string {
 					break
 				}
 			}
-			affectedInfo += "\n\nThe project_id will be removed from these tasks."
+			affectedInfo += "\n\nThe project_id will be removed from these tasks and restored if the project is."
 		}
 		
 		options := `
@@ -921,7 +939,7 @@ func (m Model) renderConfirmDelete() string {
   (y) Yes, delete project and clear task associations
   (n) No, cancel
   
-  This action cannot be undone!`
+  Deleted items can be restored from the trash (X).`
 		
 		dangerStyle := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("196")).
@@ -942,7 +960,7 @@ func (m Model) renderConfirmDelete() string {
   (y) Yes, delete
   (n) No, cancel
   
-  This action cannot be undone!`
+  Deleted items can be restored from the trash (X).`
 		
 		dangerStyle := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("196")).
@@ -975,7 +993,7 @@ func (m Model) renderConfirmDelete() string {
   (y) Yes, delete
   (n) No, cancel
   
-  This action cannot be undone!`
+  Deleted items can be restored from the trash (X).`
 	
 	dangerStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("196")).
@@ -984,6 +1002,42 @@ func (m Model) renderConfirmDelete() string {
 	return prompt + warning + fileName + "\n" + dangerStyle.Render(options)
 }
 
+func (m Model) renderTrash() string {
+	prompt := titleStyle.Render("Trash")
+	
+	if len(m.trashEntries) == 0 {
+		return prompt + "\n\n" + helpStyle.Render("Trash is empty.\n\nPress Esc to go back")
+	}
+	
+	var lines []string
+	for i, entry := range m.trashEntries {
+		selector := " "
+		if i == m.trashCursor {
+			selector = ">"
+		}
+		
+		line := fmt.Sprintf("%s %-8s %s  %s", selector, entry.Type, entry.DeletedAt.Format("2006-01-02 15:04"), entry.Title)
+		if len(entry.Cleared) > 0 {
+			line += fmt.Sprintf(" (%d tasks)", len(entry.Cleared))
+		}
+		
+		if i == m.trashCursor {
+			lines = append(lines, selectedStyle.Render(line))
+		} else {
+			lines = append(lines, baseStyle.Render(line))
+		}
+	}
+	
+	status := ""
+	if m.statusMsg != "" {
+		status = "\n\n" + statusStyle.Render(m.statusMsg)
+	}
+	
+	help := "\n\nj/k:nav • enter/r:restore • esc:back"
+	
+	return prompt + "\n\n" + strings.Join(lines, "\n") + status + helpStyle.Render(help)
+}
+
 func (m Model) renderConfirmClearToday() string {
 	prompt := titleStyle.Render("Clear All 'Today' Tags")
 
@@ -1044,6 +1098,9 @@ func (m Model) renderFilterMenu() string {
 			current += "\n  • " + f
 		}
 	}
+	if m.queryText != "" {
+		current += fmt.Sprintf("\n\nQuery %s is applied in place of these filters", m.queryText)
+	}
 	
 	// Apply base style to current filters section
 	current = baseStyle.Render(current)
@@ -1057,6 +1114,7 @@ Filter by:
   (l) Loose tasks (toggle) - no project
   (d) Due soon (toggle)
   (t) Due today (toggle)
+  (e) Query expression (area:work AND due<+7d)
 
   (c) Clear all filters
 